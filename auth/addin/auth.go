@@ -16,6 +16,7 @@ import (
 
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 )
 
 // AuthCnfg - AddIn Only auth config structure
@@ -27,17 +28,21 @@ import (
 }
 */
 type AuthCnfg struct {
-	SiteURL      string `json:"siteUrl"`      // SPSite or SPWeb URL, which is the context target for the API calls
-	ClientID     string `json:"clientId"`     // Client ID obtained when registering the AddIn
-	ClientSecret string `json:"clientSecret"` // Client Secret obtained when registering the AddIn
-	Realm        string `json:"realm"`        // Your SharePoint Online tenant ID (optional)
-
-	masterKey string
-	client    *http.Client
+	SiteURL      string                 `json:"siteUrl" required:"true"`                    // SPSite or SPWeb URL, which is the context target for the API calls
+	ClientID     string                 `json:"clientId" required:"true"`                   // Client ID obtained when registering the AddIn
+	ClientSecret string                 `json:"clientSecret" required:"true" secret:"true"` // Client Secret obtained when registering the AddIn
+	Realm        string                 `json:"realm"`                                      // Your SharePoint Online tenant ID (optional)
+	Transport    *gosip.TransportConfig `json:"transport,omitempty"`                        // HTTP transport settings (optional)
+
+	privateFile string
+	masterKey   string
+	secrets     secret.Refs
+	client      *http.Client
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	jsonFile, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -53,20 +58,29 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
+	if err := c.secrets.Resolve(&c.ClientSecret); err != nil {
+		return err
+	}
 
-	crypt := cpass.Cpass(c.masterKey)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	secret, err := crypt.Decode(c.ClientSecret)
 	if err == nil {
 		c.ClientSecret = secret
 	}
 
-	return nil
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
 }
 
 // WriteConfig writes private config with auth options
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	crypt := cpass.Cpass(c.masterKey)
-	secret, err := crypt.Encode(c.ClientSecret)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	secret, err := c.secrets.Encode(&c.ClientSecret, crypt.Encode)
 	if err != nil {
 		secret = c.ClientSecret
 	}
@@ -76,6 +90,11 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		ClientSecret: secret,
 		Realm:        c.Realm,
 	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
@@ -84,7 +103,15 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
 
 // GetAuth authenticates, receives access token
-func (c *AuthCnfg) GetAuth() (string, int64, error) { return GetAuth(c) }
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	return c.secrets.Retry(func() (string, int64, error) { return GetAuth(c) })
+}
+
+// GetResourceAuth authenticates for another SharePoint host, e.g. admin site or OneDrive host
+func (c *AuthCnfg) GetResourceAuth(resource string) (string, int64, error) {
+	resource = gosip.ResourceOf(resource)
+	return c.secrets.Retry(func() (string, int64, error) { return getResourceAuth(c, resource) })
+}
 
 // GetSiteURL gets siteURL
 func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
@@ -92,6 +119,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "addin" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticate request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
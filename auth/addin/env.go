@@ -40,3 +40,21 @@ func resolveSPOEnv(siteURL string) spoEnv {
 
 	return spoProd // ToDo: Research how to identify Office 365 Dedicated
 }
+
+// isSharePointHost checks if the host is a SharePoint Online one or the config site host,
+// add-in only tokens are not issued for other resources, e.g. Microsoft Graph
+func isSharePointHost(host string, siteURL string) bool {
+	if host == "" {
+		return false
+	}
+	if parsedURL, err := url.Parse(siteURL); err == nil && strings.EqualFold(parsedURL.Host, host) {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, suffix := range []string{".sharepoint.com", ".sharepoint.de", ".sharepoint.cn", ".sharepoint-mil.us", ".sharepoint.us"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
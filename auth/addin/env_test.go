@@ -30,4 +30,18 @@ func TestSPOHostingEnvCases(t *testing.T) {
 		}
 	})
 
+	t.Run("SharePointHost", func(t *testing.T) {
+		siteURL := "https://sp.contoso.com/sites/test"
+		for _, host := range []string{"contoso.sharepoint.com", "contoso-admin.sharepoint.com", "Contoso.SharePoint.de", "sp.contoso.com"} {
+			if !isSharePointHost(host, siteURL) {
+				t.Errorf("%s should be a SharePoint host", host)
+			}
+		}
+		for _, host := range []string{"", "graph.microsoft.com", "contoso.com", "sharepoint.com.evil.net"} {
+			if isSharePointHost(host, siteURL) {
+				t.Errorf("%s should not be a SharePoint host", host)
+			}
+		}
+	})
+
 }
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/koltyakov/gosip"
 	"github.com/patrickmn/go-cache"
 )
 
@@ -26,14 +27,26 @@ var (
 
 // GetAuth gets authentication
 func GetAuth(c *AuthCnfg) (string, int64, error) {
-	if c.client == nil {
-		c.client = &http.Client{}
-	}
+	return getResourceAuth(c, gosip.ResourceOf(c.SiteURL))
+}
 
-	parsedURL, err := url.Parse(c.SiteURL)
+// getResourceAuth gets authentication for SharePoint host resource, the realm is shared within the tenant
+func getResourceAuth(c *AuthCnfg, resource string) (string, int64, error) {
+	parsedURL, err := url.Parse(resource)
 	if err != nil {
 		return "", 0, err
 	}
+	if !isSharePointHost(parsedURL.Host, c.SiteURL) {
+		return "", 0, fmt.Errorf("add-in only tokens are issued for SharePoint hosts, %s is not a SharePoint host", resource)
+	}
+
+	if c.client == nil {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", 0, err
+		}
+		c.client = client
+	}
 
 	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.ClientID + "@" + c.ClientSecret
 	if accessToken, exp, found := storage.GetWithExpiration(cacheKey); found {
@@ -52,14 +65,14 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 	}
 
 	servicePrincipal := "00000003-0000-0ff1-ce00-000000000000" // TODO: move to constants
-	resource := fmt.Sprintf("%s/%s@%s", servicePrincipal, parsedURL.Host, c.Realm)
+	principal := fmt.Sprintf("%s/%s@%s", servicePrincipal, parsedURL.Host, c.Realm)
 	fullClientID := fmt.Sprintf("%s@%s", c.ClientID, c.Realm)
 
 	params := url.Values{}
 	params.Set("grant_type", "client_credentials")
 	params.Set("client_id", fullClientID)
 	params.Set("client_secret", c.ClientSecret)
-	params.Set("resource", resource)
+	params.Set("resource", principal)
 
 	// resp, err := http.Post(authURL, "application/x-www-form-urlencoded", strings.NewReader(params.Encode()))
 	resp, err := c.client.Post(authURL, "application/x-www-form-urlencoded", strings.NewReader(params.Encode()))
@@ -108,7 +121,11 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 
 func getAuthURL(c *AuthCnfg, realm string) (string, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", err
+		}
+		c.client = client
 	}
 
 	accEndpoint := accEndpoints[resolveSPOEnv(c.SiteURL)] // "accounts.accesscontrol.windows.net"
@@ -166,7 +183,11 @@ func getAuthURL(c *AuthCnfg, realm string) (string, error) {
 
 func getRealm(c *AuthCnfg) (string, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", err
+		}
+		c.client = client
 	}
 
 	if c.Realm != "" {
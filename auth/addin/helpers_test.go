@@ -1,6 +1,7 @@
 package addin
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -41,4 +42,12 @@ func TestHelpersEdgeCases(t *testing.T) {
 		}
 	})
 
+	t.Run("GetResourceAuth/Graph", func(t *testing.T) {
+		cnfg := &AuthCnfg{SiteURL: "https://contoso.sharepoint.com/sites/test", Realm: "any"}
+		_, _, err := cnfg.GetResourceAuth("https://graph.microsoft.com")
+		if err == nil || !strings.Contains(err.Error(), "not a SharePoint host") {
+			t.Errorf("non SharePoint resource should not go: %v", err)
+		}
+	})
+
 }
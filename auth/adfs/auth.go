@@ -12,12 +12,22 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 )
 
+// loginPages are sign in pages SharePoint redirects to when the session is not valid anymore
+var loginPages = []string{
+	"/_trust/",                       // SharePoint trusted identity provider redirect
+	"/adfs/ls/",                      // ADFS or WAP sign in form
+	"/_layouts/15/authenticate.aspx", // SharePoint 2013+ sign in redirect
+	"/_layouts/authenticate.aspx",    // SharePoint 2010 sign in redirect
+}
+
 // AuthCnfg - ADFS auth config structure
 /* On-Premises config sample:
 {
@@ -39,6 +49,16 @@ import (
   "adfsCookie": "EdgeAccessCookie"
 }
 */
+/* On-Premises with a certificate and non-ADFS WS-Trust IdP config sample:
+{
+  "siteUrl": "https://www.contoso.com/sites/test",
+  "relyingParty": "urn:sharepoint:www",
+  "adfsUrl": "https://sts.contoso.com",
+  "mexUrl": "https://sts.contoso.com/idp/mex",
+  "certPath": "./cert.pfx",
+  "certPass": "this-is-not-a-real-password"
+}
+*/
 /* SharePoint Online config sample:
 {
   "siteUrl": "https://www.contoso.com/sites/test",
@@ -47,20 +67,27 @@ import (
 }
 */
 type AuthCnfg struct {
-	SiteURL      string `json:"siteUrl"` // SPSite or SPWeb URL, which is the context target for the API calls
-	Domain       string `json:"domain"`
-	Username     string `json:"username"`
-	Password     string `json:"password"`
-	RelyingParty string `json:"relyingParty"`
-	AdfsURL      string `json:"adfsUrl"`
-	AdfsCookie   string `json:"adfsCookie"`
-
-	masterKey string
-	client    *http.Client
+	SiteURL      string                 `json:"siteUrl" required:"true"` // SPSite or SPWeb URL, which is the context target for the API calls
+	Domain       string                 `json:"domain"`
+	Username     string                 `json:"username"`
+	Password     string                 `json:"password" secret:"true"`
+	RelyingParty string                 `json:"relyingParty"`
+	AdfsURL      string                 `json:"adfsUrl"`
+	AdfsCookie   string                 `json:"adfsCookie"`
+	MexURL       string                 `json:"mexUrl"`                 // WS-Trust MEX metadata URL, ADFS default `/adfs/services/trust/mex` is used if not provided
+	CertPath     string                 `json:"certPath"`               // Client certificate (.pfx) for certificatemixed binding, relative to config location or absolute
+	CertPass     string                 `json:"certPass" secret:"true"` // Client certificate export password
+	Transport    *gosip.TransportConfig `json:"transport,omitempty"`    // HTTP transport settings (optional)
+
+	privateFile string
+	masterKey   string
+	secrets     secret.Refs
+	client      *http.Client
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	jsonFile, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -76,11 +103,23 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
-	crypt := cpass.Cpass(c.masterKey)
+	if err := c.secrets.Resolve(&c.Password, &c.CertPass); err != nil {
+		return err
+	}
+	if c.CertPath != "" && !filepath.IsAbs(c.CertPath) && c.privateFile != "" {
+		c.CertPath = filepath.Join(filepath.Dir(c.privateFile), c.CertPath)
+	}
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	pass, err := crypt.Decode(c.Password)
 	if err == nil {
 		c.Password = pass
 	}
+	if secret, err := crypt.Decode(c.CertPass); err == nil {
+		c.CertPass = secret
+	}
 
 	if c.Domain != "" && !strings.Contains(c.Username, "\\") && !strings.Contains(c.Username, "@") {
 		c.Username = c.Domain + "\\" + c.Username
@@ -90,13 +129,16 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 		c.AdfsCookie = "FedAuth"
 	}
 
-	return nil
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
 }
 
 // WriteConfig writes private config with auth options
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	crypt := cpass.Cpass(c.masterKey)
-	pass, err := crypt.Encode(c.Password)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	pass, err := c.secrets.Encode(&c.Password, crypt.Encode)
 	if err != nil {
 		pass = c.Password
 	}
@@ -108,7 +150,21 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		RelyingParty: c.RelyingParty,
 		AdfsURL:      c.AdfsURL,
 		AdfsCookie:   c.AdfsCookie,
+		MexURL:       c.MexURL,
+		CertPath:     c.CertPath,
+	}
+	if c.CertPass != "" {
+		secret, err := c.secrets.Encode(&c.CertPass, crypt.Encode)
+		if err != nil {
+			return err
+		}
+		config.CertPass = secret
 	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
@@ -117,7 +173,9 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
 
 // GetAuth authenticates, receives access token
-func (c *AuthCnfg) GetAuth() (string, int64, error) { return GetAuth(c) }
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	return c.secrets.Retry(func() (string, int64, error) { return GetAuth(c) })
+}
 
 // GetSiteURL gets siteURL
 func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
@@ -125,6 +183,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "adfs" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticate request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
@@ -138,3 +199,8 @@ func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error
 	req.Header.Set("Cookie", authCookie)
 	return nil
 }
+
+// IsSessionExpired checks if the response is a sign in page instead of the requested resource
+func (c *AuthCnfg) IsSessionExpired(resp *http.Response) bool {
+	return gosip.IsLoginResponse(resp, loginPages...)
+}
@@ -1,8 +1,6 @@
 package adfs
 
 import (
-	"bytes"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +11,7 @@ import (
 
 	"github.com/patrickmn/go-cache"
 
+	"github.com/koltyakov/gosip/auth/internal/wstrust"
 	"github.com/koltyakov/gosip/templates"
 )
 
@@ -23,7 +22,11 @@ var (
 // GetAuth gets authentication
 func GetAuth(c *AuthCnfg) (string, int64, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", 0, err
+		}
+		c.client = client
 	}
 
 	parsedURL, err := url.Parse(c.SiteURL)
@@ -32,6 +35,9 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 	}
 
 	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.Username + "@" + c.Password
+	if c.CertPath != "" {
+		cacheKey += "@" + c.CertPath
+	}
 	if authCookie, exp, found := storage.GetWithExpiration(cacheKey); found {
 		return authCookie.(string), exp.Unix(), nil
 	}
@@ -65,85 +71,19 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 
 func adfsAuthFlow(c *AuthCnfg, edgeCookie string) (string, string, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
-	}
-
-	parsedAdfsURL, err := url.Parse(c.AdfsURL)
-	if err != nil {
-		return "", "", err
-	}
-
-	usernameMixedURL := fmt.Sprintf("%s://%s/adfs/services/trust/13/usernamemixed", parsedAdfsURL.Scheme, parsedAdfsURL.Host)
-	samlBody, err := templates.AdfsSamlWsfedTemplate(usernameMixedURL, c.Username, c.Password, c.RelyingParty)
-	if err != nil {
-		return "", "", err
-	}
-
-	req, err := http.NewRequest("POST", usernameMixedURL, bytes.NewBuffer([]byte(samlBody)))
-	if err != nil {
-		return "", "", err
-	}
-
-	req.Header.Set("Content-Type", "application/soap+xml;charset=utf-8")
-	if edgeCookie != "" {
-		req.Header.Set("Cookie", edgeCookie)
-	}
-
-	// client := &http.Client{}
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", "", err
-	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			_ = resp.Body.Close()
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", "", err
 		}
-	}()
-
-	res, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", err
+		c.client = client
 	}
 
-	// fmt.Printf("ADFS: %s\n", string(res))
-
-	type samlAssertion struct {
-		Fault    string `xml:"Body>Fault>Reason>Text"`
-		Response struct {
-			Token struct {
-				Inner      []byte `xml:",innerxml"`
-				Conditions struct {
-					NotBefore    string `xml:"NotBefore,attr"`
-					NotOnOrAfter string `xml:"NotOnOrAfter,attr"`
-				} `xml:"Assertion>Conditions"`
-			} `xml:"RequestedSecurityToken"`
-			// This is for WPA (urn:AppProxy:com)
-			Lifetime struct {
-				Created string `xml:"Created"`
-				Expires string `xml:"Expires"`
-			} `xml:"Lifetime"`
-		} `xml:"Body>RequestSecurityTokenResponseCollection>RequestSecurityTokenResponse"`
-	}
-	result := &samlAssertion{}
-	if err := xml.Unmarshal(res, &result); err != nil {
+	token, err := requestSecurityToken(c, c.RelyingParty, edgeCookie)
+	if err != nil {
 		return "", "", err
 	}
 
-	if result.Fault != "" {
-		return "", "", errors.New(result.Fault)
-	}
-
-	created := result.Response.Token.Conditions.NotBefore
-	if created == "" {
-		created = result.Response.Lifetime.Created
-	}
-
-	expires := result.Response.Token.Conditions.NotOnOrAfter
-	if expires == "" {
-		expires = result.Response.Lifetime.Expires
-	}
-
-	wresult, err := templates.AdfsSamlTokenTemplate(result.Response.Token.Inner, created, expires, c.RelyingParty)
+	wresult, err := templates.AdfsSamlTokenTemplate(token.Assertion, token.Created, token.Expires, c.RelyingParty)
 	if err != nil {
 		return "", "", err
 	}
@@ -170,7 +110,7 @@ func adfsAuthFlow(c *AuthCnfg, edgeCookie string) (string, string, error) {
 	// }
 	c.client.CheckRedirect = doNotCheckRedirect
 
-	req, err = http.NewRequest("POST", rootSiteURL+"/_trust/", strings.NewReader(params.Encode()))
+	req, err := http.NewRequest("POST", rootSiteURL+"/_trust/", strings.NewReader(params.Encode()))
 	if err != nil {
 		return "", "", err
 	}
@@ -180,7 +120,7 @@ func adfsAuthFlow(c *AuthCnfg, edgeCookie string) (string, string, error) {
 		req.Header.Set("Cookie", edgeCookie)
 	}
 
-	resp, err = c.client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return "", "", err
 	}
@@ -197,13 +137,49 @@ func adfsAuthFlow(c *AuthCnfg, edgeCookie string) (string, string, error) {
 	authCookie := resp.Header.Get("Set-Cookie") // FedAuth
 	authCookie = strings.Split(authCookie, ";")[0]
 
-	return authCookie, expires, nil
+	return authCookie, token.Expires, nil
+}
+
+// requestSecurityToken requests SAML token from ADFS or other WS-Trust IdP,
+// the endpoint is discovered via MEX metadata, default ADFS endpoint is used when discovery fails
+// unless mexUrl is explicitly configured
+func requestSecurityToken(c *AuthCnfg, appliesTo string, edgeCookie string) (*wstrust.Token, error) {
+	creds := &wstrust.Credentials{Username: c.Username, Password: c.Password}
+	if c.CertPath != "" {
+		cert, key, err := wstrust.LoadCertificate(c.CertPath, c.CertPass)
+		if err != nil {
+			return nil, err
+		}
+		creds.Certificate = cert
+		creds.PrivateKey = key
+	} else if c.Username == "" || c.Password == "" {
+		return nil, errors.New("either username and password or certificate should be provided")
+	}
+
+	mexURL, fallback, err := wstrust.ADFS(c.AdfsURL, creds.Kind())
+	if err != nil {
+		return nil, err
+	}
+	if c.MexURL != "" {
+		mexURL = c.MexURL
+	}
+
+	endpoint, err := wstrust.Resolve(c.client, mexURL, c.MexURL != "", creds.Kind(), fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	return wstrust.RequestToken(c.client, endpoint, creds, appliesTo, edgeCookie)
 }
 
 // WAP auth flow - TODO: refactor
 func wapAuthFlow(c *AuthCnfg) (string, string, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", "", err
+		}
+		c.client = client
 	}
 
 	// client := &http.Client{
@@ -375,6 +351,9 @@ func (c *AuthCnfg) CleanAuthCache() error {
 		return err
 	}
 	cacheKey := parsedURL.Host + "@adfs@" + c.Username + "@" + c.Password
+	if c.CertPath != "" {
+		cacheKey += "@" + c.CertPath
+	}
 	storage.Delete(cacheKey)
 	return nil
 }
@@ -18,11 +18,15 @@ import (
 }
 */
 type AuthCnfg struct {
-	SiteURL string `json:"siteUrl"` // SPSite or SPWeb URL, which is the context target for the API calls
+	SiteURL   string                 `json:"siteUrl" required:"true"` // SPSite or SPWeb URL, which is the context target for the API calls
+	Transport *gosip.TransportConfig `json:"transport,omitempty"`     // HTTP transport settings (optional)
+
+	privateFile string
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	jsonFile, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -39,7 +43,7 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 		return err
 	}
 
-	return nil
+	return c.Transport.Prepare(c.privateFile, "")
 }
 
 // WriteConfig writes private config with auth options
@@ -47,6 +51,11 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 	config := &AuthCnfg{
 		SiteURL: c.SiteURL,
 	}
+	transport, err := c.Transport.Encode("")
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
@@ -60,6 +69,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "anonymous" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth : authenticate request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error { return nil }
@@ -13,14 +13,29 @@ import (
 	"github.com/koltyakov/gosip/auth/azurecreds"
 	"github.com/koltyakov/gosip/auth/device"
 	"github.com/koltyakov/gosip/auth/fba"
+	"github.com/koltyakov/gosip/auth/federated"
 	"github.com/koltyakov/gosip/auth/ntlm"
+	"github.com/koltyakov/gosip/auth/obo"
 	"github.com/koltyakov/gosip/auth/ondemand"
+	"github.com/koltyakov/gosip/auth/pkce"
 	"github.com/koltyakov/gosip/auth/saml"
 	"github.com/koltyakov/gosip/auth/tmg"
+	"github.com/koltyakov/gosip/auth/token"
 )
 
 // NewAuthByStrategy resolves AuthCnfg object based on strategy name
 func NewAuthByStrategy(strategy string) (gosip.AuthCnfg, error) {
+	auth := newBuiltinAuth(strategy)
+	if auth == nil {
+		if auth = resolveRegistered(strategy); auth == nil {
+			return nil, fmt.Errorf("can't resolve the strategy: %s", strategy)
+		}
+	}
+	return auth, nil
+}
+
+// newBuiltinAuth creates built-in strategy config, returns nil for other strategy names
+func newBuiltinAuth(strategy string) gosip.AuthCnfg {
 	var auth gosip.AuthCnfg
 
 	switch strategy {
@@ -32,6 +47,8 @@ func NewAuthByStrategy(strategy string) (gosip.AuthCnfg, error) {
 		auth = &azurecreds.AuthCnfg{}
 	case "device":
 		auth = &device.AuthCnfg{}
+	case "federated":
+		auth = &federated.AuthCnfg{}
 	case "addin":
 		auth = &addin.AuthCnfg{}
 	case "adfs":
@@ -40,15 +57,19 @@ func NewAuthByStrategy(strategy string) (gosip.AuthCnfg, error) {
 		auth = &fba.AuthCnfg{}
 	case "ntlm":
 		auth = &ntlm.AuthCnfg{}
+	case "obo":
+		auth = &obo.AuthCnfg{}
+	case "pkce":
+		auth = &pkce.AuthCnfg{}
 	case "saml":
 		auth = &saml.AuthCnfg{}
 	case "tmg":
 		auth = &tmg.AuthCnfg{}
-	default:
-		return nil, fmt.Errorf("can't resolve the strategy: %s", strategy)
+	case "token":
+		auth = &token.AuthCnfg{}
 	}
 
-	return auth, nil
+	return auth
 }
 
 // NewAuthFromFile resolves AuthCnfg object based on private file
@@ -77,7 +98,8 @@ func NewAuthFromFile(privateFile string) (gosip.AuthCnfg, error) {
 		return nil, err
 	}
 
-	if err := auth.ParseConfig(byteValue); err != nil {
+	// Strategies resolve relative paths, e.g. certificates and transport files, against the config location
+	if err := auth.ReadConfig(privateFile); err != nil {
 		return nil, err
 	}
 
@@ -1,8 +1,14 @@
 package auth
 
 import (
+	"encoding/json"
+	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth/azurecert"
 )
 
 func TestAuthResolver(t *testing.T) {
@@ -10,12 +16,16 @@ func TestAuthResolver(t *testing.T) {
 		"azurecert",
 		"azurecreds",
 		"device",
+		"federated",
 		"addin",
 		"adfs",
 		"fba",
 		"ntlm",
+		"obo",
+		"pkce",
 		"saml",
 		"tmg",
+		"token",
 	}
 
 	for _, strategy := range strategies {
@@ -32,6 +42,18 @@ func TestAuthResolver(t *testing.T) {
 	}
 }
 
+func TestResourceAuthStrategies(t *testing.T) {
+	for _, strategy := range []string{"azurecert", "azurecreds", "device", "addin"} {
+		cnfg, err := NewAuthByStrategy(strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := cnfg.(gosip.ResourceAuthCnfg); !ok {
+			t.Errorf("%s should support resource auth", strategy)
+		}
+	}
+}
+
 func TestAuthResolverError(t *testing.T) {
 	_, err := NewAuthByStrategy("unknown")
 	if err == nil {
@@ -68,3 +90,183 @@ func TestAuthFileResolver(t *testing.T) {
 		t.Errorf("strategy should be saml, but %s", cnfg.GetStrategy())
 	}
 }
+
+func TestAuthFileRelativePaths(t *testing.T) {
+	strategies := []string{
+		"azurecert",
+		"azurecreds",
+		"device",
+		"federated",
+		"addin",
+		"adfs",
+		"fba",
+		"ntlm",
+		"obo",
+		"ondemand",
+		"pkce",
+		"saml",
+		"tmg",
+		"token",
+	}
+
+	cert, err := azurecert.GenerateCertificate(azurecert.CertificateOptions{CommonName: "ca"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ca.pem"), cert.CertPEM(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Config files are read from another working directory
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	for _, strategy := range strategies {
+		t.Run(strategy, func(t *testing.T) {
+			file := filepath.Join(dir, strategy+".json")
+			config := `{
+				"strategy": "` + strategy + `",
+				"siteUrl": "https://contoso.sharepoint.com",
+				"transport": { "rootCAs": ["ca.pem"] }
+			}`
+			if err := os.WriteFile(file, []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cnfg, err := NewAuthFromFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cnfg.(gosip.TransportConfigurer).GetTransportConfig().NewClient(); err != nil {
+				t.Errorf("root CA bundle should be resolved relative to the config: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthFileGeneratedConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "private.json")
+	cnfg := &azurecert.AuthCnfg{SiteURL: "https://contoso.sharepoint.com", CertPath: "cert.pfx", CertPass: "pass"}
+	if err := cnfg.WriteConfig(file); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	auth, err := NewAuthFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, ok := auth.(*azurecert.AuthCnfg)
+	if !ok {
+		t.Fatalf("azurecert strategy is expected, got %s", auth.GetStrategy())
+	}
+	if loaded.CertPath != filepath.Join(dir, "cert.pfx") || loaded.CertPass != "pass" {
+		t.Errorf("unexpected certificate settings: %s", loaded.CertPath)
+	}
+}
+
+func TestAuthTransportConfig(t *testing.T) {
+	strategies := []string{
+		"azurecert",
+		"azurecreds",
+		"device",
+		"federated",
+		"addin",
+		"adfs",
+		"fba",
+		"ntlm",
+		"obo",
+		"ondemand",
+		"pkce",
+		"saml",
+		"tmg",
+		"token",
+	}
+
+	dir := t.TempDir()
+	for _, strategy := range strategies {
+		t.Run(strategy, func(t *testing.T) {
+			cnfg, err := NewAuthByStrategy(strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := `{
+				"siteUrl": "https://contoso.sharepoint.com",
+				"certPath": "cert.pfx",
+				"transport": { "proxyUrl": "http://proxy:8080", "proxyUsername": "user", "proxyPassword": "pass" }
+			}`
+			if err := cnfg.ParseConfig([]byte(config)); err != nil {
+				t.Fatal(err)
+			}
+			c, ok := cnfg.(gosip.TransportConfigurer)
+			if !ok {
+				t.Fatal("strategy should support transport settings")
+			}
+			if c.GetTransportConfig() == nil || c.GetTransportConfig().ProxyPassword != "pass" {
+				t.Fatal("transport settings are not parsed")
+			}
+
+			file := filepath.Join(dir, strategy+".json")
+			if err := cnfg.(interface{ WriteConfig(string) error }).WriteConfig(file); err != nil {
+				t.Fatal(err)
+			}
+			data, _ := os.ReadFile(file)
+			var written struct {
+				Transport *gosip.TransportConfig `json:"transport"`
+			}
+			if err := json.Unmarshal(data, &written); err != nil {
+				t.Fatal(err)
+			}
+			if written.Transport == nil || written.Transport.ProxyURL != "http://proxy:8080" {
+				t.Error("transport settings are not written")
+			}
+			if written.Transport != nil && written.Transport.ProxyPassword == "pass" {
+				t.Error("proxy password should be encoded")
+			}
+		})
+	}
+}
+
+func TestAuthSessionRenewer(t *testing.T) {
+	loginPages := map[string]string{
+		"fba":  "https://sp.contoso.com/_login/default.aspx?ReturnUrl=%2f_api%2fweb",
+		"tmg":  "https://sp.contoso.com/CookieAuth.dll?GetLogon?curl=Z2F_apiZ2Fweb",
+		"adfs": "https://sp.contoso.com/_trust/default.aspx?trust=ADFS",
+		"saml": "https://contoso.sharepoint.com/_forms/default.aspx?ReturnUrl=%2f_api%2fweb",
+	}
+	for strategy, loginPage := range loginPages {
+		t.Run(strategy, func(t *testing.T) {
+			cnfg, err := NewAuthByStrategy(strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			renewer, ok := cnfg.(gosip.SessionRenewer)
+			if !ok {
+				t.Fatal("strategy should support session renewal")
+			}
+			resp := &http.Response{StatusCode: 302, Header: http.Header{"Location": {loginPage}}}
+			if !renewer.IsSessionExpired(resp) {
+				t.Errorf("sign in redirect is not detected: %s", loginPage)
+			}
+			resp = &http.Response{StatusCode: 302, Header: http.Header{"Location": {"https://sp.contoso.com/sites/test"}}}
+			if renewer.IsSessionExpired(resp) {
+				t.Error("regular redirect should not be treated as sign in")
+			}
+			if err := cnfg.ParseConfig([]byte(`{"siteUrl":"https://sp.contoso.com","username":"user","password":"pass"}`)); err != nil {
+				t.Fatal(err)
+			}
+			if err := renewer.CleanAuthCache(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
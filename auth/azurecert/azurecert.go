@@ -14,8 +14,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/go-autorest/autorest"
@@ -23,6 +24,7 @@ import (
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 	"github.com/patrickmn/go-cache"
 )
 
@@ -46,15 +48,18 @@ var (
 //  - *.sharepoint.cn   -> login.chinacloudapi.cn (China)
 //  - *.sharepoint.de   -> login.microsoftonline.de (Germany)
 type AuthCnfg struct {
-	SiteURL  string `json:"siteUrl"`  // SPSite or SPWeb URL, which is the context target for the API calls
-	TenantID string `json:"tenantId"` // Azure Tenant ID
-	ClientID string `json:"clientId"` // Azure Client ID
-	CertPath string `json:"certPath"` // Azure certificate (.pfx) file location, relative to config location or absolute
-	CertPass string `json:"certPass"` // Azure certificate export password
+	SiteURL   string                 `json:"siteUrl" required:"true"`  // SPSite or SPWeb URL, which is the context target for the API calls
+	TenantID  string                 `json:"tenantId" required:"true"` // Azure Tenant ID
+	ClientID  string                 `json:"clientId" required:"true"` // Azure Client ID
+	CertPath  string                 `json:"certPath" required:"true"` // Azure certificate (.pfx) file location, relative to config location or absolute
+	CertPass  string                 `json:"certPass" secret:"true"`   // Azure certificate export password
+	Transport *gosip.TransportConfig `json:"transport,omitempty"`      // HTTP transport settings (optional)
 
-	authorizer  autorest.Authorizer
+	authorizers map[string]autorest.Authorizer // authorizers by resource
+	mux         sync.Mutex
 	privateFile string
 	masterKey   string
+	secrets     secret.Refs
 }
 
 // ReadConfig reads private config with auth options
@@ -74,19 +79,31 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
-	c.CertPath = path.Join(path.Dir(c.privateFile), c.CertPath)
-	crypt := cpass.Cpass(c.masterKey)
+	if err := c.secrets.Resolve(&c.CertPass); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(c.CertPath) {
+		c.CertPath = filepath.Join(filepath.Dir(c.privateFile), c.CertPath)
+	}
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	secret, err := crypt.Decode(c.CertPass)
 	if err == nil {
 		c.CertPass = secret
 	}
-	return nil
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
 }
 
-// WriteConfig writes private config with auth options
+// WriteConfig writes private config with auth options,
+// the config includes "strategy" property, so it can be loaded with auth.NewAuthFromFile
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	crypt := cpass.Cpass(c.masterKey)
-	secret, err := crypt.Encode(c.CertPass)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	secret, err := c.secrets.Encode(&c.CertPass, crypt.Encode)
 	if err != nil {
 		return err
 	}
@@ -97,7 +114,15 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		CertPath: c.CertPath,
 		CertPass: secret,
 	}
-	file, _ := json.MarshalIndent(config, "", "  ")
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
+	file, _ := json.MarshalIndent(struct {
+		Strategy string `json:"strategy"`
+		*AuthCnfg
+	}{c.GetStrategy(), config}, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
 
@@ -106,9 +131,27 @@ func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
 
 // GetAuth authenticates, receives access token
 func (c *AuthCnfg) GetAuth() (string, int64, error) {
-	if c.authorizer == nil {
+	return c.GetResourceAuth(gosip.ResourceOf(c.SiteURL))
+}
+
+// GetResourceAuth receives access token for the resource, e.g. admin site host or "https://graph.microsoft.com"
+func (c *AuthCnfg) GetResourceAuth(resource string) (string, int64, error) {
+	resource = gosip.ResourceOf(resource)
+	return c.secrets.RetryReset(func() (string, int64, error) { return c.getAuth(resource) }, func() {
+		c.mux.Lock()
+		c.authorizers = nil // re-create the authorizers with a rotated secret
+		c.mux.Unlock()
+	})
+}
+
+// getAuth receives access token with the resource authorizer
+func (c *AuthCnfg) getAuth(resource string) (string, int64, error) {
+	c.mux.Lock()
+	authorizer, ok := c.authorizers[resource]
+	c.mux.Unlock()
+
+	if !ok {
 		u, _ := url.Parse(c.SiteURL)
-		resource := fmt.Sprintf("https://%s", u.Host)
 
 		config := auth.NewClientCertificateConfig(c.CertPath, c.CertPass, c.ClientID, c.TenantID)
 		config.Resource = resource
@@ -116,14 +159,28 @@ func (c *AuthCnfg) GetAuth() (string, int64, error) {
 		// Auto-detect Azure AD endpoint from SharePoint URL
 		config.AADEndpoint = getAADEndpoint(u.Host)
 
-		authorizer, err := config.Authorizer()
+		spt, err := config.ServicePrincipalToken()
 		if err != nil {
-			return "", 0, err
+			return "", 0, fmt.Errorf("failed to get oauth token from certificate auth: %v", err)
+		}
+		if c.Transport != nil {
+			client, err := c.Transport.NewClient()
+			if err != nil {
+				return "", 0, err
+			}
+			spt.SetSender(client)
 		}
-		c.authorizer = authorizer
+		authorizer = autorest.NewBearerAuthorizer(spt)
+
+		c.mux.Lock()
+		if c.authorizers == nil {
+			c.authorizers = map[string]autorest.Authorizer{}
+		}
+		c.authorizers[resource] = authorizer
+		c.mux.Unlock()
 	}
 
-	return c.getToken()
+	return c.getToken(authorizer, resource)
 }
 
 // getAADEndpoint returns the Azure AD endpoint based on SharePoint domain
@@ -146,6 +203,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "azurecert" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticates request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
@@ -153,26 +213,21 @@ func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error
 	if err != nil {
 		return err
 	}
-	// _, err := c.authorizer.WithAuthorization()(preparer{}).Prepare(req)
 	req.Header.Set("Authorization", "Bearer "+authToken)
 	return err
 }
 
 // Getting token with prepare for external usage scenarious
-func (c *AuthCnfg) getToken() (string, int64, error) {
+func (c *AuthCnfg) getToken(authorizer autorest.Authorizer, resource string) (string, int64, error) {
 	// Get from cache
-	parsedURL, err := url.Parse(c.SiteURL)
-	if err != nil {
-		return "", 0, err
-	}
-	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.TenantID + "@" + c.ClientID
+	cacheKey := resource + "@" + c.GetStrategy() + "@" + c.TenantID + "@" + c.ClientID
 	if accessToken, exp, found := storage.GetWithExpiration(cacheKey); found {
 		return accessToken.(string), exp.Unix(), nil
 	}
 
 	// Get token
 	req, _ := http.NewRequest("GET", c.SiteURL, nil)
-	req, err = c.authorizer.WithAuthorization()(preparer{}).Prepare(req)
+	req, err := authorizer.WithAuthorization()(preparer{}).Prepare(req)
 	if err != nil {
 		return "", 0, err
 	}
@@ -2,6 +2,7 @@ package azurecert
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	h "github.com/koltyakov/gosip/test/helpers"
@@ -74,6 +75,10 @@ func TestAuthEdgeCases(t *testing.T) {
 		if err := cnfg.WriteConfig(filePath); err != nil {
 			t.Error(err)
 		}
+		data, _ := os.ReadFile(filePath)
+		if !strings.Contains(string(data), `"strategy": "azurecert"`) {
+			t.Errorf("strategy property is expected in the config:\n%s", data)
+		}
 		_ = os.RemoveAll(filePath)
 	})
 }
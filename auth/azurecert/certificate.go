@@ -0,0 +1,130 @@
+package azurecert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// CertificateOptions - self-signed certificate options
+type CertificateOptions struct {
+	CommonName string // Certificate subject common name, "gosip" by default
+	ValidDays  int    // Validity period in days, 365 by default
+	KeySize    int    // RSA key size in bits, 2048 by default
+}
+
+// Certificate - self-signed certificate for an Azure AD app registration
+type Certificate struct {
+	Certificate *x509.Certificate
+	PrivateKey  *rsa.PrivateKey
+}
+
+// KeyCredential - Azure AD app manifest `keyCredentials` item
+type KeyCredential struct {
+	CustomKeyIdentifier string `json:"customKeyIdentifier"` // Base64 encoded SHA-1 thumbprint
+	KeyID               string `json:"keyId"`
+	Type                string `json:"type"`
+	Usage               string `json:"usage"`
+	Value               string `json:"value"` // Base64 encoded DER certificate
+}
+
+// GenerateCertificate generates a self-signed RSA certificate to use with azurecert strategy
+func GenerateCertificate(opts CertificateOptions) (*Certificate, error) {
+	if opts.CommonName == "" {
+		opts.CommonName = "gosip"
+	}
+	if opts.ValidDays == 0 {
+		opts.ValidDays = 365
+	}
+	if opts.KeySize == 0 {
+		opts.KeySize = 2048
+	}
+	if opts.ValidDays < 0 {
+		return nil, fmt.Errorf("invalid validity period: %d days", opts.ValidDays)
+	}
+	if opts.KeySize < 2048 {
+		return nil, fmt.Errorf("key size must be at least 2048 bits, got %d", opts.KeySize)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, opts.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now().Add(-5 * time.Minute).UTC()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.CommonName},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(0, 0, opts.ValidDays),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Certificate{Certificate: cert, PrivateKey: key}, nil
+}
+
+// PFX encodes the certificate and the private key to a password-protected PKCS#12 container
+// Legacy 3DES encryption is used for compatibility with the strategy PFX decoder.
+func (c *Certificate) PFX(password string) ([]byte, error) {
+	return pkcs12.LegacyDES.Encode(c.PrivateKey, c.Certificate, nil, password)
+}
+
+// CertPEM encodes the certificate in PEM format, e.g. to upload to an app registration
+func (c *Certificate) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate.Raw})
+}
+
+// KeyPEM encodes the private key in PKCS#8 PEM format
+func (c *Certificate) KeyPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(c.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Thumbprint gets SHA-1 certificate thumbprint in upper case hex, as shown in Azure Portal
+func (c *Certificate) Thumbprint() string {
+	hash := sha1.Sum(c.Certificate.Raw)
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+// KeyCredential gets the app manifest `keyCredentials` item for the certificate
+func (c *Certificate) KeyCredential() KeyCredential {
+	hash := sha1.Sum(c.Certificate.Raw)
+	return KeyCredential{
+		CustomKeyIdentifier: base64.StdEncoding.EncodeToString(hash[:]),
+		KeyID:               uuid.New().String(),
+		Type:                "AsymmetricX509Cert",
+		Usage:               "Verify",
+		Value:               base64.StdEncoding.EncodeToString(c.Certificate.Raw),
+	}
+}
@@ -0,0 +1,79 @@
+package azurecert
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	cert, err := GenerateCertificate(CertificateOptions{CommonName: "gosip-test", ValidDays: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cert.Certificate.Subject.CommonName != "gosip-test" || cert.PrivateKey.N.BitLen() != 2048 {
+		t.Errorf("unexpected certificate: %s, %d bits", cert.Certificate.Subject, cert.PrivateKey.N.BitLen())
+	}
+	if validity := cert.Certificate.NotAfter.Sub(cert.Certificate.NotBefore); validity != 30*24*time.Hour {
+		t.Errorf("unexpected validity: %s", validity)
+	}
+
+	t.Run("PFX", func(t *testing.T) {
+		pfx, err := cert.PFX("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The same decoder is used by the strategy
+		decoded, key, err := adal.DecodePfxCertificateData(pfx, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(cert.Certificate) || !key.Equal(cert.PrivateKey) {
+			t.Error("decoded PFX doesn't match the certificate")
+		}
+		if _, _, err := adal.DecodePfxCertificateData(pfx, "wrong"); err == nil {
+			t.Error("wrong password should not pass")
+		}
+	})
+
+	t.Run("PEM", func(t *testing.T) {
+		if block, _ := pem.Decode(cert.CertPEM()); block == nil || block.Type != "CERTIFICATE" {
+			t.Error("unexpected certificate PEM")
+		}
+		keyPEM, err := cert.KeyPEM()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block, _ := pem.Decode(keyPEM); block == nil || block.Type != "PRIVATE KEY" {
+			t.Error("unexpected private key PEM")
+		}
+	})
+
+	t.Run("KeyCredential", func(t *testing.T) {
+		hash := sha1.Sum(cert.Certificate.Raw)
+		cred := cert.KeyCredential()
+		if cred.CustomKeyIdentifier != base64.StdEncoding.EncodeToString(hash[:]) || cred.Type != "AsymmetricX509Cert" || cred.Usage != "Verify" {
+			t.Errorf("unexpected key credential: %+v", cred)
+		}
+		if cred.Value != base64.StdEncoding.EncodeToString(cert.Certificate.Raw) || cred.KeyID == "" {
+			t.Errorf("unexpected key credential value: %+v", cred)
+		}
+		if len(cert.Thumbprint()) != 40 {
+			t.Errorf("unexpected thumbprint: %s", cert.Thumbprint())
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		if _, err := GenerateCertificate(CertificateOptions{KeySize: 1024}); err == nil {
+			t.Error("weak key should not pass")
+		}
+		if _, err := GenerateCertificate(CertificateOptions{ValidDays: -1}); err == nil {
+			t.Error("negative validity should not pass")
+		}
+	})
+}
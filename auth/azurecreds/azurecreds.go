@@ -12,15 +12,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 	"github.com/patrickmn/go-cache"
 )
 
@@ -40,18 +41,23 @@ var (
 }
 */
 type AuthCnfg struct {
-	SiteURL  string `json:"siteUrl"`  // SPSite or SPWeb URL, which is the context target for the API calls
-	TenantID string `json:"tenantId"` // Azure Tenant ID
-	ClientID string `json:"clientId"` // Azure Client ID
-	Username string `json:"username"` // AAD user name
-	Password string `json:"password"` // AAD user password
-
-	authorizer autorest.Authorizer
-	masterKey  string
+	SiteURL   string                 `json:"siteUrl" required:"true"`                // SPSite or SPWeb URL, which is the context target for the API calls
+	TenantID  string                 `json:"tenantId" required:"true"`               // Azure Tenant ID
+	ClientID  string                 `json:"clientId" required:"true"`               // Azure Client ID
+	Username  string                 `json:"username" required:"true"`               // AAD user name
+	Password  string                 `json:"password" required:"true" secret:"true"` // AAD user password
+	Transport *gosip.TransportConfig `json:"transport,omitempty"`                    // HTTP transport settings (optional)
+
+	privateFile string
+	authorizers map[string]autorest.Authorizer // authorizers by resource
+	mux         sync.Mutex
+	masterKey   string
+	secrets     secret.Refs
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	f, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -66,18 +72,27 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
-	crypt := cpass.Cpass(c.masterKey)
+	if err := c.secrets.Resolve(&c.Password); err != nil {
+		return err
+	}
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	secret, err := crypt.Decode(c.Password)
 	if err == nil {
 		c.Password = secret
 	}
-	return nil
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
 }
 
 // WriteConfig writes private config with auth options
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	crypt := cpass.Cpass(c.masterKey)
-	secret, err := crypt.Encode(c.Password)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	secret, err := c.secrets.Encode(&c.Password, crypt.Encode)
 	if err != nil {
 		return err
 	}
@@ -88,6 +103,11 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		Username: c.Username,
 		Password: secret,
 	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
@@ -97,27 +117,51 @@ func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
 
 // GetAuth authenticates, receives access token
 func (c *AuthCnfg) GetAuth() (string, int64, error) {
-	if c.authorizer == nil {
-		u, _ := url.Parse(c.SiteURL)
-		resource := fmt.Sprintf("https://%s", u.Host)
+	return c.GetResourceAuth(gosip.ResourceOf(c.SiteURL))
+}
 
+// GetResourceAuth receives access token for the resource, e.g. admin site host or "https://graph.microsoft.com"
+func (c *AuthCnfg) GetResourceAuth(resource string) (string, int64, error) {
+	resource = gosip.ResourceOf(resource)
+	return c.secrets.RetryReset(func() (string, int64, error) { return c.getAuth(resource) }, func() {
+		c.mux.Lock()
+		c.authorizers = nil // re-create the authorizers with a rotated secret
+		c.mux.Unlock()
+	})
+}
+
+// getAuth receives access token with the resource authorizer
+func (c *AuthCnfg) getAuth(resource string) (string, int64, error) {
+	c.mux.Lock()
+	authorizer, ok := c.authorizers[resource]
+	c.mux.Unlock()
+
+	if !ok {
 		config := auth.NewUsernamePasswordConfig(c.Username, c.Password, c.ClientID, c.TenantID)
 		config.Resource = resource
 
-		authorizer, err := config.Authorizer()
+		spt, err := config.ServicePrincipalToken()
 		if err != nil {
-			return "", 0, err
+			return "", 0, fmt.Errorf("failed to get oauth token from username and password auth: %v", err)
 		}
-		c.authorizer = authorizer
-	}
+		if c.Transport != nil {
+			client, err := c.Transport.NewClient()
+			if err != nil {
+				return "", 0, err
+			}
+			spt.SetSender(client)
+		}
+		authorizer = autorest.NewBearerAuthorizer(spt)
 
-	// token, err := config.ServicePrincipalToken()
-	// if err != nil {
-	// 	return "", 0, err
-	// }
-	// return token.Token().AccessToken, token.Token().Expires().Unix(), nil
+		c.mux.Lock()
+		if c.authorizers == nil {
+			c.authorizers = map[string]autorest.Authorizer{}
+		}
+		c.authorizers[resource] = authorizer
+		c.mux.Unlock()
+	}
 
-	return c.getToken()
+	return c.getToken(authorizer, resource)
 }
 
 // GetSiteURL gets SharePoint siteURL
@@ -126,6 +170,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "azurecreds" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticates request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
@@ -133,26 +180,21 @@ func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error
 	if err != nil {
 		return err
 	}
-	// _, err := c.authorizer.WithAuthorization()(preparer{}).Prepare(req)
 	req.Header.Set("Authorization", "Bearer "+authToken)
 	return err
 }
 
 // Getting token with prepare for external usage scenarious
-func (c *AuthCnfg) getToken() (string, int64, error) {
+func (c *AuthCnfg) getToken(authorizer autorest.Authorizer, resource string) (string, int64, error) {
 	// Get from cache
-	parsedURL, err := url.Parse(c.SiteURL)
-	if err != nil {
-		return "", 0, err
-	}
-	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.TenantID + "@" + c.ClientID + "@" + c.Username + "@" + c.Password
+	cacheKey := resource + "@" + c.GetStrategy() + "@" + c.TenantID + "@" + c.ClientID + "@" + c.Username + "@" + c.Password
 	if accessToken, exp, found := storage.GetWithExpiration(cacheKey); found {
 		return accessToken.(string), exp.Unix(), nil
 	}
 
 	// Get token
 	req, _ := http.NewRequest("GET", c.SiteURL, nil)
-	req, err = c.authorizer.WithAuthorization()(preparer{}).Prepare(req)
+	req, err := authorizer.WithAuthorization()(preparer{}).Prepare(req)
 	if err != nil {
 		return "", 0, err
 	}
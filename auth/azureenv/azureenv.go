@@ -20,10 +20,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
@@ -38,10 +38,11 @@ import (
 { "siteUrl": "https://contoso.sharepoint.com/sites/test" }
 */
 type AuthCnfg struct {
-	SiteURL string            `json:"siteUrl"` // SPSite or SPWeb URL, which is the context target for the API calls
-	Env     map[string]string `json:"env"`     // AZURE_ environment variables
+	SiteURL string            `json:"siteUrl" required:"true"` // SPSite or SPWeb URL, which is the context target for the API calls
+	Env     map[string]string `json:"env"`                     // AZURE_ environment variables
 
-	authorizer  autorest.Authorizer
+	authorizers map[string]autorest.Authorizer // authorizers by resource
+	mux         sync.Mutex
 	privateFile string
 	masterKey   string
 }
@@ -63,7 +64,10 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
-	crypt := cpass.Cpass(c.masterKey)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	for key, val := range c.Env {
 		if key == "AZURE_AUTH_LOCATION" || key == "AZURE_CERTIFICATE_PATH" {
 			c.Env[key] = path.Join(path.Dir(c.privateFile), val)
@@ -90,19 +94,36 @@ func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
 
 // GetAuth authenticates, receives access token
 func (c *AuthCnfg) GetAuth() (string, int64, error) {
-	if c.authorizer == nil {
-		u, _ := url.Parse(c.SiteURL)
-		resource := fmt.Sprintf("https://%s", u.Host)
-
-		// authorizer, err := auth.NewAuthorizerFromEnvironmentWithResource(resource)
-		authorizer, err := c.newAuthorizerWithEnvVars(auth.NewAuthorizerFromEnvironmentWithResource, resource, c.Env)
-		if err != nil {
-			return "", 0, err
-		}
-		c.authorizer = authorizer
+	return c.GetResourceAuth(gosip.ResourceOf(c.SiteURL))
+}
+
+// GetResourceAuth receives access token for the resource, e.g. admin site host or "https://graph.microsoft.com"
+func (c *AuthCnfg) GetResourceAuth(resource string) (string, int64, error) {
+	authorizer, err := c.getAuthorizer(gosip.ResourceOf(resource))
+	if err != nil {
+		return "", 0, err
+	}
+	return c.getToken(authorizer)
+}
+
+// getAuthorizer gets or creates the resource authorizer
+func (c *AuthCnfg) getAuthorizer(resource string) (autorest.Authorizer, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if authorizer, ok := c.authorizers[resource]; ok {
+		return authorizer, nil
 	}
 
-	return c.getToken()
+	// authorizer, err := auth.NewAuthorizerFromEnvironmentWithResource(resource)
+	authorizer, err := c.newAuthorizerWithEnvVars(auth.NewAuthorizerFromEnvironmentWithResource, resource, c.Env)
+	if err != nil {
+		return nil, err
+	}
+	if c.authorizers == nil {
+		c.authorizers = map[string]autorest.Authorizer{}
+	}
+	c.authorizers[resource] = authorizer
+	return authorizer, nil
 }
 
 // GetSiteURL gets SharePoint siteURL
@@ -114,10 +135,11 @@ func (c *AuthCnfg) GetStrategy() string { return "azureenv" }
 // SetAuth authenticates request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
-	if _, _, err := c.GetAuth(); err != nil {
+	authorizer, err := c.getAuthorizer(gosip.ResourceOf(c.SiteURL))
+	if err != nil {
 		return err
 	}
-	_, err := c.authorizer.WithAuthorization()(preparer{}).Prepare(req)
+	_, err = authorizer.WithAuthorization()(preparer{}).Prepare(req)
 	return err
 }
 
@@ -154,9 +176,9 @@ func (c *AuthCnfg) newAuthorizerWithEnvVars(
 }
 
 // Getting token with prepare for external usage scenarious
-func (c *AuthCnfg) getToken() (string, int64, error) {
+func (c *AuthCnfg) getToken(authorizer autorest.Authorizer) (string, int64, error) {
 	req, _ := http.NewRequest("GET", c.SiteURL, nil)
-	req, err := c.authorizer.WithAuthorization()(preparer{}).Prepare(req)
+	req, err := authorizer.WithAuthorization()(preparer{}).Prepare(req)
 	if err != nil {
 		return "", 0, err
 	}
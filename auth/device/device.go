@@ -8,11 +8,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/koltyakov/gosip"
@@ -20,8 +23,8 @@ import (
 )
 
 var (
-	tokenCache = map[string]*adal.ServicePrincipalToken{} // ToDo: Replace with sync.Map
-	crypter    = cpass.Cpass("")
+	tokenCache = map[string]*adal.ServicePrincipalToken{}
+	tokenMux   sync.Mutex
 )
 
 // AuthCnfg - AAD Device Flow auth config structure
@@ -33,13 +36,19 @@ var (
 }
 */
 type AuthCnfg struct {
-	SiteURL  string `json:"siteUrl"`  // SPSite or SPWeb URL, which is the context target for the API calls
-	ClientID string `json:"clientId"` // Azure AD App Registration Client ID
-	TenantID string `json:"tenantId"` // Azure AD App Registration Tenant ID
+	SiteURL   string                 `json:"siteUrl" required:"true"`  // SPSite or SPWeb URL, which is the context target for the API calls
+	ClientID  string                 `json:"clientId" required:"true"` // Azure AD App Registration Client ID
+	TenantID  string                 `json:"tenantId" required:"true"` // Azure AD App Registration Tenant ID
+	Transport *gosip.TransportConfig `json:"transport,omitempty"`      // HTTP transport settings (optional)
+
+	client      *http.Client // token requests client, created once from transport settings
+	mux         sync.Mutex
+	privateFile string
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	f, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -51,7 +60,10 @@ func (c *AuthCnfg) ReadConfig(privateFile string) error {
 
 // ParseConfig parses credentials from a provided JSON byte array content
 func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
-	return json.Unmarshal(byteValue, &c)
+	if err := json.Unmarshal(byteValue, &c); err != nil {
+		return err
+	}
+	return c.Transport.Prepare(c.privateFile, "")
 }
 
 // WriteConfig writes private config with auth options
@@ -61,50 +73,101 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		ClientID: c.ClientID,
 		TenantID: c.TenantID,
 	}
+	transport, err := c.Transport.Encode("")
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
 
 // GetAuth authenticates, receives access token
 func (c *AuthCnfg) GetAuth() (string, int64, error) {
-	u, _ := url.Parse(c.SiteURL)
-	resource := fmt.Sprintf("https://%s", u.Host)
+	return c.GetResourceAuth(gosip.ResourceOf(c.SiteURL))
+}
+
+// getClient gets or creates HTTP client for token requests
+func (c *AuthCnfg) getClient() (*http.Client, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.client == nil {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+	}
+	return c.client, nil
+}
+
+// GetResourceAuth receives access token for the resource, e.g. admin site host or "https://graph.microsoft.com",
+// the site token is exchanged for other resources, so the device flow is not repeated
+func (c *AuthCnfg) GetResourceAuth(resource string) (string, int64, error) {
+	token, err := c.resourceToken(gosip.ResourceOf(resource))
+	if err != nil {
+		return "", 0, err
+	}
+	return token.Token().AccessToken, token.Token().Expires().Unix(), nil
+}
+
+// resourceToken gets cached, refreshed, exchanged or new device flow token for the resource,
+// tokens read from the disk cache or received are kept in memory
+func (c *AuthCnfg) resourceToken(resource string) (*adal.ServicePrincipalToken, error) {
+	client, err := c.getClient()
+	if err != nil {
+		return nil, err
+	}
 
 	// Check cached token per resource
-	token := tokenCache[resource]
+	key := c.cacheKey(resource)
+	token := getCachedToken(key)
 
 	// Check disk cache
 	if token == nil {
-		token, _ = c.getTokenDiskCache()
+		if token, _ = c.getTokenDiskCache(resource); token != nil {
+			token.SetSender(client)
+			setCachedToken(key, token)
+		}
 	}
 
 	if token != nil {
 		// Return cached token if not expired
 		if !token.Token().IsExpired() {
-			return token.Token().AccessToken, token.Token().Expires().Unix(), nil
+			return token, nil
 		}
 		// Expired, try to refresh
 		if err := token.Refresh(); err == nil {
 			// Cache refreshed token
-			_ = c.cacheTokenToDisk(token)
-			// Return refreshed token
-			return token.Token().AccessToken, token.Token().Expires().Unix(), nil
+			_ = c.cacheTokenToDisk(resource, token)
+			return token, nil
 		}
 		// Failed to refresh, initiating for the device auth flow
+		token = nil
 	}
 
 	config := auth.NewDeviceFlowConfig(c.ClientID, c.TenantID)
 	config.Resource = resource
 
-	token, err := config.ServicePrincipalToken()
-	if err != nil {
-		return "", 0, err
+	// Exchange the site token for another resource
+	if siteResource := gosip.ResourceOf(c.SiteURL); resource != siteResource {
+		siteToken, err := c.resourceToken(siteResource)
+		if err != nil {
+			return nil, err
+		}
+		token, _ = exchangeToken(config, siteToken, client)
 	}
 
-	_ = c.cacheTokenToDisk(token)
+	if token == nil {
+		if token, err = deviceFlowToken(config, client); err != nil {
+			return nil, err
+		}
+	}
 
-	tokenCache[resource] = token
-	return token.Token().AccessToken, token.Token().Expires().Unix(), nil
+	_ = c.cacheTokenToDisk(resource, token)
+
+	setCachedToken(key, token)
+	return token, nil
 }
 
 // GetSiteURL gets SharePoint siteURL
@@ -113,6 +176,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "device" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticates request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
@@ -124,29 +190,111 @@ func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error
 	return nil
 }
 
+// deviceFlowToken runs device auth flow sending requests with the client
+func deviceFlowToken(config auth.DeviceFlowConfig, client *http.Client) (*adal.ServicePrincipalToken, error) {
+	oauthConfig, err := adal.NewOAuthConfig(config.AADEndpoint, config.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	oauthClient := &autorest.Client{Sender: client}
+	deviceCode, err := adal.InitiateDeviceAuth(oauthClient, *oauthConfig, config.ClientID, config.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device auth flow: %s", err)
+	}
+	log.Println(*deviceCode.Message)
+	token, err := adal.WaitForUserCompletion(oauthClient, deviceCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish device auth flow: %s", err)
+	}
+	spt, err := adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, config.ClientID, config.Resource, *token)
+	if err != nil {
+		return nil, err
+	}
+	spt.SetSender(client)
+	return spt, nil
+}
+
+// exchangeToken receives a token for the config resource with the refresh token of another resource token
+func exchangeToken(config auth.DeviceFlowConfig, base *adal.ServicePrincipalToken, client *http.Client) (*adal.ServicePrincipalToken, error) {
+	oauthConfig, err := adal.NewOAuthConfig(config.AADEndpoint, config.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return nil, fmt.Errorf("no token to exchange for %s", config.Resource)
+	}
+	// Exchange on a copy, the base token keeps its resource
+	data, err := base.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	exchange := &adal.ServicePrincipalToken{}
+	if err := exchange.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	exchange.SetSender(client)
+	if err := exchange.RefreshExchange(config.Resource); err != nil {
+		return nil, err
+	}
+	spt, err := adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, config.ClientID, config.Resource, exchange.Token())
+	if err != nil {
+		return nil, err
+	}
+	spt.SetSender(client)
+	return spt, nil
+}
+
 // === File system token caching helpers === //
 
 // CleanTokenCache removes token information
 func (c *AuthCnfg) CleanTokenCache() error {
-	tokenCachePath := c.getTokenCachePath()
+	tokenCachePath := c.getTokenCachePath(gosip.ResourceOf(c.SiteURL))
 
-	delete(tokenCache, c.ClientID)
+	tokenMux.Lock()
+	delete(tokenCache, c.cacheKey(gosip.ResourceOf(c.SiteURL)))
+	tokenMux.Unlock()
 	if err := os.Remove(tokenCachePath); err != nil {
 		return err
 	}
 	return nil
 }
 
+// cacheKey gets in-memory token cache key, tokens are cached per app registration and resource
+func (c *AuthCnfg) cacheKey(resource string) string {
+	return c.TenantID + "/" + c.ClientID + "/" + resource
+}
+
+// getCachedToken gets token from in-memory cache
+func getCachedToken(key string) *adal.ServicePrincipalToken {
+	tokenMux.Lock()
+	defer tokenMux.Unlock()
+	return tokenCache[key]
+}
+
+// setCachedToken puts token to in-memory cache
+func setCachedToken(key string, token *adal.ServicePrincipalToken) {
+	tokenMux.Lock()
+	defer tokenMux.Unlock()
+	tokenCache[key] = token
+}
+
 // cacheTokenToDisk writes serialized token to temporary cache file
-func (c *AuthCnfg) cacheTokenToDisk(token *adal.ServicePrincipalToken) error {
+func (c *AuthCnfg) cacheTokenToDisk(resource string, token *adal.ServicePrincipalToken) error {
 	tmpDir := filepath.Join(os.TempDir(), "gosip")
-	tokenCachePath := c.getTokenCachePath()
+	tokenCachePath := c.getTokenCachePath(resource)
 
 	tokenCache, err := token.MarshalJSON()
 	if err != nil {
 		return err
 	}
-	tokenCacheE, _ := crypter.Encode(string(tokenCache))
+	crypt, err := cpass.New("")
+	if err != nil {
+		return err
+	}
+	tokenCacheE, err := crypt.Encode(string(tokenCache))
+	if err != nil {
+		return err
+	}
 	tokenCache = []byte(tokenCacheE)
 
 	_ = os.MkdirAll(tmpDir, os.ModePerm)
@@ -157,14 +305,18 @@ func (c *AuthCnfg) cacheTokenToDisk(token *adal.ServicePrincipalToken) error {
 }
 
 // getTokenDiskCache reads token from temporary cache file
-func (c *AuthCnfg) getTokenDiskCache() (*adal.ServicePrincipalToken, error) {
-	tokenCachePath := c.getTokenCachePath()
+func (c *AuthCnfg) getTokenDiskCache(resource string) (*adal.ServicePrincipalToken, error) {
+	tokenCachePath := c.getTokenCachePath(resource)
 
 	tokenCache, err := os.ReadFile(tokenCachePath)
 	if err != nil {
 		return nil, err
 	}
-	tokenCacheD, _ := crypter.Decode(string(tokenCache))
+	crypt, err := cpass.New("")
+	if err != nil {
+		return nil, err
+	}
+	tokenCacheD, _ := crypt.Decode(string(tokenCache))
 	tokenCache = []byte(tokenCacheD)
 
 	token := &adal.ServicePrincipalToken{}
@@ -174,8 +326,12 @@ func (c *AuthCnfg) getTokenDiskCache() (*adal.ServicePrincipalToken, error) {
 	return token, nil
 }
 
-// getTokenCachePath gets local file system file path with token cache
-func (c *AuthCnfg) getTokenCachePath() string {
+// getTokenCachePath gets local file system file path with the resource token cache
+func (c *AuthCnfg) getTokenCachePath(resource string) string {
 	tmpDir := filepath.Join(os.TempDir(), "gosip")
-	return filepath.Join(tmpDir, c.GetStrategy()+"_"+c.ClientID)
+	name := c.GetStrategy() + "_" + c.ClientID
+	if resource != gosip.ResourceOf(c.SiteURL) {
+		name += "_" + strings.NewReplacer("://", "_", "/", "_", ":", "_").Replace(resource)
+	}
+	return filepath.Join(tmpDir, name)
 }
@@ -1,8 +1,17 @@
 package device
 
 import (
+	"encoding/json"
+	"io"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
 
 	h "github.com/koltyakov/gosip/test/helpers"
 	u "github.com/koltyakov/gosip/test/utils"
@@ -97,6 +106,96 @@ func TestAuthEdgeCases(t *testing.T) {
 		}
 		_ = os.RemoveAll(filePath)
 	})
+
+	t.Run("Client", func(t *testing.T) {
+		cnfg := &AuthCnfg{SiteURL: "test"}
+		first, err := cnfg.getClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second, _ := cnfg.getClient(); second != first {
+			t.Error("token requests client should be reused")
+		}
+	})
+}
+
+// tokenEndpoint stubs AAD token endpoint responses
+type tokenEndpoint struct{ requests int }
+
+func (e *tokenEndpoint) RoundTrip(req *http.Request) (*http.Response, error) {
+	e.requests++
+	_ = req.ParseForm()
+	expires := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	body, _ := json.Marshal(map[string]string{
+		"access_token":  "token for " + req.PostForm.Get("resource"),
+		"refresh_token": "refresh",
+		"expires_in":    "3600",
+		"expires_on":    expires,
+		"not_before":    expires,
+		"resource":      req.PostForm.Get("resource"),
+		"token_type":    "Bearer",
+	})
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(body))),
+		Request:    req,
+	}, nil
+}
+
+func TestResourceAuth(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	endpoint := &tokenEndpoint{}
+	cnfg := &AuthCnfg{
+		SiteURL:  "https://contoso.sharepoint.com/sites/test",
+		ClientID: "61367a97-562c-4372-a9ee-b35307abdd26",
+		TenantID: "3f83fe32-29b2-488e-8c3f-c8b7a2e19a2f",
+		client:   &http.Client{Transport: endpoint},
+	}
+
+	// Site token is only in the disk cache
+	oauthConfig, err := adal.NewOAuthConfig("https://login.microsoftonline.com/", cnfg.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siteToken, err := adal.NewServicePrincipalTokenFromManualToken(*oauthConfig, cnfg.ClientID, "https://contoso.sharepoint.com", adal.Token{
+		AccessToken:  "site token",
+		RefreshToken: "refresh",
+		ExpiresIn:    "3600",
+		ExpiresOn:    json.Number(strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)),
+		Resource:     "https://contoso.sharepoint.com",
+		Type:         "Bearer",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cnfg.cacheTokenToDisk("https://contoso.sharepoint.com", siteToken); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, _, err := cnfg.GetAuth(); err != nil || token != "site token" {
+				t.Errorf("disk cached site token is expected, got %s, %v", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	token, _, err := cnfg.GetResourceAuth("https://graph.microsoft.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token for https://graph.microsoft.com" {
+		t.Errorf("exchanged token is expected, got %s", token)
+	}
+	if _, _, err := cnfg.GetResourceAuth("https://graph.microsoft.com"); err != nil || endpoint.requests != 1 {
+		t.Errorf("exchanged token should be cached, token requests: %d, %v", endpoint.requests, err)
+	}
 }
 
 func TestCheckTransport(t *testing.T) {
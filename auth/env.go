@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+)
+
+// DefaultEnvPrefix is environment variables prefix used when no prefix is provided
+const DefaultEnvPrefix = "GOSIP"
+
+// NewAuthFromEnv resolves AuthCnfg object based on environment variables
+// `<PREFIX>_STRATEGY` defines the strategy, other variables are mapped to the strategy config
+// JSON properties: `<PREFIX>_` + upper cased property name, e.g. GOSIP_SITEURL, GOSIP_CLIENTID, GOSIP_CERTPATH.
+// Non-string properties (numbers, booleans, maps) are provided as JSON values.
+// Properties with `required:"true"` struct tag must be present, otherwise an error lists the missing variables.
+func NewAuthFromEnv(prefix string) (gosip.AuthCnfg, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+
+	strategy := os.Getenv(prefix + "STRATEGY")
+	if strategy == "" {
+		return nil, fmt.Errorf("missing environment variable: %sSTRATEGY", prefix)
+	}
+
+	auth, err := NewAuthByStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := configFields(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]interface{}{}
+	var missing []string
+	for _, field := range fields {
+		envName := prefix + strings.ToUpper(field.name)
+		value, ok := os.LookupEnv(envName)
+		if !ok || value == "" {
+			if field.required {
+				missing = append(missing, envName)
+			}
+			continue
+		}
+		if field.kind == reflect.String {
+			config[field.name] = value
+			continue
+		}
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("invalid value of %s environment variable, %s is expected", envName, field.kind)
+		}
+		config[field.name] = json.RawMessage(value)
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing environment variables for %s strategy: %s", strategy, strings.Join(missing, ", "))
+	}
+
+	byteValue, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := auth.ParseConfig(byteValue); err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// SecretFields gets JSON property names of the strategy config fields which hold secrets
+// Secret fields are marked with `secret:"true"` struct tag, nested settings secrets
+// are named with a dot separated path, e.g. "transport.proxyPassword".
+func SecretFields(strategy string) ([]string, error) {
+	auth, err := NewAuthByStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf(auth)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't map %s strategy config of %s type", auth.GetStrategy(), t.Kind())
+	}
+	return secretFields(t, ""), nil
+}
+
+// secretFields gets secret JSON property paths of a struct type including nested structs
+func secretFields(t reflect.Type, prefix string) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if f.Tag.Get("secret") == "true" {
+			names = append(names, prefix+name)
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			names = append(names, secretFields(ft, prefix+name+".")...)
+		}
+	}
+	return names
+}
+
+// configField - strategy config property description
+type configField struct {
+	name     string       // JSON property name
+	kind     reflect.Kind // Property value kind
+	required bool         // Property is required
+	secret   bool         // Property holds a secret
+}
+
+// configFields gets strategy config JSON properties from the struct tags
+func configFields(auth gosip.AuthCnfg) ([]configField, error) {
+	t := reflect.TypeOf(auth)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't map %s strategy config of %s type", auth.GetStrategy(), t.Kind())
+	}
+
+	var fields []configField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Type.Kind() == reflect.Func {
+			continue // unexported or callback
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		kind := f.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = f.Type.Elem().Kind()
+		}
+		fields = append(fields, configField{
+			name:     name,
+			kind:     kind,
+			required: f.Tag.Get("required") == "true",
+			secret:   f.Tag.Get("secret") == "true",
+		})
+	}
+
+	return fields, nil
+}
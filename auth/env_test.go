@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth/azurecert"
+	"github.com/koltyakov/gosip/auth/pkce"
+)
+
+type customAuth struct {
+	SiteURL string            `json:"siteUrl" required:"true"`
+	APIKey  string            `json:"apiKey" required:"true" secret:"true"`
+	Retries int               `json:"retries"`
+	Headers map[string]string `json:"headers"`
+}
+
+func (c *customAuth) ReadConfig(privateFile string) error  { return nil }
+func (c *customAuth) WriteConfig(privateFile string) error { return nil }
+func (c *customAuth) SetMasterkey(masterKey string)        {}
+func (c *customAuth) GetAuth() (string, int64, error)      { return c.APIKey, 0, nil }
+func (c *customAuth) GetSiteURL() string                   { return c.SiteURL }
+func (c *customAuth) GetStrategy() string                  { return "custom" }
+func (c *customAuth) ParseConfig(byteValue []byte) error   { return json.Unmarshal(byteValue, c) }
+func (c *customAuth) SetAuth(req *http.Request, _ *gosip.SPClient) error {
+	req.Header.Set("X-Api-Key", c.APIKey)
+	return nil
+}
+
+func TestAuthFromEnv(t *testing.T) {
+	t.Run("BuiltIn", func(t *testing.T) {
+		t.Setenv("GOSIP_STRATEGY", "azurecert")
+		t.Setenv("GOSIP_SITEURL", "https://contoso.sharepoint.com")
+		t.Setenv("GOSIP_TENANTID", "tenant")
+		t.Setenv("GOSIP_CLIENTID", "client")
+		t.Setenv("GOSIP_CERTPATH", "/certs/cert.pfx")
+		t.Setenv("GOSIP_CERTPASS", "pass")
+
+		auth, err := NewAuthFromEnv("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnfg := auth.(*azurecert.AuthCnfg)
+		if cnfg.SiteURL != "https://contoso.sharepoint.com" || cnfg.ClientID != "client" || cnfg.CertPath != "/certs/cert.pfx" || cnfg.CertPass != "pass" {
+			t.Errorf("unexpected config: %+v", cnfg)
+		}
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		t.Setenv("APP_SP_STRATEGY", "pkce")
+		t.Setenv("APP_SP_SITEURL", "https://contoso.sharepoint.com")
+		t.Setenv("APP_SP_CLIENTID", "client")
+		t.Setenv("APP_SP_REDIRECTPORT", "8400")
+
+		auth, err := NewAuthFromEnv("APP_SP")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port := auth.(*pkce.AuthCnfg).RedirectPort; port != 8400 {
+			t.Errorf("numeric property is not mapped: %d", port)
+		}
+	})
+
+	t.Run("SecretReference", func(t *testing.T) {
+		t.Setenv("GOSIP_STRATEGY", "azurecert")
+		t.Setenv("GOSIP_SITEURL", "https://contoso.sharepoint.com")
+		t.Setenv("GOSIP_TENANTID", "tenant")
+		t.Setenv("GOSIP_CLIENTID", "client")
+		t.Setenv("GOSIP_CERTPATH", "/certs/cert.pfx")
+		t.Setenv("GOSIP_CERTPASS", "env:SP_CERT_PASS")
+		t.Setenv("SP_CERT_PASS", "pass")
+
+		auth, err := NewAuthFromEnv("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pass := auth.(*azurecert.AuthCnfg).CertPass; pass != "pass" {
+			t.Errorf("secret reference is not resolved: %s", pass)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		t.Setenv("GOSIP_STRATEGY", "azurecert")
+		t.Setenv("GOSIP_SITEURL", "https://contoso.sharepoint.com")
+		t.Setenv("GOSIP_CLIENTID", "client")
+
+		_, err := NewAuthFromEnv("GOSIP")
+		if err == nil {
+			t.Fatal("missing variables should not pass")
+		}
+		if !strings.Contains(err.Error(), "GOSIP_TENANTID, GOSIP_CERTPATH") {
+			t.Errorf("missing variables are not listed: %s", err)
+		}
+	})
+
+	t.Run("NoStrategy", func(t *testing.T) {
+		t.Setenv("GOSIP_STRATEGY", "")
+		if _, err := NewAuthFromEnv(""); err == nil || !strings.Contains(err.Error(), "GOSIP_STRATEGY") {
+			t.Errorf("missing strategy should be reported: %v", err)
+		}
+	})
+
+	t.Run("InvalidValue", func(t *testing.T) {
+		t.Setenv("GOSIP_STRATEGY", "pkce")
+		t.Setenv("GOSIP_SITEURL", "https://contoso.sharepoint.com")
+		t.Setenv("GOSIP_CLIENTID", "client")
+		t.Setenv("GOSIP_TIMEOUT", "five")
+		if _, err := NewAuthFromEnv(""); err == nil || !strings.Contains(err.Error(), "GOSIP_TIMEOUT") {
+			t.Errorf("invalid value should be reported: %v", err)
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		RegisterStrategy("custom", func() gosip.AuthCnfg { return &customAuth{} })
+		defer RegisterStrategy("custom", nil)
+
+		t.Setenv("GOSIP_STRATEGY", "custom")
+		t.Setenv("GOSIP_SITEURL", "https://contoso.sharepoint.com")
+		t.Setenv("GOSIP_APIKEY", "key")
+		t.Setenv("GOSIP_HEADERS", `{"X-Env":"test"}`)
+
+		auth, err := NewAuthFromEnv("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnfg := auth.(*customAuth)
+		if cnfg.APIKey != "key" || cnfg.Headers["X-Env"] != "test" {
+			t.Errorf("unexpected config: %+v", cnfg)
+		}
+	})
+}
+
+func TestSecretFields(t *testing.T) {
+	fields, err := SecretFields("obo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(fields, ",") != "clientSecret,certPass,transport.proxyPassword" {
+		t.Errorf("unexpected secret fields: %v", fields)
+	}
+	if fields, _ := SecretFields("device"); strings.Join(fields, ",") != "transport.proxyPassword" {
+		t.Errorf("unexpected secret fields: %v", fields)
+	}
+	if _, err := SecretFields("unknown"); err == nil {
+		t.Error("unknown strategy should not pass")
+	}
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	RegisterStrategy("custom", func() gosip.AuthCnfg { return &customAuth{} })
+	if names := RegisteredStrategies(); len(names) != 1 || names[0] != "custom" {
+		t.Errorf("unexpected registered strategies: %v", names)
+	}
+	if auth, err := NewAuthByStrategy("custom"); err != nil || auth.GetStrategy() != "custom" {
+		t.Errorf("custom strategy is not resolved: %v", err)
+	}
+	RegisterStrategy("custom", nil)
+	if _, err := NewAuthByStrategy("custom"); err == nil {
+		t.Error("unregistered strategy should not pass")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("built-in strategy should not be overridden")
+			}
+		}()
+		RegisterStrategy("saml", func() gosip.AuthCnfg { return &customAuth{} })
+	}()
+	if names := RegisteredStrategies(); len(names) != 0 {
+		t.Errorf("built-in strategy should not be registered: %v", names)
+	}
+}
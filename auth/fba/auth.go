@@ -14,8 +14,16 @@ import (
 
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 )
 
+// loginPages are sign in pages SharePoint redirects to when the session is not valid anymore
+var loginPages = []string{
+	"/_login/",                       // FBA sign in page
+	"/_layouts/15/authenticate.aspx", // SharePoint 2013+ sign in redirect
+	"/_layouts/authenticate.aspx",    // SharePoint 2010 sign in redirect
+}
+
 // AuthCnfg - FBA auth config structure
 /* On-Premises config sample:
 {
@@ -25,16 +33,20 @@ import (
 }
 */
 type AuthCnfg struct {
-	SiteURL  string `json:"siteUrl"` // SPSite or SPWeb URL, which is the context target for the API calls
-	Username string `json:"username"`
-	Password string `json:"password"`
-
-	masterKey string
-	client    *http.Client
+	SiteURL   string                 `json:"siteUrl" required:"true"` // SPSite or SPWeb URL, which is the context target for the API calls
+	Username  string                 `json:"username" required:"true"`
+	Password  string                 `json:"password" required:"true" secret:"true"`
+	Transport *gosip.TransportConfig `json:"transport,omitempty"` // HTTP transport settings (optional)
+
+	privateFile string
+	masterKey   string
+	secrets     secret.Refs
+	client      *http.Client
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	jsonFile, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -50,20 +62,29 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
+	if err := c.secrets.Resolve(&c.Password); err != nil {
+		return err
+	}
 
-	crypt := cpass.Cpass(c.masterKey)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	pass, err := crypt.Decode(c.Password)
 	if err == nil {
 		c.Password = pass
 	}
 
-	return nil
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
 }
 
 // WriteConfig writes private config with auth options
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	crypt := cpass.Cpass(c.masterKey)
-	pass, err := crypt.Encode(c.Password)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	pass, err := c.secrets.Encode(&c.Password, crypt.Encode)
 	if err != nil {
 		pass = c.Password
 	}
@@ -72,6 +93,11 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		Username: c.Username,
 		Password: pass,
 	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
@@ -80,7 +106,9 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
 
 // GetAuth authenticates, receives access token
-func (c *AuthCnfg) GetAuth() (string, int64, error) { return GetAuth(c) }
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	return c.secrets.Retry(func() (string, int64, error) { return GetAuth(c) })
+}
 
 // GetSiteURL gets siteURL
 func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
@@ -88,6 +116,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "fba" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticate request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
@@ -101,3 +132,8 @@ func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error
 	req.Header.Set("Cookie", authCookie)
 	return nil
 }
+
+// IsSessionExpired checks if the response is a sign in page instead of the requested resource
+func (c *AuthCnfg) IsSessionExpired(resp *http.Response) bool {
+	return gosip.IsLoginResponse(resp, loginPages...)
+}
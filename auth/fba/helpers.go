@@ -22,7 +22,11 @@ var (
 // GetAuth gets authentication
 func GetAuth(c *AuthCnfg) (string, int64, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", 0, err
+		}
+		c.client = client
 	}
 
 	parsedURL, err := url.Parse(c.SiteURL)
@@ -94,3 +98,14 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 
 	return authCookie, exp, nil
 }
+
+// CleanAuthCache removes auth cache
+func (c *AuthCnfg) CleanAuthCache() error {
+	parsedURL, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return err
+	}
+	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.Username + "@" + c.Password
+	storage.Delete(cacheKey)
+	return nil
+}
@@ -0,0 +1,228 @@
+// Package federated implements AAD Workload Identity Federation Auth Flow
+// A projected federated token (Kubernetes service account token, GitHub OIDC token, etc.)
+// is exchanged for a SharePoint access token as a client assertion.
+// See more:
+//   - https://learn.microsoft.com/en-us/entra/workload-id/workload-identity-federation
+//   - https://azure.github.io/azure-workload-identity/docs/
+//
+// Amongst supported platform versions are:
+//   - SharePoint Online + Azure
+package federated
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/koltyakov/gosip"
+	"github.com/patrickmn/go-cache"
+)
+
+var (
+	storage = cache.New(5*time.Minute, 10*time.Minute)
+)
+
+// AuthCnfg - AAD Workload Identity Federation Auth Flow
+/* Config sample:
+{
+	"siteUrl": "https://contoso.sharepoint.com/sites/test",
+	"tenantId": "e4d43069-8ecb-49c4-8178-5bec83c53e9d",
+	"clientId": "628cc712-c9a4-48f0-a059-af64bdbb4be5",
+	"tokenFile": "/var/run/secrets/azure/tokens/azure-identity-token"
+}
+*/
+// Empty tenantId, clientId, tokenFile and authorityHost fall back to the variables
+// injected by the Azure Workload Identity webhook:
+//   - AZURE_TENANT_ID
+//   - AZURE_CLIENT_ID
+//   - AZURE_FEDERATED_TOKEN_FILE
+//   - AZURE_AUTHORITY_HOST
+//
+// When no authority is provided at all, Azure AD endpoint is auto-detected from SiteURL.
+type AuthCnfg struct {
+	SiteURL       string                 `json:"siteUrl" required:"true"` // SPSite or SPWeb URL, which is the context target for the API calls
+	TenantID      string                 `json:"tenantId"`                // Azure Tenant ID
+	ClientID      string                 `json:"clientId"`                // Azure Client ID with a federated credential configured
+	TokenFile     string                 `json:"tokenFile"`               // Projected federated token file location, re-read on every token exchange
+	AuthorityHost string                 `json:"authorityHost"`           // Azure AD authority, e.g. https://login.microsoftonline.com/ (optional)
+	Transport     *gosip.TransportConfig `json:"transport,omitempty"`     // HTTP transport settings (optional)
+
+	privateFile string
+	spt         *adal.ServicePrincipalToken
+	client      *http.Client
+}
+
+// ReadConfig reads private config with auth options
+func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
+	f, err := os.Open(privateFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	byteValue, _ := io.ReadAll(f)
+	return c.ParseConfig(byteValue)
+}
+
+// ParseConfig parses credentials from a provided JSON byte array content
+func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
+	if err := json.Unmarshal(byteValue, &c); err != nil {
+		return err
+	}
+	return c.Transport.Prepare(c.privateFile, "")
+}
+
+// WriteConfig writes private config with auth options
+func (c *AuthCnfg) WriteConfig(privateFile string) error {
+	config := &AuthCnfg{
+		SiteURL:       c.SiteURL,
+		TenantID:      c.TenantID,
+		ClientID:      c.ClientID,
+		TokenFile:     c.TokenFile,
+		AuthorityHost: c.AuthorityHost,
+	}
+	transport, err := c.Transport.Encode("")
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
+	file, _ := json.MarshalIndent(config, "", "  ")
+	return os.WriteFile(privateFile, file, 0644)
+}
+
+// GetAuth authenticates, receives access token
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	parsedURL, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return "", 0, err
+	}
+
+	tenantID := withEnv(c.TenantID, "AZURE_TENANT_ID")
+	clientID := withEnv(c.ClientID, "AZURE_CLIENT_ID")
+
+	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + tenantID + "@" + clientID
+	if accessToken, exp, found := storage.GetWithExpiration(cacheKey); found {
+		return accessToken.(string), exp.Unix(), nil
+	}
+
+	if c.spt == nil {
+		authority := withEnv(c.AuthorityHost, "AZURE_AUTHORITY_HOST")
+		if authority == "" {
+			authority = getAADEndpoint(parsedURL.Host)
+		}
+		oauthConfig, err := adal.NewOAuthConfig(authority, tenantID)
+		if err != nil {
+			return "", 0, err
+		}
+		resource := fmt.Sprintf("https://%s", parsedURL.Host)
+		spt, err := adal.NewServicePrincipalTokenFromFederatedTokenCallback(*oauthConfig, clientID, c.readAssertion, resource)
+		if err != nil {
+			return "", 0, err
+		}
+		c.spt = spt
+	}
+
+	if c.client == nil && c.Transport != nil {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", 0, err
+		}
+		c.client = client
+	}
+	if c.client != nil {
+		c.spt.SetSender(c.client)
+	}
+
+	// Refresh always re-reads the token file, so rotated assertions are picked up
+	if err := c.spt.Refresh(); err != nil {
+		return "", 0, err
+	}
+
+	token := c.spt.Token()
+
+	// Save to cache
+	exp := token.Expires().Add(-60 * time.Second)
+	storage.Set(cacheKey, token.AccessToken, time.Until(exp))
+
+	return token.AccessToken, exp.Unix(), nil
+}
+
+// GetSiteURL gets SharePoint siteURL
+func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
+
+// GetStrategy gets auth strategy name
+func (c *AuthCnfg) GetStrategy() string { return "federated" }
+
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
+// SetAuth authenticates request
+func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
+	if c.client == nil {
+		c.client = &httpClient.Client
+	}
+	accessToken, _, err := c.GetAuth()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return nil
+}
+
+// CleanAuthCache removes cached access token, next GetAuth exchanges the federated token again
+func (c *AuthCnfg) CleanAuthCache() error {
+	parsedURL, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return err
+	}
+	tenantID := withEnv(c.TenantID, "AZURE_TENANT_ID")
+	clientID := withEnv(c.ClientID, "AZURE_CLIENT_ID")
+	storage.Delete(parsedURL.Host + "@" + c.GetStrategy() + "@" + tenantID + "@" + clientID)
+	return nil
+}
+
+// readAssertion reads federated token file, the file is rotated by the platform so it's never cached
+func (c *AuthCnfg) readAssertion() (string, error) {
+	tokenFile := withEnv(c.TokenFile, "AZURE_FEDERATED_TOKEN_FILE")
+	if tokenFile == "" {
+		return "", fmt.Errorf("no federated token file is provided")
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("can't read federated token file: %w", err)
+	}
+	assertion := strings.TrimSpace(string(data))
+	if assertion == "" {
+		return "", fmt.Errorf("federated token file is empty: %s", tokenFile)
+	}
+	return assertion, nil
+}
+
+// withEnv returns value or environment variable fallback when the value is empty
+func withEnv(value string, envVar string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(envVar)
+}
+
+// getAADEndpoint returns the Azure AD endpoint based on SharePoint domain
+func getAADEndpoint(host string) string {
+	switch {
+	case strings.HasSuffix(host, ".sharepoint.us"):
+		return azure.USGovernmentCloud.ActiveDirectoryEndpoint
+	case strings.HasSuffix(host, ".sharepoint.cn"):
+		return azure.ChinaCloud.ActiveDirectoryEndpoint
+	case strings.HasSuffix(host, ".sharepoint.de"):
+		return azure.GermanCloud.ActiveDirectoryEndpoint
+	default:
+		return azure.PublicCloud.ActiveDirectoryEndpoint
+	}
+}
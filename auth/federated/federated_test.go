@@ -0,0 +1,153 @@
+package federated
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	h "github.com/koltyakov/gosip/test/helpers"
+	u "github.com/koltyakov/gosip/test/utils"
+)
+
+var cnfgPath = "./config/private.spo-federated.json"
+
+func TestGettingAuthToken(t *testing.T) {
+	if !h.ConfigExists(cnfgPath) {
+		t.Skip("No auth config provided")
+	}
+	err := h.CheckAuth(
+		&AuthCnfg{},
+		cnfgPath,
+		[]string{"SiteURL"},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGettingDigest(t *testing.T) {
+	if !h.ConfigExists(cnfgPath) {
+		t.Skip("No auth config provided")
+	}
+	err := h.CheckDigest(&AuthCnfg{}, cnfgPath)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	if !h.ConfigExists(cnfgPath) {
+		t.Skip("No auth config provided")
+	}
+	err := h.CheckRequest(&AuthCnfg{}, cnfgPath)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTokenExchange(t *testing.T) {
+	var assertions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		assertions = append(assertions, r.Form.Get("client_assertion"))
+		if r.Form.Get("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":"3600","expires_on":"%d","resource":"%s"}`,
+			len(assertions), time.Now().Add(time.Hour).Unix(), r.Form.Get("resource"))
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("assertion-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cnfg := &AuthCnfg{
+		SiteURL:       "https://contoso-federated.sharepoint.com/sites/test",
+		TenantID:      "tenant",
+		ClientID:      "client",
+		TokenFile:     tokenFile,
+		AuthorityHost: srv.URL,
+	}
+
+	token, _, err := cnfg.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token-1" {
+		t.Errorf("unexpected token: %s", token)
+	}
+
+	// Cached token must not cause the exchange
+	if _, _, err := cnfg.GetAuth(); err != nil {
+		t.Fatal(err)
+	}
+	if len(assertions) != 1 {
+		t.Errorf("token is not cached, exchanges: %d", len(assertions))
+	}
+
+	// Rotated token file must be re-read
+	if err := os.WriteFile(tokenFile, []byte("assertion-2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = cnfg.CleanAuthCache()
+	if _, _, err := cnfg.GetAuth(); err != nil {
+		t.Fatal(err)
+	}
+	if len(assertions) != 2 || assertions[0] != "assertion-1" || assertions[1] != "assertion-2" {
+		t.Errorf("unexpected assertions: %v", assertions)
+	}
+}
+
+func TestAuthEdgeCases(t *testing.T) {
+	t.Run("ReadConfig/MissedConfig", func(t *testing.T) {
+		cnfg := &AuthCnfg{}
+		if err := cnfg.ReadConfig("wrong_path.json"); err == nil {
+			t.Error("wrong_path config should not pass")
+		}
+	})
+
+	t.Run("ReadConfig/MalformedConfig", func(t *testing.T) {
+		cnfg := &AuthCnfg{}
+		folderPath := u.ResolveCnfgPath("./tmp")
+		filePath := u.ResolveCnfgPath("./tmp/private.federated.malformed.json")
+		_ = os.MkdirAll(folderPath, os.ModePerm)
+		_ = os.WriteFile(filePath, []byte("not a json"), 0644)
+		if err := cnfg.ReadConfig(filePath); err == nil {
+			t.Error("malformed config should not pass")
+		}
+		_ = os.RemoveAll(filePath)
+	})
+
+	t.Run("WriteConfig", func(t *testing.T) {
+		folderPath := u.ResolveCnfgPath("./tmp")
+		filePath := u.ResolveCnfgPath("./tmp/private.federated.json")
+		cnfg := &AuthCnfg{SiteURL: "test"}
+		_ = os.MkdirAll(folderPath, os.ModePerm)
+		if err := cnfg.WriteConfig(filePath); err != nil {
+			t.Error(err)
+		}
+		_ = os.RemoveAll(filePath)
+	})
+
+	t.Run("MissedTokenFile", func(t *testing.T) {
+		t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "")
+		cnfg := &AuthCnfg{
+			SiteURL:       "https://contoso-missed.sharepoint.com",
+			TenantID:      "tenant",
+			ClientID:      "client",
+			AuthorityHost: "https://localhost",
+		}
+		if _, _, err := cnfg.GetAuth(); err == nil || !strings.Contains(err.Error(), "no federated token file") {
+			t.Errorf("missed token file should not pass: %v", err)
+		}
+	})
+}
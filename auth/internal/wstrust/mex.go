@@ -0,0 +1,234 @@
+// Package wstrust implements WS-Trust security token requests shared by saml and adfs strategies:
+// endpoints discovery via MEX metadata, WS-Trust 1.3 and 2005 envelopes, usernamemixed and
+// certificatemixed bindings. Besides ADFS, it allows other WS-Trust identity providers,
+// e.g. Ping, Okta or Shibboleth.
+package wstrust
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+
+	"github.com/koltyakov/gosip/templates"
+)
+
+// Endpoint authentication kinds
+const (
+	UsernameMixed    = "usernamemixed"    // Username and password in WS-Security header over TLS
+	CertificateMixed = "certificatemixed" // Timestamp signed with an X.509 certificate over TLS
+)
+
+const (
+	soapActionTrust13   = "http://docs.oasis-open.org/ws-sx/ws-trust/200512/RST/Issue"
+	soapActionTrust2005 = "http://schemas.xmlsoap.org/ws/2005/02/trust/RST/Issue"
+)
+
+var mexCache = cache.New(time.Hour, 2*time.Hour)
+
+// Endpoint is WS-Trust endpoint discovered in MEX metadata
+type Endpoint struct {
+	URL     string // Endpoint URL
+	Version string // WS-Trust version: templates.WsTrust13 or templates.WsTrust2005
+	Kind    string // Authentication kind: UsernameMixed or CertificateMixed
+}
+
+// mexDocument is MEX metadata WSDL, elements are matched by local names to support different prefixes
+type mexDocument struct {
+	Policies []struct {
+		ID    string `xml:"Id,attr"`
+		Inner []byte `xml:",innerxml"`
+	} `xml:"MetadataSection>definitions>Policy"`
+	Bindings []struct {
+		Name      string `xml:"name,attr"`
+		PolicyRef struct {
+			URI string `xml:"URI,attr"`
+		} `xml:"PolicyReference"`
+		SOAPBinding *struct {
+			Transport string `xml:"transport,attr"`
+		} `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+		Operations []struct {
+			SOAPOperation struct {
+				SOAPAction string `xml:"soapAction,attr"`
+			} `xml:"operation"`
+		} `xml:"operation"`
+	} `xml:"MetadataSection>definitions>binding"`
+	Ports []struct {
+		Binding string `xml:"binding,attr"`
+		Address struct {
+			Location string `xml:"location,attr"`
+		} `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
+		EndpointReference struct {
+			Address string `xml:"Address"`
+		} `xml:"EndpointReference"`
+	} `xml:"MetadataSection>definitions>service>port"`
+}
+
+// Discover gets WS-Trust endpoints from IdP's MEX metadata, the metadata is cached for an hour
+func Discover(client *http.Client, mexURL string) ([]Endpoint, error) {
+	if endpoints, found := mexCache.Get(mexURL); found {
+		return endpoints.([]Endpoint), nil
+	}
+
+	req, err := http.NewRequest("GET", mexURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can't get MEX metadata %s: %s", mexURL, resp.Status)
+	}
+
+	endpoints, err := ParseMex(data)
+	if err != nil {
+		return nil, err
+	}
+	mexCache.Set(mexURL, endpoints, cache.DefaultExpiration)
+	return endpoints, nil
+}
+
+// ParseMex parses MEX metadata and gets WS-Trust endpoints with supported authentication kinds
+func ParseMex(data []byte) ([]Endpoint, error) {
+	doc := &mexDocument{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("can't parse MEX metadata: %w", err)
+	}
+
+	// Policies with transport security and supported tokens
+	kinds := map[string]string{}
+	for _, policy := range doc.Policies {
+		elements, err := localNames(policy.Inner)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse MEX metadata policy: %w", err)
+		}
+		if !elements["TransportBinding"] {
+			continue
+		}
+		switch {
+		case elements["UsernameToken"]:
+			kinds["#"+policy.ID] = UsernameMixed
+		case elements["X509Token"]:
+			kinds["#"+policy.ID] = CertificateMixed
+		}
+	}
+
+	// SOAP 1.2 bindings with the policies
+	type binding struct{ kind, version string }
+	bindings := map[string]binding{}
+	for _, b := range doc.Bindings {
+		kind, ok := kinds[b.PolicyRef.URI]
+		if !ok || b.SOAPBinding == nil {
+			continue
+		}
+		for _, op := range b.Operations {
+			switch op.SOAPOperation.SOAPAction {
+			case soapActionTrust13:
+				bindings[b.Name] = binding{kind: kind, version: templates.WsTrust13}
+			case soapActionTrust2005:
+				bindings[b.Name] = binding{kind: kind, version: templates.WsTrust2005}
+			}
+		}
+	}
+
+	var endpoints []Endpoint
+	for _, port := range doc.Ports {
+		name := port.Binding
+		if i := strings.Index(name, ":"); i != -1 {
+			name = name[i+1:]
+		}
+		b, ok := bindings[name]
+		if !ok {
+			continue
+		}
+		address := port.EndpointReference.Address
+		if address == "" {
+			address = port.Address.Location
+		}
+		if u, err := url.Parse(address); err != nil || u.Scheme != "https" {
+			continue
+		}
+		endpoints = append(endpoints, Endpoint{URL: address, Version: b.version, Kind: b.kind})
+	}
+
+	return endpoints, nil
+}
+
+// Select picks an endpoint of the authentication kind, WS-Trust 1.3 is preferred over 2005
+func Select(endpoints []Endpoint, kind string) (*Endpoint, error) {
+	var selected *Endpoint
+	for i, e := range endpoints {
+		if e.Kind != kind {
+			continue
+		}
+		if selected == nil || (selected.Version != templates.WsTrust13 && e.Version == templates.WsTrust13) {
+			selected = &endpoints[i]
+		}
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("no %s WS-Trust endpoint found in MEX metadata", kind)
+	}
+	return selected, nil
+}
+
+// Resolve discovers the endpoint of the authentication kind in MEX metadata. When the MEX URL is explicitly
+// configured, discovery and selection errors are returned, otherwise the fallback endpoint is used.
+func Resolve(client *http.Client, mexURL string, explicit bool, kind string, fallback Endpoint) (*Endpoint, error) {
+	endpoints, err := Discover(client, mexURL)
+	if err == nil {
+		var endpoint *Endpoint
+		if endpoint, err = Select(endpoints, kind); err == nil {
+			return endpoint, nil
+		}
+	}
+	if explicit {
+		return nil, fmt.Errorf("can't resolve WS-Trust endpoint from mexUrl %s: %w", mexURL, err)
+	}
+	return &fallback, nil
+}
+
+// ADFS gets ADFS default MEX and WS-Trust 1.3 endpoint URLs for an ADFS host URL
+func ADFS(adfsURL string, kind string) (mexURL string, endpoint Endpoint, err error) {
+	u, err := url.Parse(adfsURL)
+	if err != nil {
+		return "", endpoint, err
+	}
+	if u.Host == "" {
+		return "", endpoint, fmt.Errorf("can't resolve ADFS host from %s", adfsURL)
+	}
+	base := fmt.Sprintf("%s://%s/adfs/services/trust", u.Scheme, u.Host)
+	endpoint = Endpoint{URL: base + "/13/" + kind, Version: templates.WsTrust13, Kind: kind}
+	return base + "/mex", endpoint, nil
+}
+
+// localNames gets local names of all elements in XML fragment
+func localNames(data []byte) (map[string]bool, error) {
+	names := map[string]bool{}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if el, ok := t.(xml.StartElement); ok {
+			names[el.Name.Local] = true
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package wstrust
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/koltyakov/gosip/templates"
+)
+
+// Credentials used in WS-Trust security token request,
+// either username and password or certificate and its private key
+type Credentials struct {
+	Username    string
+	Password    string
+	Certificate *x509.Certificate
+	PrivateKey  *rsa.PrivateKey
+}
+
+// Kind gets endpoint authentication kind matching the credentials
+func (c *Credentials) Kind() string {
+	if c.Certificate != nil {
+		return CertificateMixed
+	}
+	return UsernameMixed
+}
+
+// Token is a security token issued by IdP
+type Token struct {
+	Assertion []byte // Raw token XML, e.g. SAML assertion
+	Created   string // Token validity start
+	Expires   string // Token expiration
+}
+
+// LoadCertificate loads a certificate with its private key from a .pfx file
+func LoadCertificate(certPath string, certPass string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, cert, _, err := pkcs12.DecodeChain(data, certPass)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't decode certificate %s: %w", certPath, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("certificate %s private key is not RSA", certPath)
+	}
+	return cert, rsaKey, nil
+}
+
+// RequestToken requests a security token for the relying party (appliesTo) from the endpoint,
+// the cookie is sent along when not empty, e.g. WAP edge access cookie
+func RequestToken(client *http.Client, endpoint *Endpoint, creds *Credentials, appliesTo string, cookie string) (*Token, error) {
+	security, err := securityHeader(endpoint, creds)
+	if err != nil {
+		return nil, err
+	}
+	body, err := templates.WsTrustTemplate(endpoint.Version, endpoint.URL, appliesTo, security)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", endpoint.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=utf-8")
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseResponse(data)
+}
+
+// securityHeader renders WS-Security header for the endpoint kind
+func securityHeader(endpoint *Endpoint, creds *Credentials) (string, error) {
+	if endpoint.Kind != creds.Kind() {
+		return "", fmt.Errorf("%s endpoint can't be used with %s credentials", endpoint.Kind, creds.Kind())
+	}
+	if endpoint.Kind == UsernameMixed {
+		return templates.WsSecurityUsernameTemplate(creds.Username, creds.Password)
+	}
+	if creds.PrivateKey == nil {
+		return "", errors.New("certificate private key is not provided")
+	}
+
+	// Exclusive canonical forms are rendered directly, as they are what gets signed
+	now := time.Now().UTC()
+	timestamp := fmt.Sprintf(
+		`<u:Timestamp xmlns:u="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd" u:Id="_0">`+
+			`<u:Created>%s</u:Created><u:Expires>%s</u:Expires></u:Timestamp>`,
+		now.Format("2006-01-02T15:04:05.000Z"),
+		now.Add(5*time.Minute).Format("2006-01-02T15:04:05.000Z"),
+	)
+	digest := sha256.Sum256([]byte(timestamp))
+	signedInfo := fmt.Sprintf(
+		`<SignedInfo xmlns="http://www.w3.org/2000/09/xmldsig#">`+
+			`<CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></CanonicalizationMethod>`+
+			`<SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></SignatureMethod>`+
+			`<Reference URI="#_0"><Transforms><Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></Transform></Transforms>`+
+			`<DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></DigestMethod>`+
+			`<DigestValue>%s</DigestValue></Reference></SignedInfo>`,
+		base64.StdEncoding.EncodeToString(digest[:]),
+	)
+	hashed := sha256.Sum256([]byte(signedInfo))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, creds.PrivateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+
+	return templates.WsSecurityX509Template(
+		timestamp,
+		base64.StdEncoding.EncodeToString(creds.Certificate.Raw),
+		signedInfo,
+		base64.StdEncoding.EncodeToString(signature),
+	)
+}
+
+// parseResponse extracts security token from WS-Trust 1.3 or 2005 response
+func parseResponse(data []byte) (*Token, error) {
+	type tokenResponse struct {
+		Token struct {
+			Inner      []byte `xml:",innerxml"`
+			Conditions struct {
+				NotBefore    string `xml:"NotBefore,attr"`
+				NotOnOrAfter string `xml:"NotOnOrAfter,attr"`
+			} `xml:"Assertion>Conditions"`
+		} `xml:"RequestedSecurityToken"`
+		Lifetime struct {
+			Created string `xml:"Created"`
+			Expires string `xml:"Expires"`
+		} `xml:"Lifetime"`
+	}
+	type envelope struct {
+		Fault       string          `xml:"Body>Fault>Reason>Text"`
+		Response13  []tokenResponse `xml:"Body>RequestSecurityTokenResponseCollection>RequestSecurityTokenResponse"`
+		Response05  []tokenResponse `xml:"Body>RequestSecurityTokenResponse"`
+		Fault11Text string          `xml:"Body>Fault>faultstring"`
+	}
+
+	result := &envelope{}
+	if err := xml.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	if result.Fault != "" {
+		return nil, errors.New(result.Fault)
+	}
+	if result.Fault11Text != "" {
+		return nil, errors.New(result.Fault11Text)
+	}
+
+	responses := append(result.Response13, result.Response05...)
+	if len(responses) == 0 || len(bytes.TrimSpace(responses[0].Token.Inner)) == 0 {
+		return nil, errors.New("can't extract security token from WS-Trust response")
+	}
+
+	r := responses[0]
+	token := &Token{
+		Assertion: r.Token.Inner,
+		Created:   r.Token.Conditions.NotBefore,
+		Expires:   r.Token.Conditions.NotOnOrAfter,
+	}
+	if token.Created == "" {
+		token.Created = r.Lifetime.Created
+	}
+	if token.Expires == "" {
+		token.Expires = r.Lifetime.Expires
+	}
+	return token, nil
+}
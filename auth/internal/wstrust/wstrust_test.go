@@ -0,0 +1,283 @@
+package wstrust
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/gosip/auth/azurecert"
+	"github.com/koltyakov/gosip/templates"
+)
+
+const mexPolicy = `
+	<wsp:Policy wsu:Id="%s">
+		<wsp:ExactlyOne><wsp:All>
+			<sp:TransportBinding><wsp:Policy><sp:TransportToken><wsp:Policy><sp:HttpsToken/></wsp:Policy></sp:TransportToken></wsp:Policy></sp:TransportBinding>
+			<sp:SignedEncryptedSupportingTokens><wsp:Policy><sp:%s/></wsp:Policy></sp:SignedEncryptedSupportingTokens>
+		</wsp:All></wsp:ExactlyOne>
+	</wsp:Policy>`
+
+const mexBinding = `
+	<wsdl:binding name="%s" type="tns:IWSTrust">
+		<wsp:PolicyReference URI="#%s"/>
+		<soap12:binding transport="http://schemas.xmlsoap.org/soap/http"/>
+		<wsdl:operation name="Issue"><soap12:operation soapAction="%s" style="document"/></wsdl:operation>
+	</wsdl:binding>`
+
+const mexPort = `
+	<wsdl:port name="%s" binding="tns:%s">
+		<soap12:address location="%s"/>
+		<wsa10:EndpointReference><wsa10:Address>%s</wsa10:Address></wsa10:EndpointReference>
+	</wsdl:port>`
+
+func mexSample(baseURL string) string {
+	policies := fmt.Sprintf(mexPolicy, "UserNameWSTrustBinding_policy", "UsernameToken") +
+		fmt.Sprintf(mexPolicy, "CertificateWSTrustBinding_policy", "X509Token") +
+		`<wsp:Policy wsu:Id="WindowsTransport_policy"><sp:TransportBinding/><http:NegotiateAuthentication/></wsp:Policy>`
+	bindings := fmt.Sprintf(mexBinding, "UserNameWSTrustBinding_IWSTrust13Async", "UserNameWSTrustBinding_policy", soapActionTrust13) +
+		fmt.Sprintf(mexBinding, "UserNameWSTrustBinding_IWSTrustFeb2005Async", "UserNameWSTrustBinding_policy", soapActionTrust2005) +
+		fmt.Sprintf(mexBinding, "CertificateWSTrustBinding_IWSTrustFeb2005Async", "CertificateWSTrustBinding_policy", soapActionTrust2005) +
+		fmt.Sprintf(mexBinding, "WindowsTransport_IWSTrust13Async", "WindowsTransport_policy", soapActionTrust13)
+	ports := fmt.Sprintf(mexPort, "UserNameWSTrustBinding_IWSTrust13Async", "UserNameWSTrustBinding_IWSTrust13Async", baseURL+"/trust/13/usernamemixed", baseURL+"/trust/13/usernamemixed") +
+		fmt.Sprintf(mexPort, "UserNameWSTrustBinding_IWSTrustFeb2005Async", "UserNameWSTrustBinding_IWSTrustFeb2005Async", baseURL+"/trust/2005/usernamemixed", baseURL+"/trust/2005/usernamemixed") +
+		fmt.Sprintf(mexPort, "CertificateWSTrustBinding_IWSTrustFeb2005Async", "CertificateWSTrustBinding_IWSTrustFeb2005Async", baseURL+"/trust/2005/certificatemixed", baseURL+"/trust/2005/certificatemixed") +
+		fmt.Sprintf(mexPort, "WindowsTransport_IWSTrust13Async", "WindowsTransport_IWSTrust13Async", baseURL+"/trust/13/windowstransport", baseURL+"/trust/13/windowstransport")
+	return `<?xml version="1.0" encoding="utf-8"?>
+		<wsx:Metadata xmlns:wsx="http://schemas.xmlsoap.org/ws/2004/09/mex">
+			<wsx:MetadataSection Dialect="http://schemas.xmlsoap.org/wsdl/">
+				<wsdl:definitions name="SecurityTokenService" targetNamespace="http://tempuri.org/"
+					xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+					xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+					xmlns:wsp="http://schemas.xmlsoap.org/ws/2004/09/policy"
+					xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+					xmlns:sp="http://docs.oasis-open.org/ws-sx/ws-securitypolicy/200702"
+					xmlns:http="http://schemas.microsoft.com/ws/06/2004/policy/http"
+					xmlns:wsa10="http://www.w3.org/2005/08/addressing"
+					xmlns:tns="http://tempuri.org/">
+					` + policies + bindings + `
+					<wsdl:service name="SecurityTokenService">` + ports + `</wsdl:service>
+				</wsdl:definitions>
+			</wsx:MetadataSection>
+		</wsx:Metadata>`
+}
+
+const tokenResponse13 = `
+	<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">
+		<s:Body>
+			<trust:RequestSecurityTokenResponseCollection xmlns:trust="http://docs.oasis-open.org/ws-sx/ws-trust/200512">
+				<trust:RequestSecurityTokenResponse>
+					<trust:RequestedSecurityToken><saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:1.0:assertion"><saml:Conditions NotBefore="2024-01-01T00:00:00.000Z" NotOnOrAfter="2024-01-01T01:00:00.000Z"/></saml:Assertion></trust:RequestedSecurityToken>
+				</trust:RequestSecurityTokenResponse>
+			</trust:RequestSecurityTokenResponseCollection>
+		</s:Body>
+	</s:Envelope>`
+
+const tokenResponse2005 = `
+	<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope">
+		<s:Body>
+			<t:RequestSecurityTokenResponse xmlns:t="http://schemas.xmlsoap.org/ws/2005/02/trust">
+				<t:Lifetime><wsu:Created xmlns:wsu="u">2024-01-01T00:00:00Z</wsu:Created><wsu:Expires xmlns:wsu="u">2024-01-01T02:00:00Z</wsu:Expires></t:Lifetime>
+				<t:RequestedSecurityToken><Assertion>token-2005</Assertion></t:RequestedSecurityToken>
+			</t:RequestSecurityTokenResponse>
+		</s:Body>
+	</s:Envelope>`
+
+func TestParseMex(t *testing.T) {
+	endpoints, err := ParseMex([]byte(mexSample("https://sts.contoso.com")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Endpoint{
+		{URL: "https://sts.contoso.com/trust/13/usernamemixed", Version: templates.WsTrust13, Kind: UsernameMixed},
+		{URL: "https://sts.contoso.com/trust/2005/usernamemixed", Version: templates.WsTrust2005, Kind: UsernameMixed},
+		{URL: "https://sts.contoso.com/trust/2005/certificatemixed", Version: templates.WsTrust2005, Kind: CertificateMixed},
+	}
+	if fmt.Sprint(endpoints) != fmt.Sprint(expected) {
+		t.Errorf("unexpected endpoints: %v", endpoints)
+	}
+
+	t.Run("Select", func(t *testing.T) {
+		e, err := Select(endpoints, UsernameMixed)
+		if err != nil || e.Version != templates.WsTrust13 {
+			t.Errorf("WS-Trust 1.3 should be preferred: %v, %v", e, err)
+		}
+		e, err = Select(endpoints, CertificateMixed)
+		if err != nil || e.Version != templates.WsTrust2005 {
+			t.Errorf("WS-Trust 2005 should be selected when 1.3 is missing: %v, %v", e, err)
+		}
+		if _, err := Select(endpoints[:2], CertificateMixed); err == nil {
+			t.Error("should fail when no endpoint of the kind")
+		}
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		endpoints, err := ParseMex([]byte(mexSample("http://sts.contoso.com")))
+		if err != nil || len(endpoints) != 0 {
+			t.Errorf("non-TLS endpoints should be ignored: %v, %v", endpoints, err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := ParseMex([]byte("not a xml")); err == nil {
+			t.Error("should fail on invalid metadata")
+		}
+	})
+}
+
+func TestADFS(t *testing.T) {
+	mexURL, endpoint, err := ADFS("https://sts.contoso.com/adfs/ls", CertificateMixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mexURL != "https://sts.contoso.com/adfs/services/trust/mex" {
+		t.Errorf("unexpected MEX URL: %s", mexURL)
+	}
+	if endpoint.URL != "https://sts.contoso.com/adfs/services/trust/13/certificatemixed" || endpoint.Version != templates.WsTrust13 {
+		t.Errorf("unexpected endpoint: %v", endpoint)
+	}
+	if _, _, err := ADFS("", UsernameMixed); err == nil {
+		t.Error("should fail on empty ADFS URL")
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	var requests []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, string(body))
+		switch r.URL.Path {
+		case "/mex":
+			_, _ = fmt.Fprint(w, mexSample("https://"+r.Host))
+		case "/trust/13/usernamemixed":
+			_, _ = w.Write([]byte(tokenResponse13))
+		case "/trust/2005/certificatemixed":
+			_, _ = w.Write([]byte(tokenResponse2005))
+		default:
+			_, _ = w.Write([]byte(`<s:Envelope><s:Body><s:Fault><s:Reason><s:Text>ID3242: not authenticated</s:Text></s:Reason></s:Fault></s:Body></s:Envelope>`))
+		}
+	}))
+	defer server.Close()
+	client := server.Client()
+
+	endpoints, err := Discover(client, server.URL+"/mex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Resolve", func(t *testing.T) {
+		fallback := Endpoint{URL: server.URL + "/adfs/services/trust/13/usernamemixed", Version: templates.WsTrust13, Kind: UsernameMixed}
+		if endpoint, err := Resolve(client, server.URL+"/mex", true, UsernameMixed, fallback); err != nil || endpoint.URL != server.URL+"/trust/13/usernamemixed" {
+			t.Errorf("discovered endpoint is expected: %v, %v", endpoint, err)
+		}
+		if endpoint, err := Resolve(client, server.URL+"/missing-mex", false, UsernameMixed, fallback); err != nil || endpoint.URL != fallback.URL {
+			t.Errorf("fallback endpoint is expected for implicit MEX URL: %v, %v", endpoint, err)
+		}
+		if _, err := Resolve(client, server.URL+"/missing-mex", true, UsernameMixed, fallback); err == nil {
+			t.Error("explicit MEX URL discovery error should be returned")
+		}
+	})
+
+	t.Run("UsernameMixed", func(t *testing.T) {
+		endpoint, _ := Select(endpoints, UsernameMixed)
+		creds := &Credentials{Username: "john.doe@contoso.com", Password: "pass"}
+		token, err := RequestToken(client, endpoint, creds, "urn:sharepoint:www", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(token.Assertion), "saml:Assertion") || token.Expires != "2024-01-01T01:00:00.000Z" {
+			t.Errorf("unexpected token: %s, %s", token.Assertion, token.Expires)
+		}
+		request := requests[len(requests)-1]
+		if !strings.Contains(request, "<o:Username>john.doe@contoso.com</o:Username>") || !strings.Contains(request, "ws-trust/200512/Bearer") {
+			t.Errorf("unexpected request: %s", request)
+		}
+	})
+
+	t.Run("CertificateMixed", func(t *testing.T) {
+		cert, err := azurecert.GenerateCertificate(azurecert.CertificateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pfx, _ := cert.PFX("pfx-pass")
+		certPath := filepath.Join(t.TempDir(), "cert.pfx")
+		_ = os.WriteFile(certPath, pfx, 0600)
+
+		creds := &Credentials{}
+		creds.Certificate, creds.PrivateKey, err = LoadCertificate(certPath, "pfx-pass")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		endpoint, _ := Select(endpoints, CertificateMixed)
+		token, err := RequestToken(client, endpoint, creds, "urn:sharepoint:www", "EdgeAccessCookie=cookie")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(token.Assertion) != "<Assertion>token-2005</Assertion>" || token.Created != "2024-01-01T00:00:00Z" {
+			t.Errorf("unexpected token: %s, %s", token.Assertion, token.Created)
+		}
+		request := requests[len(requests)-1]
+		if !strings.Contains(request, "ws/2005/05/identity/NoProofKey") {
+			t.Errorf("WS-Trust 2005 key type is expected: %s", request)
+		}
+		verifySignature(t, request, cert.PrivateKey)
+	})
+
+	t.Run("Fault", func(t *testing.T) {
+		endpoint := &Endpoint{URL: server.URL + "/unknown", Version: templates.WsTrust13, Kind: UsernameMixed}
+		_, err := RequestToken(client, endpoint, &Credentials{Username: "user", Password: "wrong"}, "urn:sharepoint:www", "")
+		if err == nil || !strings.Contains(err.Error(), "ID3242") {
+			t.Errorf("fault should be returned: %v", err)
+		}
+	})
+
+	t.Run("KindMismatch", func(t *testing.T) {
+		endpoint, _ := Select(endpoints, CertificateMixed)
+		if _, err := RequestToken(client, endpoint, &Credentials{Username: "user", Password: "pass"}, "urn:sharepoint:www", ""); err == nil {
+			t.Error("should fail when credentials don't match endpoint kind")
+		}
+	})
+}
+
+// verifySignature checks timestamp digest and signed info signature in WS-Security header
+func verifySignature(t *testing.T, request string, key *rsa.PrivateKey) {
+	t.Helper()
+	extract := func(start, end string) string {
+		i := strings.Index(request, start)
+		j := strings.Index(request, end)
+		if i == -1 || j == -1 {
+			t.Fatalf("%s is not found in request", start)
+		}
+		return request[i : j+len(end)]
+	}
+	timestamp := extract("<u:Timestamp", "</u:Timestamp>")
+	signedInfo := extract("<SignedInfo", "</SignedInfo>")
+
+	security := &struct {
+		DigestValue    string `xml:"Header>Security>Signature>SignedInfo>Reference>DigestValue"`
+		SignatureValue string `xml:"Header>Security>Signature>SignatureValue"`
+	}{}
+	if err := xml.Unmarshal([]byte(request), security); err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256([]byte(timestamp))
+	if security.DigestValue != base64.StdEncoding.EncodeToString(digest[:]) {
+		t.Error("timestamp digest doesn't match")
+	}
+	signature, _ := base64.StdEncoding.DecodeString(security.SignatureValue)
+	hashed := sha256.Sum256([]byte(signedInfo))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], signature); err != nil {
+		t.Errorf("invalid signature: %s", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 )
 
 // AuthCnfg - NTLM auth config structure
@@ -38,18 +39,22 @@ or
 }
 */
 type AuthCnfg struct {
-	SiteURL  string `json:"siteUrl"`  // SPSite or SPWeb URL, which is the context target for the API calls
-	Domain   string `json:"domain"`   // AD domain name (optional)
-	Username string `json:"username"` // AD user name
-	Password string `json:"password"` // AD user password
-
-	masterKey string
-	transport ntlmssp.Negotiator
-	mux       sync.Mutex
+	SiteURL   string                 `json:"siteUrl" required:"true"`                // SPSite or SPWeb URL, which is the context target for the API calls
+	Domain    string                 `json:"domain"`                                 // AD domain name (optional)
+	Username  string                 `json:"username" required:"true"`               // AD user name
+	Password  string                 `json:"password" required:"true" secret:"true"` // AD user password
+	Transport *gosip.TransportConfig `json:"transport,omitempty"`                    // HTTP transport settings (optional)
+
+	privateFile string
+	masterKey   string
+	secrets     secret.Refs
+	transport   ntlmssp.Negotiator
+	mux         sync.Mutex
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	jsonFile, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -65,8 +70,14 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
+	if err := c.secrets.Resolve(&c.Password); err != nil {
+		return err
+	}
 
-	crypt := cpass.Cpass(c.masterKey)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	pass, err := crypt.Decode(c.Password)
 	if err == nil {
 		c.Password = pass
@@ -76,13 +87,16 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 		c.Username = c.Domain + "\\" + c.Username
 	}
 
-	return nil
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
 }
 
 // WriteConfig writes private config with auth options
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	crypt := cpass.Cpass(c.masterKey)
-	pass, err := crypt.Encode(c.Password)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	pass, err := c.secrets.Encode(&c.Password, crypt.Encode)
 	if err != nil {
 		pass = c.Password
 	}
@@ -92,6 +106,11 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		Domain:   c.Domain,
 		Password: pass,
 	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
@@ -108,6 +127,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "ntlm" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticate request
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
 	// NTLM + Negotiation
@@ -0,0 +1,174 @@
+package obo
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/google/uuid"
+)
+
+// GetAuth gets authentication
+func GetAuth(c *AuthCnfg) (string, int64, error) {
+	client, err := c.getClient()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if c.Assertion == "" {
+		return "", 0, fmt.Errorf("no user assertion is provided, use WithAssertion to create a per-user config")
+	}
+
+	parsedURL, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return "", 0, err
+	}
+
+	cacheKey, err := c.getCacheKey()
+	if err != nil {
+		return "", 0, err
+	}
+	if accessToken, exp, found := storage.GetWithExpiration(cacheKey); found {
+		return accessToken.(string), exp.Unix(), nil
+	}
+
+	tokenEndpoint := getTokenEndpoint(c.AuthorityHost, parsedURL.Host, c.TenantID)
+
+	params := url.Values{}
+	params.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	params.Set("client_id", c.ClientID)
+	params.Set("assertion", c.Assertion)
+	params.Set("scope", fmt.Sprintf("https://%s/.default", parsedURL.Host))
+	params.Set("requested_token_use", "on_behalf_of")
+
+	if c.ClientSecret != "" {
+		params.Set("client_secret", c.ClientSecret)
+	} else {
+		clientAssertion, err := getClientAssertion(c, tokenEndpoint)
+		if err != nil {
+			return "", 0, err
+		}
+		params.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		params.Set("client_assertion", clientAssertion)
+	}
+
+	resp, err := client.Post(tokenEndpoint, "application/x-www-form-urlencoded", strings.NewReader(params.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	type getAuthResponse struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+
+	results := &getAuthResponse{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return "", 0, fmt.Errorf("can't parse token response (%s): %w", resp.Status, err)
+	}
+
+	if results.Error != "" {
+		return "", 0, fmt.Errorf("%s: %s", results.Error, results.Description)
+	}
+
+	if results.AccessToken == "" {
+		return "", 0, fmt.Errorf("received empty access token (%s)", resp.Status)
+	}
+
+	expiry := time.Duration(results.ExpiresIn-60) * time.Second
+	exp := time.Now().Add(expiry).Unix()
+
+	storage.Set(cacheKey, results.AccessToken, expiry)
+
+	return results.AccessToken, exp, nil
+}
+
+// getTokenEndpoint resolves AAD v2 token endpoint
+func getTokenEndpoint(authorityHost string, siteHost string, tenantID string) string {
+	authority := authorityHost
+	if authority == "" {
+		authority = getAADEndpoint(siteHost)
+	}
+	return fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), tenantID)
+}
+
+// getClientAssertion creates a client assertion JWT signed with the app certificate
+func getClientAssertion(c *AuthCnfg, audience string) (string, error) {
+	if c.CertPath == "" {
+		return "", fmt.Errorf("either clientSecret or certPath should be provided")
+	}
+
+	pfxData, err := os.ReadFile(c.CertPath)
+	if err != nil {
+		return "", err
+	}
+
+	cert, privateKey, err := adal.DecodePfxCertificateData(pfxData, c.CertPass)
+	if err != nil {
+		return "", err
+	}
+
+	thumbprint := sha1.Sum(cert.Raw)
+	header, _ := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+	})
+
+	now := time.Now()
+	claims, _ := json.Marshal(map[string]interface{}{
+		"aud": audience,
+		"iss": c.ClientID,
+		"sub": c.ClientID,
+		"jti": uuid.New().String(),
+		"nbf": now.Unix(),
+		"exp": now.Add(10 * time.Minute).Unix(),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hashed := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// getAADEndpoint returns the Azure AD endpoint based on SharePoint domain
+func getAADEndpoint(host string) string {
+	switch {
+	case strings.HasSuffix(host, ".sharepoint.us"):
+		return azure.USGovernmentCloud.ActiveDirectoryEndpoint
+	case strings.HasSuffix(host, ".sharepoint.cn"):
+		return azure.ChinaCloud.ActiveDirectoryEndpoint
+	case strings.HasSuffix(host, ".sharepoint.de"):
+		return azure.GermanCloud.ActiveDirectoryEndpoint
+	default:
+		return azure.PublicCloud.ActiveDirectoryEndpoint
+	}
+}
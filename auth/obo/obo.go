@@ -0,0 +1,260 @@
+// Package obo implements AAD On-Behalf-Of Auth Flow
+// A web API receives a user access token (assertion) and exchanges it for a SharePoint token
+// to call SharePoint as that user.
+// See more:
+//   - https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-on-behalf-of-flow
+//
+// Amongst supported platform versions are:
+//   - SharePoint Online + Azure
+package obo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
+	"github.com/patrickmn/go-cache"
+)
+
+var (
+	storage = cache.New(5*time.Minute, 10*time.Minute)
+)
+
+// AuthCnfg - AAD On-Behalf-Of Auth Flow
+/* Client secret config sample:
+{
+	"siteUrl": "https://contoso.sharepoint.com/sites/test",
+	"tenantId": "e4d43069-8ecb-49c4-8178-5bec83c53e9d",
+	"clientId": "628cc712-c9a4-48f0-a059-af64bdbb4be5",
+	"clientSecret": "secret"
+}
+*/
+/* Certificate config sample:
+{
+	"siteUrl": "https://contoso.sharepoint.com/sites/test",
+	"tenantId": "e4d43069-8ecb-49c4-8178-5bec83c53e9d",
+	"clientId": "628cc712-c9a4-48f0-a059-af64bdbb4be5",
+	"certPath": "cert.pfx",
+	"certPass": "password"
+}
+*/
+// The config is shared by the application, a per-user copy is created with WithAssertion
+// or a per-request client with NewClient.
+type AuthCnfg struct {
+	SiteURL       string                 `json:"siteUrl" required:"true"`    // SPSite or SPWeb URL, which is the context target for the API calls
+	TenantID      string                 `json:"tenantId" required:"true"`   // Azure Tenant ID
+	ClientID      string                 `json:"clientId" required:"true"`   // Azure Client ID of the web API app registration
+	ClientSecret  string                 `json:"clientSecret" secret:"true"` // Azure Client secret, either secret or certificate should be provided
+	CertPath      string                 `json:"certPath"`                   // Azure certificate (.pfx) file location, relative to config location or absolute
+	CertPass      string                 `json:"certPass" secret:"true"`     // Azure certificate export password
+	AuthorityHost string                 `json:"authorityHost"`              // Azure AD authority, e.g. https://login.microsoftonline.com/ (optional)
+	Transport     *gosip.TransportConfig `json:"transport,omitempty"`        // HTTP transport settings (optional)
+
+	Assertion string `json:"-"` // Incoming user access token which is exchanged
+
+	privateFile string
+	masterKey   string
+	secrets     secret.Refs
+	client      *http.Client // token requests client, shared with per-user copies
+	mux         sync.Mutex
+}
+
+// ReadConfig reads private config with auth options
+func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
+	f, err := os.Open(privateFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	byteValue, _ := io.ReadAll(f)
+	return c.ParseConfig(byteValue)
+}
+
+// ParseConfig parses credentials from a provided JSON byte array content
+func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
+	if err := json.Unmarshal(byteValue, &c); err != nil {
+		return err
+	}
+	if err := c.secrets.Resolve(&c.ClientSecret, &c.CertPass); err != nil {
+		return err
+	}
+	if c.CertPath != "" && !filepath.IsAbs(c.CertPath) && c.privateFile != "" {
+		c.CertPath = filepath.Join(filepath.Dir(c.privateFile), c.CertPath)
+	}
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	if secret, err := crypt.Decode(c.ClientSecret); err == nil {
+		c.ClientSecret = secret
+	}
+	if secret, err := crypt.Decode(c.CertPass); err == nil {
+		c.CertPass = secret
+	}
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
+}
+
+// WriteConfig writes private config with auth options
+func (c *AuthCnfg) WriteConfig(privateFile string) error {
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config := &AuthCnfg{
+		SiteURL:       c.SiteURL,
+		TenantID:      c.TenantID,
+		ClientID:      c.ClientID,
+		CertPath:      c.CertPath,
+		AuthorityHost: c.AuthorityHost,
+	}
+	if c.ClientSecret != "" {
+		secret, err := c.secrets.Encode(&c.ClientSecret, crypt.Encode)
+		if err != nil {
+			return err
+		}
+		config.ClientSecret = secret
+	}
+	if c.CertPass != "" {
+		secret, err := c.secrets.Encode(&c.CertPass, crypt.Encode)
+		if err != nil {
+			return err
+		}
+		config.CertPass = secret
+	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
+	file, _ := json.MarshalIndent(config, "", "  ")
+	return os.WriteFile(privateFile, file, 0644)
+}
+
+// SetMasterkey defines custom masterkey
+func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
+
+// WithAssertion creates a per-user copy of the config, app credentials and token cache are shared
+func (c *AuthCnfg) WithAssertion(assertion string) *AuthCnfg {
+	cnfg := &AuthCnfg{
+		SiteURL:       c.SiteURL,
+		TenantID:      c.TenantID,
+		ClientID:      c.ClientID,
+		CertPath:      c.CertPath,
+		AuthorityHost: c.AuthorityHost,
+		Transport:     c.Transport,
+		Assertion:     assertion,
+		privateFile:   c.privateFile,
+		masterKey:     c.masterKey,
+	}
+	c.mux.Lock()
+	cnfg.client = c.client
+	c.mux.Unlock()
+	cnfg.secrets.Copy(c.secrets, &c.ClientSecret, &cnfg.ClientSecret)
+	cnfg.secrets.Copy(c.secrets, &c.CertPass, &cnfg.CertPass)
+	return cnfg
+}
+
+// NewClient creates a per-request client acting on behalf of the user,
+// the client shares HTTP transport, retry policies, hooks, dry-run plan and journal with the base client,
+// base config transport settings are applied once and reused by all per-request clients
+func NewClient(base *gosip.SPClient, assertion string) (*gosip.SPClient, error) {
+	cnfg, ok := base.AuthCnfg.(*AuthCnfg)
+	if !ok {
+		return nil, fmt.Errorf("base client must use obo strategy")
+	}
+	// Transport settings are applied once on the base config, so per-request clients share connections
+	httpClient := base.Client
+	if httpClient.Transport == nil {
+		client, err := cnfg.getClient()
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = client.Transport
+		if httpClient.Timeout == 0 {
+			httpClient.Timeout = client.Timeout
+		}
+	}
+	return &gosip.SPClient{
+		Client:        httpClient,
+		AuthCnfg:      cnfg.WithAssertion(assertion),
+		RetryPolicies: base.RetryPolicies,
+		Hooks:         base.Hooks,
+		DryRun:        base.DryRun,
+		Journal:       base.Journal,
+	}, nil
+}
+
+// GetAuth authenticates, receives access token on behalf of the user
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	return c.secrets.Retry(func() (string, int64, error) { return GetAuth(c) })
+}
+
+// GetSiteURL gets SharePoint siteURL
+func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
+
+// GetStrategy gets auth strategy name
+func (c *AuthCnfg) GetStrategy() string { return "obo" }
+
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
+// SetAuth authenticates request
+func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
+	c.mux.Lock()
+	if c.client == nil {
+		c.client = &httpClient.Client
+	}
+	c.mux.Unlock()
+	accessToken, _, err := c.GetAuth()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return nil
+}
+
+// getClient gets or creates HTTP client for token requests from transport settings
+func (c *AuthCnfg) getClient() (*http.Client, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.client == nil {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+	}
+	return c.client, nil
+}
+
+// CleanAuthCache removes cached token of the current user assertion
+func (c *AuthCnfg) CleanAuthCache() error {
+	cacheKey, err := c.getCacheKey()
+	if err != nil {
+		return err
+	}
+	storage.Delete(cacheKey)
+	return nil
+}
+
+// getCacheKey gets token cache key, assertions are stored hashed
+func (c *AuthCnfg) getCacheKey() (string, error) {
+	parsedURL, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return "", err
+	}
+	assertionHash := sha256.Sum256([]byte(c.Assertion))
+	return parsedURL.Host + "@" + c.GetStrategy() + "@" + c.TenantID + "@" + c.ClientID + "@" + hex.EncodeToString(assertionHash[:]), nil
+}
@@ -0,0 +1,168 @@
+package obo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth/anon"
+	u "github.com/koltyakov/gosip/test/utils"
+)
+
+// journal - no-op journal for client options checks
+type journal struct{}
+
+func (j *journal) Record(*gosip.JournalEvent) error { return nil }
+
+func TestOnBehalfOf(t *testing.T) {
+	exchanges := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/tenant/oauth2/v2.0/token") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = r.ParseForm()
+		if r.Form.Get("requested_token_use") != "on_behalf_of" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_request","error_description":"AADSTS90014: missing parameter"}`))
+			return
+		}
+		assertion := r.Form.Get("assertion")
+		exchanges[assertion]++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"token_type":"Bearer","expires_in":3600,"access_token":"sp-%s"}`, assertion)
+	}))
+	defer srv.Close()
+
+	app := &AuthCnfg{
+		SiteURL:       "https://contoso-obo.sharepoint.com/sites/test",
+		TenantID:      "tenant",
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		AuthorityHost: srv.URL,
+	}
+
+	t.Run("PerUserTokens", func(t *testing.T) {
+		for _, user := range []string{"alice", "bob", "alice"} {
+			token, _, err := app.WithAssertion(user).GetAuth()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token != "sp-"+user {
+				t.Errorf("unexpected token for %s: %s", user, token)
+			}
+		}
+		if exchanges["alice"] != 1 || exchanges["bob"] != 1 {
+			t.Errorf("tokens are not cached per assertion: %v", exchanges)
+		}
+	})
+
+	t.Run("NoAssertion", func(t *testing.T) {
+		if _, _, err := app.GetAuth(); err == nil {
+			t.Error("empty assertion should not pass")
+		}
+	})
+
+	t.Run("ExchangeError", func(t *testing.T) {
+		cnfg := app.WithAssertion("carol")
+		cnfg.ClientSecret = "wrong"
+		if _, _, err := cnfg.GetAuth(); err == nil || !strings.Contains(err.Error(), "AADSTS90014") {
+			t.Errorf("exchange error should be returned: %v", err)
+		}
+	})
+
+	t.Run("NoCredentials", func(t *testing.T) {
+		cnfg := app.WithAssertion("dave")
+		cnfg.ClientSecret = ""
+		if _, _, err := cnfg.GetAuth(); err == nil {
+			t.Error("missing app credentials should not pass")
+		}
+	})
+
+	t.Run("Transport", func(t *testing.T) {
+		cnfg := &AuthCnfg{SiteURL: app.SiteURL, Transport: &gosip.TransportConfig{ProxyURL: "http://proxy.contoso.com:8080"}}
+		if cnfg.WithAssertion("frank").GetTransportConfig() != cnfg.Transport {
+			t.Error("per-user config should keep transport settings")
+		}
+	})
+
+	t.Run("NewClient", func(t *testing.T) {
+		transport := &http.Transport{}
+		base := &gosip.SPClient{
+			Client:   http.Client{Transport: transport},
+			AuthCnfg: app,
+			Hooks:    &gosip.HookHandlers{},
+		}
+		client, err := NewClient(base, "erin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.Transport != transport || client.Hooks != base.Hooks {
+			t.Error("per-request client should share transport and hooks")
+		}
+		if client.AuthCnfg.(*AuthCnfg).Assertion != "erin" || app.Assertion != "" {
+			t.Error("assertion should only be set for the per-request client")
+		}
+		if _, err := NewClient(&gosip.SPClient{AuthCnfg: &anon.AuthCnfg{}}, "erin"); err == nil {
+			t.Error("non obo base client should not pass")
+		}
+	})
+
+	t.Run("NewClient/TransportConfig", func(t *testing.T) {
+		app := &AuthCnfg{SiteURL: "https://contoso.sharepoint.com", Transport: &gosip.TransportConfig{Timeout: "1m"}}
+		base := &gosip.SPClient{
+			AuthCnfg: app,
+			DryRun:   &gosip.DryRun{},
+			Journal:  &journal{},
+		}
+		first, err := NewClient(base, "erin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := NewClient(base, "frank")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Transport == nil || first.Transport != second.Transport || first.Timeout != time.Minute {
+			t.Error("per-request clients should share transport created from the base config")
+		}
+		if first.AuthCnfg.(*AuthCnfg).client != second.AuthCnfg.(*AuthCnfg).client {
+			t.Error("per-request configs should share token requests client")
+		}
+		if first.DryRun != base.DryRun || first.Journal != base.Journal {
+			t.Error("per-request client should share dry-run plan and journal")
+		}
+	})
+}
+
+func TestAuthEdgeCases(t *testing.T) {
+	t.Run("ReadConfig/MissedConfig", func(t *testing.T) {
+		cnfg := &AuthCnfg{}
+		if err := cnfg.ReadConfig("wrong_path.json"); err == nil {
+			t.Error("wrong_path config should not pass")
+		}
+	})
+
+	t.Run("WriteConfig", func(t *testing.T) {
+		folderPath := u.ResolveCnfgPath("./tmp")
+		filePath := u.ResolveCnfgPath("./tmp/private.obo.json")
+		cnfg := &AuthCnfg{SiteURL: "test", ClientSecret: "secret"}
+		_ = os.MkdirAll(folderPath, os.ModePerm)
+		if err := cnfg.WriteConfig(filePath); err != nil {
+			t.Error(err)
+		}
+		c := &AuthCnfg{}
+		if err := c.ReadConfig(filePath); err != nil {
+			t.Error(err)
+		}
+		if c.ClientSecret != "secret" {
+			t.Error("client secret is not encrypted symmetrically")
+		}
+		_ = os.RemoveAll(filePath)
+	})
+}
@@ -0,0 +1,170 @@
+package ondemand
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ImportCookies parses browser-exported cookies: Netscape `cookies.txt`,
+// JSON export (DevTools, EditThisCookie or Playwright storage state) or a raw `Cookie` header value
+func ImportCookies(data []byte) (*Cookies, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no cookies to import")
+	}
+
+	var cookies Cookies
+	var err error
+	switch {
+	case data[0] == '[' || data[0] == '{':
+		cookies, err = parseJSONCookies(data)
+	case bytes.Contains(data, []byte("\t")) || bytes.HasPrefix(data, []byte("#")):
+		cookies, err = parseNetscapeCookies(data)
+	default:
+		cookies, err = parseCookieHeader(string(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cookies) == 0 {
+		return nil, fmt.Errorf("no cookies found in import data")
+	}
+	return &cookies, nil
+}
+
+// parseJSONCookies parses JSON cookies array or an object with `cookies` property
+func parseJSONCookies(data []byte) (Cookies, error) {
+	type jsonCookie struct {
+		Domain         string   `json:"domain"`
+		Name           string   `json:"name"`
+		Value          string   `json:"value"`
+		Expires        *float64 `json:"expires"`        // DevTools and Playwright, seconds
+		ExpirationDate *float64 `json:"expirationDate"` // EditThisCookie, seconds
+		Session        bool     `json:"session"`
+	}
+
+	var items []jsonCookie
+	if data[0] == '{' {
+		state := &struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}{}
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("can't parse JSON cookies: %w", err)
+		}
+		items = state.Cookies
+	} else if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("can't parse JSON cookies: %w", err)
+	}
+
+	cookies := Cookies{}
+	for _, item := range items {
+		if item.Name == "" {
+			continue
+		}
+		cookie := Cookie{Domain: item.Domain, Name: item.Name, Value: item.Value, Expires: -1}
+		if item.Expires != nil && *item.Expires > 0 {
+			cookie.Expires = *item.Expires
+		}
+		if item.ExpirationDate != nil && *item.ExpirationDate > 0 {
+			cookie.Expires = *item.ExpirationDate
+		}
+		if item.Session {
+			cookie.Expires = -1
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// parseNetscapeCookies parses Netscape `cookies.txt` format:
+// domain, include subdomains, path, secure, expiry (unix seconds), name and value separated with tabs
+func parseNetscapeCookies(data []byte) (Cookies, error) {
+	cookies := Cookies{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// HttpOnly cookies are prefixed as comments by curl and browser extensions
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("can't parse cookies.txt line %d: unexpected format", n)
+		}
+		expires, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse cookies.txt line %d: invalid expiry %s", n, fields[4])
+		}
+		if expires == 0 {
+			expires = -1 // session cookie
+		}
+		cookie := Cookie{Domain: fields[0], Name: fields[5], Expires: expires}
+		if len(fields) > 6 {
+			cookie.Value = fields[6]
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, scanner.Err()
+}
+
+// parseCookieHeader parses raw `Cookie` header value, such cookies have no expiry information
+func parseCookieHeader(header string) (Cookies, error) {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "Cookie:"))
+	cookies := Cookies{}
+	for _, pair := range strings.Split(header, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("can't parse cookie header: invalid pair %q", pair)
+		}
+		cookies = append(cookies, Cookie{Name: strings.TrimSpace(name), Value: value, Expires: -1})
+	}
+	return cookies, nil
+}
+
+// forHost gets cookies applicable to the host, cookies without domain are kept
+func (cookies *Cookies) forHost(host string) *Cookies {
+	res := Cookies{}
+	for _, cookie := range *cookies {
+		domain := strings.TrimPrefix(strings.ToLower(cookie.Domain), ".")
+		if domain == "" || host == domain || strings.HasSuffix(host, "."+domain) {
+			res = append(res, cookie)
+		}
+	}
+	return &res
+}
+
+// importCookies loads cookies from config's `cookie` header or `cookiesFile` export
+func (c *AuthCnfg) importCookies() (*Cookies, error) {
+	u, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []byte(c.Cookie)
+	if c.Cookie == "" {
+		if data, err = os.ReadFile(c.CookiesFile); err != nil {
+			return nil, err
+		}
+	}
+
+	cookies, err := ImportCookies(data)
+	if err != nil {
+		return nil, err
+	}
+	cookies = cookies.forHost(strings.ToLower(u.Hostname()))
+	if len(*cookies) == 0 {
+		return nil, fmt.Errorf("no imported cookies match %s", u.Host)
+	}
+	return cookies, nil
+}
@@ -0,0 +1,138 @@
+package ondemand
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportCookies(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+
+	t.Run("Netscape", func(t *testing.T) {
+		data := fmt.Sprintf("# Netscape HTTP Cookie File\n\n"+
+			"#HttpOnly_.contoso.sharepoint.com\tTRUE\t/\tTRUE\t%d\tFedAuth\tfed-auth\n"+
+			".contoso.sharepoint.com\tTRUE\t/\tTRUE\t0\trtFa\trt-fa\r\n", expires)
+		cookies, err := ImportCookies([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*cookies) != 2 || (*cookies)[0].Name != "FedAuth" || (*cookies)[0].Expires != float64(expires) || (*cookies)[1].Expires != -1 {
+			t.Errorf("unexpected cookies: %+v", cookies)
+		}
+		if _, err := ImportCookies([]byte("#comment\ndomain\tTRUE\t/\n")); err == nil {
+			t.Error("malformed cookies.txt should not pass")
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		formats := map[string]string{
+			"DevTools":       `[{"domain":".contoso.sharepoint.com","name":"FedAuth","value":"fed-auth","expires":%d}]`,
+			"EditThisCookie": `[{"domain":".contoso.sharepoint.com","name":"FedAuth","value":"fed-auth","expirationDate":%d,"session":false}]`,
+			"Playwright":     `{"cookies":[{"domain":".contoso.sharepoint.com","name":"FedAuth","value":"fed-auth","expires":%d}],"origins":[]}`,
+		}
+		for name, format := range formats {
+			cookies, err := ImportCookies([]byte(fmt.Sprintf(format, expires)))
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+			if len(*cookies) != 1 || (*cookies)[0].Value != "fed-auth" || (*cookies)[0].getExpire() != expires {
+				t.Errorf("%s: unexpected cookies: %+v", name, cookies)
+			}
+		}
+		if _, err := ImportCookies([]byte(`[{"name":`)); err == nil {
+			t.Error("malformed JSON should not pass")
+		}
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		cookies, err := ImportCookies([]byte("Cookie: FedAuth=fed=auth; rtFa=rt-fa"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cookies.toString() != "FedAuth=fed=auth; rtFa=rt-fa" || cookies.isExpired() {
+			t.Errorf("unexpected cookies: %s", cookies.toString())
+		}
+		if _, err := ImportCookies([]byte("FedAuth")); err == nil {
+			t.Error("malformed header should not pass")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		if _, err := ImportCookies([]byte(" \n")); err == nil {
+			t.Error("empty data should not pass")
+		}
+	})
+
+	t.Run("ForHost", func(t *testing.T) {
+		cookies := &Cookies{
+			{Domain: ".sharepoint.com", Name: "a"},
+			{Domain: "contoso.sharepoint.com", Name: "b"},
+			{Domain: "fabrikam.sharepoint.com", Name: "c"},
+			{Name: "d"},
+		}
+		if res := cookies.forHost("contoso.sharepoint.com").toString(); res != "a=; b=; d=" {
+			t.Errorf("unexpected cookies: %s", res)
+		}
+	})
+}
+
+func TestImportedAuth(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "private.ondemand.json")
+
+	t.Run("CookiesFile", func(t *testing.T) {
+		cookiesFile := fmt.Sprintf("[{\"domain\":\".import.contoso.com\",\"name\":\"FedAuth\",\"value\":\"fed-auth\",\"expires\":%d}]", time.Now().Add(time.Hour).Unix())
+		_ = os.WriteFile(filepath.Join(dir, "cookies.json"), []byte(cookiesFile), 0600)
+		_ = os.WriteFile(configPath, []byte(`{"siteUrl":"https://import.contoso.com/sites/test","cookiesFile":"cookies.json"}`), 0600)
+
+		cnfg := &AuthCnfg{}
+		if err := cnfg.ReadConfig(configPath); err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = cnfg.CleanCookieCache() }()
+
+		cookie, _, err := cnfg.GetAuth()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cookie != "FedAuth=fed-auth" {
+			t.Errorf("unexpected cookie: %s", cookie)
+		}
+		if _, err := cnfg.getCookieDiskCache(); err != nil {
+			t.Errorf("imported cookies are not cached: %s", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		cookiesFile := filepath.Join(dir, "cookies.txt")
+		_ = os.WriteFile(cookiesFile, []byte(fmt.Sprintf("expired.contoso.com\tFALSE\t/\tTRUE\t%d\tFedAuth\tfed-auth\n", time.Now().Add(-time.Hour).Unix())), 0600)
+		cnfg := &AuthCnfg{SiteURL: "https://expired.contoso.com", CookiesFile: cookiesFile}
+		defer func() { _ = cnfg.CleanCookieCache() }()
+
+		if _, _, err := cnfg.GetAuth(); err == nil || !strings.Contains(err.Error(), "expired") {
+			t.Errorf("expired cookies should fail with re-login error: %v", err)
+		}
+	})
+
+	t.Run("WriteConfig", func(t *testing.T) {
+		cnfg := &AuthCnfg{SiteURL: "https://import.contoso.com", Cookie: "FedAuth=fed-auth"}
+		if err := cnfg.WriteConfig(configPath); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(configPath)
+		if strings.Contains(string(data), "fed-auth") {
+			t.Error("cookie header should be encoded")
+		}
+		restored := &AuthCnfg{}
+		if err := restored.ReadConfig(configPath); err != nil {
+			t.Fatal(err)
+		}
+		if restored.Cookie != cnfg.Cookie {
+			t.Errorf("cookie header is not restored: %s", restored.Cookie)
+		}
+	})
+}
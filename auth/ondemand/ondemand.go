@@ -15,12 +15,10 @@ import (
 
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 )
 
-var (
-	cookieCache = map[string]*Cookies{} // ToDo: Replace with sync.Map
-	crypter     = cpass.Cpass("")
-)
+var cookieCache = map[string]*Cookies{} // ToDo: Replace with sync.Map
 
 // AuthCnfg - On-Demand auth config structure
 /* Config sample:
@@ -28,13 +26,26 @@ var (
   "siteUrl": "https://contoso.sharepoint.com/sites/test",
 }
 */
+/* Pre-captured cookies config sample (no browser is launched):
+{
+  "siteUrl": "https://contoso.sharepoint.com/sites/test",
+  "cookiesFile": "./cookies.txt"
+}
+*/
 type AuthCnfg struct {
-	SiteURL    string             `json:"siteUrl"`    // SPSite or SPWeb URL, which is the context target for the API calls
-	ChromeArgs *map[string]string `json:"chromeArgs"` // Arbitrary parameters to be used with embedded browser (see more: https://www.chromium.org/developers/how-tos/run-chromium-with-flags/, https://peter.sh/experiments/chromium-command-line-switches/)
+	SiteURL     string                 `json:"siteUrl" required:"true"` // SPSite or SPWeb URL, which is the context target for the API calls
+	ChromeArgs  *map[string]string     `json:"chromeArgs"`              // Arbitrary parameters to be used with embedded browser (see more: https://www.chromium.org/developers/how-tos/run-chromium-with-flags/, https://peter.sh/experiments/chromium-command-line-switches/)
+	CookiesFile string                 `json:"cookiesFile"`             // Browser-exported cookies file (Netscape cookies.txt or JSON), relative to config location or absolute
+	Cookie      string                 `json:"cookie" secret:"true"`    // Raw `Cookie` header value captured from a browser session
+	Transport   *gosip.TransportConfig `json:"transport,omitempty"`     // HTTP transport settings (optional)
+
+	privateFile string
+	secrets     secret.Refs
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	f, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -46,18 +57,54 @@ func (c *AuthCnfg) ReadConfig(privateFile string) error {
 
 // ParseConfig parses credentials from a provided JSON byte array content
 func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
-	return json.Unmarshal(byteValue, &c)
+	if err := json.Unmarshal(byteValue, &c); err != nil {
+		return err
+	}
+	if err := c.secrets.Resolve(&c.Cookie); err != nil {
+		return err
+	}
+	crypt, err := cpass.New("")
+	if err != nil {
+		return err
+	}
+	if cookie, err := crypt.Decode(c.Cookie); err == nil {
+		c.Cookie = cookie
+	}
+	if c.CookiesFile != "" && !filepath.IsAbs(c.CookiesFile) && c.privateFile != "" {
+		c.CookiesFile = filepath.Join(filepath.Dir(c.privateFile), c.CookiesFile)
+	}
+	return c.Transport.Prepare(c.privateFile, "")
 }
 
 // WriteConfig writes private config with auth options
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	config := &AuthCnfg{SiteURL: c.SiteURL}
+	config := &AuthCnfg{SiteURL: c.SiteURL, CookiesFile: c.CookiesFile}
+	if c.Cookie != "" {
+		crypt, err := cpass.New("")
+		if err != nil {
+			return err
+		}
+		cookie, err := c.secrets.Encode(&c.Cookie, crypt.Encode)
+		if err != nil {
+			return err
+		}
+		config.Cookie = cookie
+	}
+	transport, err := c.Transport.Encode("")
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
 
 // GetAuth authenticates, receives access token
 func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	if c.Cookie != "" || c.CookiesFile != "" {
+		return c.getImportedAuth()
+	}
+
 	u, _ := url.Parse(c.SiteURL)
 
 	// Check cached cookie per host
@@ -95,12 +142,47 @@ func (c *AuthCnfg) GetAuth() (string, int64, error) {
 	return cookies.toString(), cookies.getExpire(), nil
 }
 
+// getImportedAuth authenticates with pre-captured cookies instead of the browser flow
+func (c *AuthCnfg) getImportedAuth() (string, int64, error) {
+	u, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Check cached cookie per host
+	if cookies := cookieCache[u.Host]; cookies != nil && !cookies.isExpired() {
+		return cookies.toString(), cookies.getExpire(), nil
+	}
+
+	cookies, err := c.importCookies()
+	if err != nil {
+		// Fallback to previously imported cookies in disk cache
+		cached, cacheErr := c.getCookieDiskCache()
+		if cacheErr != nil {
+			return "", 0, err
+		}
+		cookies = cached
+	}
+
+	if cookies.isExpired() {
+		return "", 0, fmt.Errorf("imported cookies for %s are expired, sign in with a browser and export the cookies again", u.Host)
+	}
+
+	_ = c.cacheCookieToDisk(cookies)
+	cookieCache[u.Host] = cookies
+
+	return cookies.toString(), cookies.getExpire(), nil
+}
+
 // GetSiteURL gets SharePoint siteURL
 func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "ondemand" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth authenticates request
 // noinspection ALL
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
@@ -138,7 +220,14 @@ func (c *AuthCnfg) cacheCookieToDisk(cookies *Cookies) error {
 	if err != nil {
 		return err
 	}
-	cookieCacheE, _ := crypter.Encode(fmt.Sprintf("%s", cookieCache))
+	crypt, err := cpass.New("")
+	if err != nil {
+		return err
+	}
+	cookieCacheE, err := crypt.Encode(fmt.Sprintf("%s", cookieCache))
+	if err != nil {
+		return err
+	}
 	cookieCache = []byte(cookieCacheE)
 
 	_ = os.MkdirAll(tmpDir, os.ModePerm)
@@ -156,7 +245,11 @@ func (c *AuthCnfg) getCookieDiskCache() (*Cookies, error) {
 	if err != nil {
 		return nil, err
 	}
-	cookieCacheD, _ := crypter.Decode(fmt.Sprintf("%s", cookieCache))
+	crypt, err := cpass.New("")
+	if err != nil {
+		return nil, err
+	}
+	cookieCacheD, _ := crypt.Decode(fmt.Sprintf("%s", cookieCache))
 	cookieCache = []byte(cookieCacheD)
 
 	cookies := &Cookies{}
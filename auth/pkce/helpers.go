@@ -0,0 +1,239 @@
+package pkce
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// authCodeFlow runs interactive authorization code flow with a loopback redirect
+func (c *AuthCnfg) authCodeFlow() (*Token, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", c.RedirectPort))
+	if err != nil {
+		return nil, fmt.Errorf("can't start loopback listener: %w", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	// Loopback IP literal is used rather than localhost, which may resolve to another interface (RFC 8252, 7.3)
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d/", listener.Addr().(*net.TCPAddr).Port)
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	params := url.Values{}
+	params.Set("client_id", c.ClientID)
+	params.Set("response_type", "code")
+	params.Set("response_mode", "query")
+	params.Set("redirect_uri", redirectURI)
+	params.Set("scope", c.getScope())
+	params.Set("state", state)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+	authURL := c.getEndpoint("authorize") + "?" + params.Encode()
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			if query.Get("code") == "" && query.Get("error") == "" {
+				http.NotFound(w, r)
+				return
+			}
+			// Responses not bound to the flow state are ignored, so they can't end the sign in
+			if query.Get("state") != state {
+				http.Error(w, "authorization response state mismatch", http.StatusBadRequest)
+				return
+			}
+			result := callbackResult{code: query.Get("code")}
+			if e := query.Get("error"); e != "" {
+				result.err = fmt.Errorf("%s: %s", e, query.Get("error_description"))
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if result.err != nil {
+				_, _ = fmt.Fprintf(w, callbackPage, "Authentication failed", "You can close this window and check the terminal output.")
+			} else {
+				_, _ = fmt.Fprintf(w, callbackPage, "Authentication complete", "You can close this window and return to the application.")
+			}
+			select {
+			case results <- result:
+			default:
+			}
+		}),
+	}
+	go func() { _ = srv.Serve(listener) }()
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+
+	openURL := c.OpenURL
+	if openURL == nil {
+		openURL = openBrowser
+	}
+	fmt.Printf("To sign in, open the following URL in a browser:\n%s\n", authURL)
+	if err := openURL(authURL); err != nil {
+		fmt.Printf("Unable to open a browser: %s\n", err)
+	}
+
+	timeout := time.Duration(c.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+
+	var result callbackResult
+	select {
+	case result = <-results:
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("sign in was not completed in %s", timeout)
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	tokenParams := url.Values{}
+	tokenParams.Set("grant_type", "authorization_code")
+	tokenParams.Set("code", result.code)
+	tokenParams.Set("redirect_uri", redirectURI)
+	tokenParams.Set("code_verifier", verifier)
+	return c.requestToken(tokenParams)
+}
+
+// refreshToken exchanges refresh token for a new access token
+func (c *AuthCnfg) refreshToken(refreshToken string) (*Token, error) {
+	params := url.Values{}
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", refreshToken)
+	return c.requestToken(params)
+}
+
+// requestToken sends token endpoint request
+func (c *AuthCnfg) requestToken(params url.Values) (*Token, error) {
+	params.Set("client_id", c.ClientID)
+	params.Set("scope", c.getScope())
+
+	client, err := c.getClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(c.getEndpoint("token"), "application/x-www-form-urlencoded", strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	type tokenResponse struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Error        string `json:"error"`
+		Description  string `json:"error_description"`
+	}
+
+	results := &tokenResponse{}
+	if err := json.Unmarshal(data, results); err != nil {
+		return nil, fmt.Errorf("can't parse token response (%s): %w", resp.Status, err)
+	}
+	if results.Error != "" {
+		return nil, fmt.Errorf("%s: %s", results.Error, results.Description)
+	}
+	if results.AccessToken == "" {
+		return nil, fmt.Errorf("received empty access token (%s)", resp.Status)
+	}
+
+	token := &Token{
+		AccessToken:  results.AccessToken,
+		RefreshToken: results.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(results.ExpiresIn) * time.Second),
+	}
+	// Refresh token might not be rotated, keeping the existing one
+	if token.RefreshToken == "" {
+		token.RefreshToken = params.Get("refresh_token")
+	}
+
+	return token, nil
+}
+
+// getEndpoint resolves AAD v2 OAuth2 endpoint
+func (c *AuthCnfg) getEndpoint(name string) string {
+	authority := c.AuthorityHost
+	if authority == "" {
+		u, _ := url.Parse(c.SiteURL)
+		authority = getAADEndpoint(u.Host)
+	}
+	tenantID := c.TenantID
+	if tenantID == "" {
+		tenantID = "organizations"
+	}
+	return fmt.Sprintf("%s/%s/oauth2/v2.0/%s", strings.TrimSuffix(authority, "/"), tenantID, name)
+}
+
+// getScope gets SharePoint host scope with refresh token
+func (c *AuthCnfg) getScope() string {
+	u, _ := url.Parse(c.SiteURL)
+	return fmt.Sprintf("https://%s/.default offline_access", u.Host)
+}
+
+// getAADEndpoint returns the Azure AD endpoint based on SharePoint domain
+func getAADEndpoint(host string) string {
+	switch {
+	case strings.HasSuffix(host, ".sharepoint.us"):
+		return azure.USGovernmentCloud.ActiveDirectoryEndpoint
+	case strings.HasSuffix(host, ".sharepoint.cn"):
+		return azure.ChinaCloud.ActiveDirectoryEndpoint
+	case strings.HasSuffix(host, ".sharepoint.de"):
+		return azure.GermanCloud.ActiveDirectoryEndpoint
+	default:
+		return azure.PublicCloud.ActiveDirectoryEndpoint
+	}
+}
+
+// openBrowser opens URL in the default system browser
+func openBrowser(authURL string) error {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", authURL).Start()
+	case "darwin":
+		return exec.Command("open", authURL).Start()
+	default:
+		return exec.Command("xdg-open", authURL).Start()
+	}
+}
+
+// randomString generates URL safe random string from n random bytes
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+const callbackPage = `<html><head><title>Gosip</title></head>` +
+	`<body style="font-family: sans-serif; text-align: center; margin-top: 160px;"><h1>%s</h1><p>%s</p></body></html>`
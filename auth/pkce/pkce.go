@@ -0,0 +1,257 @@
+// Package pkce implements AAD Authorization Code + PKCE Auth Flow
+// The flow is interactive and intended for desktop and CLI tools: a temporary loopback
+// HTTP listener receives the authorization code after a user signs in in a browser.
+// See more:
+//   - https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-auth-code-flow
+//   - https://datatracker.ietf.org/doc/html/rfc8252#section-7.3
+//
+// Amongst supported platform versions are:
+//   - SharePoint Online + Azure
+package pkce
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/cpass"
+)
+
+var (
+	tokenCache = map[string]*Token{}
+	tokenMux   sync.Mutex
+)
+
+// AuthCnfg - AAD Authorization Code + PKCE auth config structure
+/* Config sample:
+{
+  "siteUrl": "https://contoso.sharepoint.com/sites/test",
+  "clientId": "61367a97-562c-4372-a9ee-b35307abdd26",
+  "tenantId": "3f83fe32-29b2-488e-8c3f-c8b7a2e19a2f"
+}
+*/
+// The app registration must allow public client flows and contain `http://localhost`
+// redirect URI under "Mobile and desktop applications" platform.
+type AuthCnfg struct {
+	SiteURL       string                 `json:"siteUrl" required:"true"`  // SPSite or SPWeb URL, which is the context target for the API calls
+	ClientID      string                 `json:"clientId" required:"true"` // Azure AD App Registration Client ID
+	TenantID      string                 `json:"tenantId"`                 // Azure AD App Registration Tenant ID, "organizations" when empty
+	AuthorityHost string                 `json:"authorityHost"`            // Azure AD authority, e.g. https://login.microsoftonline.com/ (optional)
+	RedirectPort  int                    `json:"redirectPort"`             // Loopback listener port, a random free port is used when empty
+	Timeout       int                    `json:"timeout"`                  // Seconds to wait for the sign in to complete, 300 by default
+	Transport     *gosip.TransportConfig `json:"transport,omitempty"`      // HTTP transport settings (optional)
+
+	OpenURL func(authURL string) error `json:"-"` // Custom authorize URL opener, e.g. to print the URL only
+
+	client      *http.Client // token requests client, created once from transport settings
+	mux         sync.Mutex
+	privateFile string
+}
+
+// getClient gets or creates HTTP client for token requests
+func (c *AuthCnfg) getClient() (*http.Client, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.client == nil {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
+	}
+	return c.client, nil
+}
+
+// Token - cached token information
+type Token struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// ReadConfig reads private config with auth options
+func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
+	f, err := os.Open(privateFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	byteValue, _ := io.ReadAll(f)
+	return c.ParseConfig(byteValue)
+}
+
+// ParseConfig parses credentials from a provided JSON byte array content
+func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
+	if err := json.Unmarshal(byteValue, &c); err != nil {
+		return err
+	}
+	return c.Transport.Prepare(c.privateFile, "")
+}
+
+// WriteConfig writes private config with auth options
+func (c *AuthCnfg) WriteConfig(privateFile string) error {
+	config := &AuthCnfg{
+		SiteURL:       c.SiteURL,
+		ClientID:      c.ClientID,
+		TenantID:      c.TenantID,
+		AuthorityHost: c.AuthorityHost,
+		RedirectPort:  c.RedirectPort,
+		Timeout:       c.Timeout,
+	}
+	transport, err := c.Transport.Encode("")
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
+	file, _ := json.MarshalIndent(config, "", "  ")
+	return os.WriteFile(privateFile, file, 0644)
+}
+
+// GetAuth authenticates, receives access token
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	key, err := c.cacheKey()
+	if err != nil {
+		return "", 0, err
+	}
+
+	tokenMux.Lock()
+	defer tokenMux.Unlock()
+
+	// Check cached token per app registration and host
+	token := tokenCache[key]
+
+	// Check disk cache
+	if token == nil {
+		token, _ = c.getTokenDiskCache()
+	}
+
+	if token != nil {
+		// Return cached token if not expired
+		if time.Now().Add(60 * time.Second).Before(token.ExpiresAt) {
+			tokenCache[key] = token
+			return token.AccessToken, token.ExpiresAt.Unix(), nil
+		}
+		// Expired, try to refresh
+		if token.RefreshToken != "" {
+			if refreshed, err := c.refreshToken(token.RefreshToken); err == nil {
+				tokenCache[key] = refreshed
+				_ = c.cacheTokenToDisk(refreshed)
+				return refreshed.AccessToken, refreshed.ExpiresAt.Unix(), nil
+			}
+		}
+		// Failed to refresh, initiating for the interactive auth flow
+	}
+
+	token, err = c.authCodeFlow()
+	if err != nil {
+		return "", 0, err
+	}
+
+	_ = c.cacheTokenToDisk(token)
+
+	tokenCache[key] = token
+	return token.AccessToken, token.ExpiresAt.Unix(), nil
+}
+
+// GetSiteURL gets SharePoint siteURL
+func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
+
+// GetStrategy gets auth strategy name
+func (c *AuthCnfg) GetStrategy() string { return "pkce" }
+
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
+// SetAuth authenticates request
+// noinspection GoUnusedParameter
+func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
+	accessToken, _, err := c.GetAuth()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return nil
+}
+
+// === File system token caching helpers === //
+
+// CleanTokenCache removes token information
+func (c *AuthCnfg) CleanTokenCache() error {
+	key, err := c.cacheKey()
+	if err != nil {
+		return err
+	}
+
+	tokenMux.Lock()
+	delete(tokenCache, key)
+	tokenMux.Unlock()
+
+	if err := os.Remove(c.getTokenCachePath()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// cacheKey gets in-memory token cache key, tokens are cached per app registration, tenant and host
+func (c *AuthCnfg) cacheKey() (string, error) {
+	u, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return "", err
+	}
+	return u.Host + "@" + c.TenantID + "@" + c.ClientID, nil
+}
+
+// cacheTokenToDisk writes serialized token to temporary cache file
+func (c *AuthCnfg) cacheTokenToDisk(token *Token) error {
+	tmpDir := filepath.Join(os.TempDir(), "gosip")
+	tokenCachePath := c.getTokenCachePath()
+
+	tokenCache, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	crypt, err := cpass.New("")
+	if err != nil {
+		return err
+	}
+	tokenCacheE, err := crypt.Encode(string(tokenCache))
+	if err != nil {
+		return err
+	}
+
+	_ = os.MkdirAll(tmpDir, os.ModePerm)
+	return os.WriteFile(tokenCachePath, []byte(tokenCacheE), 0600)
+}
+
+// getTokenDiskCache reads token from temporary cache file
+func (c *AuthCnfg) getTokenDiskCache() (*Token, error) {
+	tokenCache, err := os.ReadFile(c.getTokenCachePath())
+	if err != nil {
+		return nil, err
+	}
+	crypt, err := cpass.New("")
+	if err != nil {
+		return nil, err
+	}
+	tokenCacheD, _ := crypt.Decode(string(tokenCache))
+
+	token := &Token{}
+	if err := json.Unmarshal([]byte(tokenCacheD), token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// getTokenCachePath gets local file system file path with token cache
+func (c *AuthCnfg) getTokenCachePath() string {
+	tmpDir := filepath.Join(os.TempDir(), "gosip")
+	u, _ := url.Parse(c.SiteURL)
+	return filepath.Join(tmpDir, c.GetStrategy()+"_"+c.ClientID+"_"+u.Host)
+}
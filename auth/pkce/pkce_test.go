@@ -0,0 +1,200 @@
+package pkce
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	u "github.com/koltyakov/gosip/test/utils"
+)
+
+func TestAuthCodeFlow(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	var challenge, redirectURI string
+	grants := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tenant/oauth2/v2.0/authorize":
+			q := r.URL.Query()
+			challenge = q.Get("code_challenge")
+			redirectURI = q.Get("redirect_uri")
+			http.Redirect(w, r, q.Get("redirect_uri")+"?code=auth-code&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+		case "/tenant/oauth2/v2.0/token":
+			_ = r.ParseForm()
+			grant := r.Form.Get("grant_type")
+			grants[grant]++
+			if grant == "authorization_code" {
+				hash := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+				if r.Form.Get("code") != "auth-code" || base64.RawURLEncoding.EncodeToString(hash[:]) != challenge {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"code verifier mismatch"}`))
+					return
+				}
+			}
+			if grant == "refresh_token" && r.Form.Get("refresh_token") != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"unknown refresh token"}`))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"access_token":"access-%d","refresh_token":"refresh-1","expires_in":3600}`, grants["authorization_code"]+grants["refresh_token"])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cnfg := &AuthCnfg{
+		SiteURL:       "https://contoso-pkce.sharepoint.com/sites/test",
+		ClientID:      "client",
+		TenantID:      "tenant",
+		AuthorityHost: srv.URL,
+		Timeout:       10,
+		OpenURL: func(authURL string) error {
+			// Emulates a user signing in with a browser
+			go func() {
+				resp, err := http.Get(authURL)
+				if err == nil {
+					_ = resp.Body.Close()
+				}
+			}()
+			return nil
+		},
+	}
+
+	token, _, err := cnfg.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "access-1" {
+		t.Errorf("unexpected access token: %s", token)
+	}
+	if !strings.HasPrefix(redirectURI, "http://127.0.0.1:") {
+		t.Errorf("loopback IP redirect URI is expected, got %s", redirectURI)
+	}
+
+	// Disk cache must survive memory cache cleanup
+	tokenMux.Lock()
+	tokenCache = map[string]*Token{}
+	tokenMux.Unlock()
+	if token, _, err := cnfg.GetAuth(); err != nil || token != "access-1" {
+		t.Errorf("token is not restored from disk cache: %s, %v", token, err)
+	}
+
+	// Expired token must be refreshed without sign in
+	cached, err := cnfg.getTokenDiskCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached.ExpiresAt = time.Now().Add(-time.Minute)
+	_ = cnfg.cacheTokenToDisk(cached)
+	tokenMux.Lock()
+	tokenCache = map[string]*Token{}
+	tokenMux.Unlock()
+	token, _, err = cnfg.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "access-2" || grants["refresh_token"] != 1 || grants["authorization_code"] != 1 {
+		t.Errorf("token is not refreshed: %s, grants %v", token, grants)
+	}
+
+	// Another app registration on the same site doesn't share the token
+	other := &AuthCnfg{
+		SiteURL:       cnfg.SiteURL,
+		ClientID:      "other-client",
+		TenantID:      cnfg.TenantID,
+		AuthorityHost: cnfg.AuthorityHost,
+		Timeout:       cnfg.Timeout,
+		OpenURL:       cnfg.OpenURL,
+	}
+	if token, _, err := other.GetAuth(); err != nil || token == "access-2" || grants["authorization_code"] != 2 {
+		t.Errorf("token should not be shared between app registrations: %s, %v", token, err)
+	}
+	_ = other.CleanTokenCache()
+
+	if err := cnfg.CleanTokenCache(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuthCodeFlowErrors(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?error=access_denied&error_description=user+cancelled&state="+q.Get("state"), http.StatusFound)
+	}))
+	defer srv.Close()
+
+	forged := make(chan int, 1)
+
+	cnfg := &AuthCnfg{
+		SiteURL:       "https://contoso-pkce-denied.sharepoint.com",
+		ClientID:      "client",
+		TenantID:      "tenant",
+		AuthorityHost: srv.URL,
+		Timeout:       10,
+		OpenURL: func(authURL string) error {
+			go func() {
+				// A callback without the flow state must not end the sign in
+				parsed, _ := url.Parse(authURL)
+				if resp, err := http.Get(parsed.Query().Get("redirect_uri") + "?error=access_denied&state=forged"); err == nil {
+					forged <- resp.StatusCode
+					_ = resp.Body.Close()
+				}
+				if resp, err := http.Get(authURL); err == nil {
+					_ = resp.Body.Close()
+				}
+			}()
+			return nil
+		},
+	}
+
+	_, _, err := cnfg.GetAuth()
+	if err == nil || !strings.Contains(err.Error(), "user cancelled") {
+		t.Errorf("denied sign in should not pass: %v", err)
+	}
+	if status := <-forged; status != http.StatusBadRequest {
+		t.Errorf("callback with a forged state should be rejected, got %d", status)
+	}
+}
+
+func TestAuthEdgeCases(t *testing.T) {
+	t.Run("ReadConfig/MissedConfig", func(t *testing.T) {
+		cnfg := &AuthCnfg{}
+		if err := cnfg.ReadConfig("wrong_path.json"); err == nil {
+			t.Error("wrong_path config should not pass")
+		}
+	})
+
+	t.Run("WriteConfig", func(t *testing.T) {
+		folderPath := u.ResolveCnfgPath("./tmp")
+		filePath := u.ResolveCnfgPath("./tmp/private.pkce.json")
+		cnfg := &AuthCnfg{SiteURL: "test"}
+		_ = os.MkdirAll(folderPath, os.ModePerm)
+		if err := cnfg.WriteConfig(filePath); err != nil {
+			t.Error(err)
+		}
+		_ = os.RemoveAll(filePath)
+	})
+
+	t.Run("Client", func(t *testing.T) {
+		cnfg := &AuthCnfg{SiteURL: "test", Transport: &gosip.TransportConfig{Timeout: "1m"}}
+		first, err := cnfg.getClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if second, _ := cnfg.getClient(); second != first {
+			t.Error("token requests client should be reused")
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+)
+
+// DefaultProfile is a profile name used when no profile is selected
+const DefaultProfile = "default"
+
+// NewAuthFromProfile resolves AuthCnfg object based on a named profile of the credentials file
+// The credentials file is `~/.gosip/credentials.json` or a path from GOSIP_CREDENTIALS_FILE environment variable.
+// When name is empty, the profile is selected with GOSIP_PROFILE environment variable, "default" otherwise.
+/* Credentials file sample:
+{
+  "default": {
+    "strategy": "azurecert",
+    "siteUrl": "https://contoso.sharepoint.com/sites/dev",
+    "tenantId": "e4d43069-8ecb-49c4-8178-5bec83c53e9d",
+    "clientId": "628cc712-c9a4-48f0-a059-af64bdbb4be5",
+    "certPath": "/home/user/.gosip/cert.pfx",
+    "certPass": "env:SP_CERT_PASS"
+  },
+  "prod": {
+    "extends": "default",
+    "siteUrl": "https://contoso.sharepoint.com/sites/prod"
+  }
+}
+*/
+// A profile with "extends" property inherits properties of the parent profile and overrides the defined ones.
+func NewAuthFromProfile(name string) (gosip.AuthCnfg, error) {
+	credentialsFile := os.Getenv("GOSIP_CREDENTIALS_FILE")
+	if credentialsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		credentialsFile = filepath.Join(home, ".gosip", "credentials.json")
+	}
+	return NewAuthFromProfileFile(credentialsFile, name)
+}
+
+// NewAuthFromProfileFile resolves AuthCnfg object based on a named profile of a provided credentials file
+func NewAuthFromProfileFile(credentialsFile string, name string) (gosip.AuthCnfg, error) {
+	if name == "" {
+		name = os.Getenv("GOSIP_PROFILE")
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+
+	byteValue, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(byteValue, &profiles); err != nil {
+		return nil, fmt.Errorf("can't parse credentials file %s: %w", credentialsFile, err)
+	}
+
+	props, err := resolveProfile(profiles, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var strategy string
+	if err := json.Unmarshal(props["strategy"], &strategy); err != nil || strategy == "" {
+		return nil, fmt.Errorf("profile %s has no strategy", name)
+	}
+
+	auth, err := NewAuthByStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := json.Marshal(props)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := auth.ParseConfig(config); err != nil {
+		return nil, err
+	}
+
+	return auth, nil
+}
+
+// resolveProfile merges profile properties with its parents
+func resolveProfile(profiles map[string]map[string]json.RawMessage, name string, chain []string) (map[string]json.RawMessage, error) {
+	for _, n := range chain {
+		if n == name {
+			return nil, fmt.Errorf("profiles inheritance cycle: %s -> %s", strings.Join(chain, " -> "), name)
+		}
+	}
+	chain = append(chain, name)
+
+	profile, ok := profiles[name]
+	if !ok {
+		if len(chain) > 1 {
+			return nil, fmt.Errorf("profile %s extends unknown profile %s", chain[len(chain)-2], name)
+		}
+		return nil, fmt.Errorf("profile %s is not found", name)
+	}
+
+	props := map[string]json.RawMessage{}
+	if raw, ok := profile["extends"]; ok {
+		var parent string
+		if err := json.Unmarshal(raw, &parent); err != nil {
+			return nil, fmt.Errorf("profile %s has invalid extends property: %w", name, err)
+		}
+		parentProps, err := resolveProfile(profiles, parent, chain)
+		if err != nil {
+			return nil, err
+		}
+		props = parentProps
+	}
+
+	for key, value := range profile {
+		if key == "extends" {
+			continue
+		}
+		props[key] = value
+	}
+
+	return props, nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/gosip/auth/saml"
+)
+
+func TestAuthFromProfile(t *testing.T) {
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	_ = os.WriteFile(credentialsFile, []byte(`{
+		"default": {
+			"strategy": "saml",
+			"siteUrl": "https://contoso.sharepoint.com/sites/dev",
+			"username": "user@contoso.onmicrosoft.com",
+			"password": "env:GOSIP_TEST_PROFILE_PASS"
+		},
+		"prod": {
+			"extends": "default",
+			"siteUrl": "https://contoso.sharepoint.com/sites/prod"
+		},
+		"broken": { "extends": "loop" },
+		"loop": { "extends": "broken" },
+		"orphan": { "extends": "unknown" },
+		"nostrategy": { "siteUrl": "https://contoso.sharepoint.com" }
+	}`), 0600)
+	t.Setenv("GOSIP_CREDENTIALS_FILE", credentialsFile)
+	t.Setenv("GOSIP_PROFILE", "")
+	t.Setenv("GOSIP_TEST_PROFILE_PASS", "pass")
+
+	t.Run("Default", func(t *testing.T) {
+		auth, err := NewAuthFromProfile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnfg := auth.(*saml.AuthCnfg)
+		if cnfg.SiteURL != "https://contoso.sharepoint.com/sites/dev" || cnfg.Password != "pass" {
+			t.Errorf("unexpected config: %+v", cnfg)
+		}
+	})
+
+	t.Run("Inheritance", func(t *testing.T) {
+		auth, err := NewAuthFromProfile("prod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cnfg := auth.(*saml.AuthCnfg)
+		if cnfg.SiteURL != "https://contoso.sharepoint.com/sites/prod" || cnfg.Username != "user@contoso.onmicrosoft.com" {
+			t.Errorf("unexpected config: %+v", cnfg)
+		}
+	})
+
+	t.Run("Selector", func(t *testing.T) {
+		t.Setenv("GOSIP_PROFILE", "prod")
+		auth, err := NewAuthFromProfile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth.GetSiteURL() != "https://contoso.sharepoint.com/sites/prod" {
+			t.Errorf("profile is not selected: %s", auth.GetSiteURL())
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := map[string]string{
+			"missing":    "not found",
+			"broken":     "cycle",
+			"orphan":     "unknown profile",
+			"nostrategy": "no strategy",
+		}
+		for name, msg := range cases {
+			if _, err := NewAuthFromProfile(name); err == nil || !strings.Contains(err.Error(), msg) {
+				t.Errorf("%s profile should fail with \"%s\": %v", name, msg, err)
+			}
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		if _, err := NewAuthFromProfileFile(credentialsFile+".missing", ""); err == nil {
+			t.Error("missing credentials file should not pass")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/koltyakov/gosip"
+)
+
+var (
+	registry    = map[string]func() gosip.AuthCnfg{}
+	registryMux sync.RWMutex
+)
+
+// RegisterStrategy registers custom auth strategy constructor, a nil factory unregisters the strategy
+// Registered strategies are resolved by NewAuthByStrategy, NewAuthFromFile and NewAuthFromEnv
+// the same way as the built-in ones. Built-in strategy names can't be overridden,
+// registering one panics, as registration is a programming error which is usually done in init.
+func RegisterStrategy(name string, factory func() gosip.AuthCnfg) {
+	if newBuiltinAuth(name) != nil {
+		panic(fmt.Sprintf("auth: %s is a built-in strategy and can't be registered", name))
+	}
+	registryMux.Lock()
+	defer registryMux.Unlock()
+	if factory == nil {
+		delete(registry, name)
+		return
+	}
+	registry[name] = factory
+}
+
+// RegisteredStrategies gets names of the registered custom strategies
+func RegisteredStrategies() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveRegistered resolves custom strategy, returns nil when the strategy is not registered
+func resolveRegistered(name string) gosip.AuthCnfg {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	if factory, ok := registry[name]; ok {
+		return factory()
+	}
+	return nil
+}
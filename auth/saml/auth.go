@@ -13,11 +13,21 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 )
 
+// loginPages are sign in pages SharePoint redirects to when the session is not valid anymore
+var loginPages = []string{
+	"/_forms/default.aspx",           // SharePoint Online sign in redirect
+	"/_layouts/15/authenticate.aspx", // SharePoint sign in redirect
+	"login.microsoftonline",          // Azure AD sign in page
+	"/adfs/ls/",                      // Federated ADFS sign in form
+}
+
 // AuthCnfg - SAML auth config structure
 /* SharePoint Online config sample:
 {
@@ -26,17 +36,33 @@ import (
   "password": "this-is-not-a-real-password"
 }
 */
+/* SharePoint Online with federated WS-Trust IdP and a certificate config sample:
+{
+  "siteUrl": "https://contoso.sharepoint.com/sites/test",
+  "username": "john.doe@contoso.com",
+  "mexUrl": "https://sts.contoso.com/idp/mex",
+  "certPath": "./cert.pfx",
+  "certPass": "this-is-not-a-real-password"
+}
+*/
 type AuthCnfg struct {
-	SiteURL  string `json:"siteUrl"`  // SPSite or SPWeb URL, which is the context target for the API calls
-	Username string `json:"username"` // Username for SharePoint Online, for example `[user]@[company].onmicrosoft.com`
-	Password string `json:"password"` // User or App password
-
-	masterKey string
-	client    *http.Client
+	SiteURL   string                 `json:"siteUrl" required:"true"`  // SPSite or SPWeb URL, which is the context target for the API calls
+	Username  string                 `json:"username" required:"true"` // Username for SharePoint Online, for example `[user]@[company].onmicrosoft.com`
+	Password  string                 `json:"password" secret:"true"`   // User or App password
+	MexURL    string                 `json:"mexUrl"`                   // Federated IdP's WS-Trust MEX metadata URL, discovered from user realm if not provided
+	CertPath  string                 `json:"certPath"`                 // Client certificate (.pfx) for federated IdP's certificatemixed binding, relative to config location or absolute
+	CertPass  string                 `json:"certPass" secret:"true"`   // Client certificate export password
+	Transport *gosip.TransportConfig `json:"transport,omitempty"`      // HTTP transport settings (optional)
+
+	privateFile string
+	masterKey   string
+	secrets     secret.Refs
+	client      *http.Client
 }
 
 // ReadConfig reads private config with auth options
 func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
 	jsonFile, err := os.Open(privateFile)
 	if err != nil {
 		return err
@@ -52,20 +78,35 @@ func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
 	if err := json.Unmarshal(byteValue, &c); err != nil {
 		return err
 	}
+	if err := c.secrets.Resolve(&c.Password, &c.CertPass); err != nil {
+		return err
+	}
+	if c.CertPath != "" && !filepath.IsAbs(c.CertPath) && c.privateFile != "" {
+		c.CertPath = filepath.Join(filepath.Dir(c.privateFile), c.CertPath)
+	}
 
-	crypt := cpass.Cpass(c.masterKey)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
 	pass, err := crypt.Decode(c.Password)
 	if err == nil {
 		c.Password = pass
 	}
+	if secret, err := crypt.Decode(c.CertPass); err == nil {
+		c.CertPass = secret
+	}
 
-	return nil
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
 }
 
 // WriteConfig writes private config with auth options
 func (c *AuthCnfg) WriteConfig(privateFile string) error {
-	crypt := cpass.Cpass(c.masterKey)
-	pass, err := crypt.Encode(c.Password)
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	pass, err := c.secrets.Encode(&c.Password, crypt.Encode)
 	if err != nil {
 		pass = c.Password
 	}
@@ -73,7 +114,21 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 		SiteURL:  c.SiteURL,
 		Username: c.Username,
 		Password: pass,
+		MexURL:   c.MexURL,
+		CertPath: c.CertPath,
+	}
+	if c.CertPass != "" {
+		secret, err := c.secrets.Encode(&c.CertPass, crypt.Encode)
+		if err != nil {
+			return err
+		}
+		config.CertPass = secret
 	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
 	file, _ := json.MarshalIndent(config, "", "  ")
 	return os.WriteFile(privateFile, file, 0644)
 }
@@ -82,7 +137,9 @@ func (c *AuthCnfg) WriteConfig(privateFile string) error {
 func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
 
 // GetAuth authenticates, receives access token
-func (c *AuthCnfg) GetAuth() (string, int64, error) { return GetAuth(c) }
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	return c.secrets.Retry(func() (string, int64, error) { return GetAuth(c) })
+}
 
 // GetSiteURL gets siteURL
 func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
@@ -90,6 +147,9 @@ func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
 // GetStrategy gets auth strategy name
 func (c *AuthCnfg) GetStrategy() string { return "saml" }
 
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
 // SetAuth : authenticate request
 // noinspection GoUnusedParameter
 func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
@@ -103,3 +163,8 @@ func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error
 	req.Header.Set("Cookie", authCookie)
 	return nil
 }
+
+// IsSessionExpired checks if the response is a sign in page instead of the requested resource
+func (c *AuthCnfg) IsSessionExpired(resp *http.Response) bool {
+	return gosip.IsLoginResponse(resp, loginPages...)
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/patrickmn/go-cache"
 
+	"github.com/koltyakov/gosip/auth/internal/wstrust"
 	"github.com/koltyakov/gosip/templates"
 )
 
@@ -31,7 +32,11 @@ var (
 // GetAuth gets authentication
 func GetAuth(c *AuthCnfg) (string, int64, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", 0, err
+		}
+		c.client = client
 	}
 
 	parsedURL, err := url.Parse(c.SiteURL)
@@ -40,6 +45,9 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 	}
 
 	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.Username + "@" + c.Password
+	if c.CertPath != "" {
+		cacheKey += "@" + c.CertPath
+	}
 	if authToken, exp, found := storage.GetWithExpiration(cacheKey); found {
 		return authToken.(string), exp.Unix(), nil
 	}
@@ -60,7 +68,11 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 
 func getSecurityToken(c *AuthCnfg) (string, string, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", "", err
+		}
+		c.client = client
 	}
 
 	loginEndpoint := loginEndpoints[resolveSPOEnv(c.SiteURL)]
@@ -126,7 +138,11 @@ func getSecurityToken(c *AuthCnfg) (string, string, error) {
 
 func getSecurityTokenWithOnline(c *AuthCnfg) (string, string, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", "", err
+		}
+		c.client = client
 	}
 
 	parsedURL, err := url.Parse(c.SiteURL)
@@ -220,73 +236,18 @@ func getSecurityTokenWithOnline(c *AuthCnfg) (string, string, error) {
 // TODO: test the method, it possibly contains issues and extra complexity
 func getSecurityTokenWithAdfs(adfsURL string, c *AuthCnfg) (string, string, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
-	}
-
-	parsedAdfsURL, err := url.Parse(adfsURL)
-	if err != nil {
-		return "", "", err
-	}
-
-	// proxyURL, _ := url.Parse("http://127.0.0.1:8888")
-	// http.DefaultTransport = &http.Transport{Proxy: http.ProxyURL(proxyURL), TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
-
-	usernameMixedURL := fmt.Sprintf("%s://%s/adfs/services/trust/13/usernamemixed", parsedAdfsURL.Scheme, parsedAdfsURL.Host)
-	samlBody, err := templates.AdfsSamlWsfedTemplate(usernameMixedURL, c.Username, c.Password, "urn:federation:MicrosoftOnline")
-	if err != nil {
-		return "", "", err
-	}
-
-	req, err := http.NewRequest("POST", usernameMixedURL, bytes.NewBuffer([]byte(samlBody)))
-	if err != nil {
-		return "", "", err
-	}
-
-	req.Header.Set("Content-Type", "application/soap+xml;charset=utf-8")
-
-	// client := &http.Client{}
-	c.client.CheckRedirect = doNotCheckRedirect
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", "", err
-	}
-	defer func() {
-		if resp != nil && resp.Body != nil {
-			_ = resp.Body.Close()
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", "", err
 		}
-	}()
-
-	res, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", err
-	}
-
-	// fmt.Printf("ADFS: %s\n", string(res))
-
-	type samlAssertion struct {
-		Response struct {
-			Fault string `xml:"Fault>Reason>Text"`
-			Token struct {
-				Inner      []byte `xml:",innerxml"`
-				Conditions struct {
-					NotBefore    string `xml:"NotBefore,attr"`
-					NotOnOrAfter string `xml:"NotOnOrAfter,attr"`
-				} `xml:"Assertion>Conditions"`
-			} `xml:"RequestSecurityTokenResponseCollection>RequestSecurityTokenResponse>RequestedSecurityToken"`
-		} `xml:"Body"`
+		c.client = client
 	}
 
-	result := &samlAssertion{}
-	if err := xml.Unmarshal(res, &result); err != nil {
+	token, err := requestSecurityToken(adfsURL, c)
+	if err != nil {
 		return "", "", err
 	}
 
-	// fmt.Printf("Token: %s", result.Response.Token.Inner)
-
-	if result.Response.Fault != "" {
-		return "", "", errors.New(result.Response.Fault)
-	}
-
 	// parsedURL, err := url.Parse(adfsURL)
 	parsedURL, err := url.Parse(c.SiteURL)
 	if err != nil {
@@ -294,7 +255,7 @@ func getSecurityTokenWithAdfs(adfsURL string, c *AuthCnfg) (string, string, erro
 	}
 
 	rootSite := fmt.Sprintf("%s://%s", parsedURL.Scheme, parsedURL.Host)
-	tokenRequest, err := templates.OnlineSamlWsfedAdfsTemplate(rootSite, string(result.Response.Token.Inner))
+	tokenRequest, err := templates.OnlineSamlWsfedAdfsTemplate(rootSite, string(token.Assertion))
 	if err != nil {
 		return "", "", err
 	}
@@ -303,14 +264,14 @@ func getSecurityTokenWithAdfs(adfsURL string, c *AuthCnfg) (string, string, erro
 
 	stsEndpoint := "https://login.microsoftonline.com/extSTS.srf" // TODO: mapping
 
-	req, err = http.NewRequest("POST", stsEndpoint, bytes.NewBuffer([]byte(tokenRequest)))
+	req, err := http.NewRequest("POST", stsEndpoint, bytes.NewBuffer([]byte(tokenRequest)))
 	if err != nil {
 		return "", "", err
 	}
 
 	req.Header.Set("Content-Type", "application/soap+xml;charset=utf-8")
 
-	resp, err = c.client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
 		return "", "", err
 	}
@@ -379,7 +340,79 @@ func getSecurityTokenWithAdfs(adfsURL string, c *AuthCnfg) (string, string, erro
 	return authCookie, tokenResult.Response.Lifetime.Expires, nil
 }
 
+// requestSecurityToken requests SAML token for Microsoft Online from federated WS-Trust IdP,
+// the endpoint is discovered via MEX metadata, default ADFS endpoint is used when discovery fails
+// unless mexUrl is explicitly configured
+func requestSecurityToken(adfsURL string, c *AuthCnfg) (*wstrust.Token, error) {
+	creds := &wstrust.Credentials{Username: c.Username, Password: c.Password}
+	if c.CertPath != "" {
+		cert, key, err := wstrust.LoadCertificate(c.CertPath, c.CertPass)
+		if err != nil {
+			return nil, err
+		}
+		creds.Certificate = cert
+		creds.PrivateKey = key
+	}
+
+	mexURL, fallback, err := wstrust.ADFS(adfsURL, creds.Kind())
+	if err != nil {
+		return nil, err
+	}
+	if c.MexURL != "" {
+		mexURL = c.MexURL
+	} else if metadataURL, err := getFederationMetadataURL(c); err == nil && metadataURL != "" {
+		mexURL = metadataURL
+	}
+
+	c.client.CheckRedirect = doNotCheckRedirect
+	endpoint, err := wstrust.Resolve(c.client, mexURL, c.MexURL != "", creds.Kind(), fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	return wstrust.RequestToken(c.client, endpoint, creds, "urn:federation:MicrosoftOnline", "")
+}
+
+// getFederationMetadataURL gets federated IdP's MEX metadata URL from user realm info
+func getFederationMetadataURL(c *AuthCnfg) (string, error) {
+	loginEndpoint := loginEndpoints[resolveSPOEnv(c.SiteURL)]
+	endpoint := fmt.Sprintf("https://%s/common/userrealm/%s?api-version=1.0", loginEndpoint, url.PathEscape(c.Username))
+
+	resp, err := c.client.Get(endpoint)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	userRealm := &struct {
+		FederationMetadataURL string `json:"federation_metadata_url"`
+	}{}
+	if err := json.Unmarshal(data, userRealm); err != nil {
+		return "", err
+	}
+	return userRealm.FederationMetadataURL, nil
+}
+
 // doNotCheckRedirect *http.Client CheckRedirect callback to ignore redirects
 func doNotCheckRedirect(_ *http.Request, _ []*http.Request) error {
 	return http.ErrUseLastResponse
 }
+
+// CleanAuthCache removes auth cache
+func (c *AuthCnfg) CleanAuthCache() error {
+	parsedURL, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return err
+	}
+	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.Username + "@" + c.Password
+	if c.CertPath != "" {
+		cacheKey += "@" + c.CertPath
+	}
+	storage.Delete(cacheKey)
+	return nil
+}
@@ -18,7 +18,11 @@ var (
 // GetAuth gets authentication
 func GetAuth(c *AuthCnfg) (string, int64, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return "", 0, err
+		}
+		c.client = client
 	}
 
 	parsedURL, err := url.Parse(c.SiteURL)
@@ -87,7 +91,11 @@ func GetAuth(c *AuthCnfg) (string, int64, error) {
 
 func detectCookieAuthURL(c *AuthCnfg, siteURL string) (*url.URL, error) {
 	if c.client == nil {
-		c.client = &http.Client{}
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		c.client = client
 	}
 
 	// client := &http.Client{
@@ -132,3 +140,14 @@ func detectCookieAuthURL(c *AuthCnfg, siteURL string) (*url.URL, error) {
 func doNotCheckRedirect(_ *http.Request, _ []*http.Request) error {
 	return http.ErrUseLastResponse
 }
+
+// CleanAuthCache removes auth cache
+func (c *AuthCnfg) CleanAuthCache() error {
+	parsedURL, err := url.Parse(c.SiteURL)
+	if err != nil {
+		return err
+	}
+	cacheKey := parsedURL.Host + "@" + c.GetStrategy() + "@" + c.Username + "@" + c.Password
+	storage.Delete(cacheKey)
+	return nil
+}
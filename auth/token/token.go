@@ -0,0 +1,231 @@
+/*
+Package token implements externally managed credentials auth
+
+The strategy doesn't acquire credentials on its own but uses an access token or a cookie obtained elsewhere,
+e.g. from a broker service, an API gateway on-behalf-of flow or a script argument.
+Credentials can be either static or provided by a callback which is called when the previous value is expired.
+
+Amongst supported platform versions are:
+  - SharePoint Online (SPO)
+  - On-Premise: 2019, 2016, and 2013
+*/
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
+)
+
+// Credential modes
+const (
+	ModeBearer = "bearer" // credential is sent as `Authorization: Bearer <token>` header
+	ModeCookie = "cookie" // credential is sent as raw `Cookie` header
+)
+
+// Provider is a callback returning an externally managed credential and its expiration,
+// zero expiration means the credential is not cached and the provider is called for each request
+type Provider func(ctx context.Context) (token string, expiresAt time.Time, err error)
+
+// AuthCnfg - externally managed credentials auth config structure
+/* Static token config sample:
+{
+  "siteUrl": "https://contoso.sharepoint.com/sites/test",
+  "token": "eyJ0eXAiOiJKV1QiLCJhbGciOiJSUzI1NiIs...",
+  "mode": "bearer"
+}
+*/
+/* Static cookie config sample:
+{
+  "siteUrl": "https://www.contoso.com/sites/test",
+  "token": "FedAuth=77u/PD94bWwgdmVyc2lvbj0iMS4wIiBlbmNvZGluZz0idXRmLTgiPz4...",
+  "mode": "cookie"
+}
+*/
+// Callback provider can only be set in code:
+//
+//	auth := &token.AuthCnfg{
+//		SiteURL: "https://contoso.sharepoint.com/sites/test",
+//		Provider: func(ctx context.Context) (string, time.Time, error) {
+//			return broker.AccessToken(ctx, "https://contoso.sharepoint.com")
+//		},
+//	}
+type AuthCnfg struct {
+	SiteURL   string                 `json:"siteUrl" required:"true"`             // SPSite or SPWeb URL, which is the context target for the API calls
+	Token     string                 `json:"token" required:"true" secret:"true"` // Static access token or raw cookie header value, ignored when Provider is set
+	Mode      string                 `json:"mode"`                                // Credential mode: "bearer" (default) or "cookie"
+	Transport *gosip.TransportConfig `json:"transport,omitempty"`                 // HTTP transport settings (optional)
+
+	Provider Provider `json:"-"` // Credential callback, takes precedence over the static Token
+
+	privateFile string
+	masterKey   string
+	secrets     secret.Refs
+	mux         sync.Mutex
+	cached      string
+	expiresAt   time.Time
+}
+
+// ReadConfig reads private config with auth options
+func (c *AuthCnfg) ReadConfig(privateFile string) error {
+	c.privateFile = privateFile
+	jsonFile, err := os.Open(privateFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = jsonFile.Close() }()
+
+	byteValue, _ := io.ReadAll(jsonFile)
+	return c.ParseConfig(byteValue)
+}
+
+// ParseConfig parses credentials from a provided JSON byte array content
+func (c *AuthCnfg) ParseConfig(byteValue []byte) error {
+	if err := json.Unmarshal(byteValue, &c); err != nil {
+		return err
+	}
+	if err := c.secrets.Resolve(&c.Token); err != nil {
+		return err
+	}
+
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	token, err := crypt.Decode(c.Token)
+	if err == nil {
+		c.Token = token
+	}
+
+	return c.Transport.Prepare(c.privateFile, c.masterKey)
+}
+
+// WriteConfig writes private config with auth options
+func (c *AuthCnfg) WriteConfig(privateFile string) error {
+	crypt, err := cpass.New(c.masterKey)
+	if err != nil {
+		return err
+	}
+	token, err := c.secrets.Encode(&c.Token, crypt.Encode)
+	if err != nil {
+		token = c.Token
+	}
+	config := &AuthCnfg{
+		SiteURL: c.SiteURL,
+		Token:   token,
+		Mode:    c.Mode,
+	}
+	transport, err := c.Transport.Encode(c.masterKey)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
+	file, _ := json.MarshalIndent(config, "", "  ")
+	return os.WriteFile(privateFile, file, 0644)
+}
+
+// SetMasterkey defines custom masterkey
+func (c *AuthCnfg) SetMasterkey(masterKey string) { c.masterKey = masterKey }
+
+// GetAuth returns the credential, receives it from the provider when the cached one is expired
+func (c *AuthCnfg) GetAuth() (string, int64, error) {
+	return c.secrets.Retry(func() (string, int64, error) { return c.getAuth(context.Background()) })
+}
+
+// GetSiteURL gets siteURL
+func (c *AuthCnfg) GetSiteURL() string { return c.SiteURL }
+
+// GetStrategy gets auth strategy name
+func (c *AuthCnfg) GetStrategy() string { return "token" }
+
+// GetTransportConfig gets HTTP transport settings
+func (c *AuthCnfg) GetTransportConfig() *gosip.TransportConfig { return c.Transport }
+
+// SetAuth authenticates request
+// noinspection GoUnusedParameter
+func (c *AuthCnfg) SetAuth(req *http.Request, httpClient *gosip.SPClient) error {
+	authToken, _, err := c.secrets.Retry(func() (string, int64, error) { return c.getAuth(req.Context()) })
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(c.Mode, ModeCookie) {
+		req.Header.Set("Cookie", authToken)
+		return nil
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimPrefix(authToken, "Bearer "))
+	return nil
+}
+
+// CleanAuthCache removes the credential received from the provider
+func (c *AuthCnfg) CleanAuthCache() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.cached = ""
+	c.expiresAt = time.Time{}
+	return nil
+}
+
+// getAuth resolves static or provided credential
+func (c *AuthCnfg) getAuth(ctx context.Context) (string, int64, error) {
+	if c.Provider == nil {
+		if c.Token == "" {
+			return "", 0, fmt.Errorf("no token is provided")
+		}
+		exp := getJwtExpiration(c.Token)
+		if exp != 0 && time.Unix(exp, 0).Before(time.Now()) {
+			return "", 0, fmt.Errorf("static token is expired at %s", time.Unix(exp, 0).Format(time.RFC3339))
+		}
+		return c.Token, exp, nil
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.cached != "" && time.Now().Add(60*time.Second).Before(c.expiresAt) {
+		return c.cached, c.expiresAt.Unix(), nil
+	}
+
+	token, expiresAt, err := c.Provider(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("token provider error: %w", err)
+	}
+	if token == "" {
+		return "", 0, fmt.Errorf("token provider returned empty token")
+	}
+
+	c.cached, c.expiresAt = "", time.Time{}
+	if !expiresAt.IsZero() {
+		c.cached, c.expiresAt = token, expiresAt
+		return token, expiresAt.Unix(), nil
+	}
+
+	return token, 0, nil
+}
+
+// getJwtExpiration extracts `exp` claim from JWT, returns 0 for non JWT values
+func getJwtExpiration(token string) int64 {
+	tt := strings.Split(strings.TrimPrefix(token, "Bearer "), ".")
+	if len(tt) != 3 {
+		return 0
+	}
+	jsonBytes, err := base64.RawURLEncoding.DecodeString(tt[1])
+	if err != nil {
+		return 0
+	}
+	j := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	_ = json.Unmarshal(jsonBytes, &j)
+	return j.Exp
+}
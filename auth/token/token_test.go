@@ -0,0 +1,195 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	u "github.com/koltyakov/gosip/test/utils"
+)
+
+func TestStaticToken(t *testing.T) {
+	var authHeader, cookieHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		cookieHeader = r.Header.Get("Cookie")
+		_, _ = w.Write([]byte(`{"d":{}}`))
+	}))
+	defer srv.Close()
+
+	t.Run("Bearer", func(t *testing.T) {
+		client := &gosip.SPClient{AuthCnfg: &AuthCnfg{SiteURL: srv.URL, Token: "static"}}
+		if err := get(client); err != nil {
+			t.Fatal(err)
+		}
+		if authHeader != "Bearer static" {
+			t.Errorf("unexpected authorization header: %s", authHeader)
+		}
+	})
+
+	t.Run("Cookie", func(t *testing.T) {
+		client := &gosip.SPClient{AuthCnfg: &AuthCnfg{SiteURL: srv.URL, Token: "FedAuth=value", Mode: ModeCookie}}
+		if err := get(client); err != nil {
+			t.Fatal(err)
+		}
+		if cookieHeader != "FedAuth=value" {
+			t.Errorf("unexpected cookie header: %s", cookieHeader)
+		}
+	})
+
+	t.Run("ExpiredJwt", func(t *testing.T) {
+		claims := fmt.Sprintf(`{"exp":%d}`, time.Now().Add(-time.Hour).Unix())
+		jwt := "e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+		cnfg := &AuthCnfg{SiteURL: srv.URL, Token: jwt}
+		if _, _, err := cnfg.GetAuth(); err == nil {
+			t.Error("expired token should not pass")
+		}
+	})
+
+	t.Run("NoToken", func(t *testing.T) {
+		cnfg := &AuthCnfg{SiteURL: srv.URL}
+		if _, _, err := cnfg.GetAuth(); err == nil {
+			t.Error("empty token should not pass")
+		}
+	})
+}
+
+func TestProvider(t *testing.T) {
+	var authHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"d":{}}`))
+	}))
+	defer srv.Close()
+
+	t.Run("Caching", func(t *testing.T) {
+		calls := 0
+		cnfg := &AuthCnfg{
+			SiteURL: srv.URL,
+			Provider: func(ctx context.Context) (string, time.Time, error) {
+				calls++
+				return fmt.Sprintf("token-%d", calls), time.Now().Add(time.Hour), nil
+			},
+		}
+		client := &gosip.SPClient{AuthCnfg: cnfg}
+		for i := 0; i < 3; i++ {
+			if err := get(client); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("provider should be called once, called %d times", calls)
+		}
+		if authHeader != "Bearer token-1" {
+			t.Errorf("unexpected authorization header: %s", authHeader)
+		}
+		_ = cnfg.CleanAuthCache()
+		if err := get(client); err != nil {
+			t.Fatal(err)
+		}
+		if authHeader != "Bearer token-2" {
+			t.Errorf("unexpected authorization header after cache clean: %s", authHeader)
+		}
+	})
+
+	t.Run("NoExpiration", func(t *testing.T) {
+		calls := 0
+		cnfg := &AuthCnfg{
+			SiteURL: srv.URL,
+			Provider: func(ctx context.Context) (string, time.Time, error) {
+				calls++
+				return "token", time.Time{}, nil
+			},
+		}
+		for i := 0; i < 2; i++ {
+			if _, _, err := cnfg.GetAuth(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if calls != 2 {
+			t.Errorf("provider should be called for each request, called %d times", calls)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		cnfg := &AuthCnfg{
+			SiteURL: srv.URL,
+			Provider: func(ctx context.Context) (string, time.Time, error) {
+				return "", time.Time{}, errors.New("broker is down")
+			},
+		}
+		client := &gosip.SPClient{AuthCnfg: cnfg}
+		if err := get(client); err == nil {
+			t.Error("provider error should not pass")
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		type ctxKey struct{}
+		var received interface{}
+		cnfg := &AuthCnfg{
+			SiteURL: srv.URL,
+			Provider: func(ctx context.Context) (string, time.Time, error) {
+				received = ctx.Value(ctxKey{})
+				return "token", time.Time{}, nil
+			},
+		}
+		client := &gosip.SPClient{AuthCnfg: cnfg}
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		req = req.WithContext(context.WithValue(context.Background(), ctxKey{}, "value"))
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if received != "value" {
+			t.Error("request context is not passed to the provider")
+		}
+	})
+}
+
+func TestAuthEdgeCases(t *testing.T) {
+	t.Run("ReadConfig/MissedConfig", func(t *testing.T) {
+		cnfg := &AuthCnfg{}
+		if err := cnfg.ReadConfig("wrong_path.json"); err == nil {
+			t.Error("wrong_path config should not pass")
+		}
+	})
+
+	t.Run("WriteConfig", func(t *testing.T) {
+		folderPath := u.ResolveCnfgPath("./tmp")
+		filePath := u.ResolveCnfgPath("./tmp/private.token.json")
+		cnfg := &AuthCnfg{SiteURL: "test", Token: "secret"}
+		_ = os.MkdirAll(folderPath, os.ModePerm)
+		if err := cnfg.WriteConfig(filePath); err != nil {
+			t.Error(err)
+		}
+		c := &AuthCnfg{}
+		if err := c.ReadConfig(filePath); err != nil {
+			t.Error(err)
+		}
+		if c.Token != "secret" {
+			t.Error("token is not encrypted symmetrically")
+		}
+		_ = os.RemoveAll(filePath)
+	})
+}
+
+func get(client *gosip.SPClient) error {
+	req, err := http.NewRequest("GET", client.AuthCnfg.GetSiteURL()+"/_api/web", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Execute(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/koltyakov/gosip/auth/azurecert"
+)
+
+func main() {
+
+	var name, outDir, certPass, configPath string
+	var siteURL, tenantID, clientID string
+	var days, keySize int
+	var force bool
+
+	flag.StringVar(&name, "name", "gosip", "Certificate common name and files base name")
+	flag.IntVar(&days, "days", 365, "Certificate validity period in days")
+	flag.IntVar(&keySize, "keysize", 2048, "RSA key size in bits")
+	flag.StringVar(&certPass, "pass", "", "PFX export password, a random one is generated when empty")
+	flag.StringVar(&outDir, "out", ".", "Output folder")
+	flag.StringVar(&configPath, "config", "", "Private config path, <out>/private.json by default")
+	flag.StringVar(&siteURL, "siteUrl", "https://contoso.sharepoint.com", "SharePoint site URL for the private config")
+	flag.StringVar(&tenantID, "tenantId", "", "Azure Tenant ID for the private config")
+	flag.StringVar(&clientID, "clientId", "", "Azure Client ID for the private config")
+	flag.BoolVar(&force, "force", false, "Overwrite existing certificate files and private config")
+	flag.Parse()
+
+	if certPass == "" {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			log.Fatal(err)
+		}
+		certPass = base64.RawURLEncoding.EncodeToString(b)
+	}
+	if configPath == "" {
+		configPath = filepath.Join(outDir, "private.json")
+	}
+
+	pfxPath := filepath.Join(outDir, name+".pfx")
+	pemPath := filepath.Join(outDir, name+".pem")
+	keyPath := filepath.Join(outDir, name+".key")
+	if !force {
+		for _, path := range []string{pfxPath, pemPath, keyPath, configPath} {
+			if _, err := os.Stat(path); err == nil {
+				log.Fatalf("%s already exists, use -force flag to overwrite it", path)
+			}
+		}
+	}
+
+	cert, err := azurecert.GenerateCertificate(azurecert.CertificateOptions{
+		CommonName: name,
+		ValidDays:  days,
+		KeySize:    keySize,
+	})
+	if err != nil {
+		log.Fatalf("unable to generate certificate: %s", err)
+	}
+
+	pfx, err := cert.PFX(certPass)
+	if err != nil {
+		log.Fatalf("unable to encode PFX: %s", err)
+	}
+	keyPEM, err := cert.KeyPEM()
+	if err != nil {
+		log.Fatalf("unable to encode private key: %s", err)
+	}
+
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		log.Fatal(err)
+	}
+	files := []struct {
+		path string
+		data []byte
+		mode os.FileMode
+	}{
+		{pfxPath, pfx, 0600},
+		{pemPath, cert.CertPEM(), 0644},
+		{keyPath, keyPEM, 0600},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.path, f.data, f.mode); err != nil {
+			log.Fatalf("unable to write %s: %s", f.path, err)
+		}
+		fmt.Printf("Created %s\n", f.path)
+	}
+
+	// Certificate path is stored relative to the config location
+	certPath, err := filepath.Rel(filepath.Dir(configPath), pfxPath)
+	if err != nil {
+		certPath, _ = filepath.Abs(pfxPath)
+	}
+	cnfg := &azurecert.AuthCnfg{
+		SiteURL:  siteURL,
+		TenantID: tenantID,
+		ClientID: clientID,
+		CertPath: certPath,
+		CertPass: certPass,
+	}
+	if err := cnfg.WriteConfig(configPath); err != nil {
+		log.Fatalf("unable to write private config: %s", err)
+	}
+	fmt.Printf("Created %s, the certificate password is encrypted with cpass\n", configPath)
+
+	manifest, _ := json.MarshalIndent(map[string]interface{}{
+		"keyCredentials": []azurecert.KeyCredential{cert.KeyCredential()},
+	}, "", "  ")
+
+	fmt.Printf("\nThumbprint: %s\n", cert.Thumbprint())
+	fmt.Printf("Expires:    %s\n", cert.Certificate.NotAfter.Format("2006-01-02"))
+	fmt.Printf("PFX pass:   %s\n", certPass)
+	fmt.Printf("\nUpload %s.pem to the app registration \"Certificates & secrets\" or merge into the app manifest:\n\n%s\n", name, manifest)
+	if tenantID == "" || clientID == "" {
+		fmt.Printf("\nProvide tenantId and clientId of the app registration in %s\n", configPath)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/api"
+)
+
+// stage - load profile stage
+type stage struct {
+	workers  int
+	duration time.Duration
+}
+
+// bench - benchmark runner
+type bench struct {
+	auth    gosip.AuthCnfg
+	options *scenarioOptions
+	runs    []*scenarioRun
+}
+
+// scenarioRun - scenario with its own client and stats
+type scenarioRun struct {
+	name  string
+	run   scenario
+	sp    *api.SP
+	stats *stats
+}
+
+// stats - scenario measurements, counters are updated from the client hooks
+type stats struct {
+	mux       sync.Mutex
+	latencies []time.Duration
+	errors    int64
+	requests  int64
+	retries   int64
+	throttled int64
+	authTime  int64 // nanoseconds spent in auth and digest before requests are sent
+}
+
+// Report - benchmark results
+type Report struct {
+	Duration  time.Duration     `json:"duration"`
+	Scenarios []*ScenarioReport `json:"scenarios"`
+}
+
+// ScenarioReport - scenario results
+type ScenarioReport struct {
+	Name       string        `json:"name"`
+	Operations int           `json:"operations"`
+	Errors     int64         `json:"errors"`
+	Throughput float64       `json:"throughput"` // operations per second
+	P50        time.Duration `json:"p50"`
+	P95        time.Duration `json:"p95"`
+	P99        time.Duration `json:"p99"`
+	Requests   int64         `json:"requests"`  // HTTP requests including retries
+	Retries    int64         `json:"retries"`   // Retried requests
+	Throttled  int64         `json:"throttled"` // 429 and 503 responses
+	AuthAvg    time.Duration `json:"authAvg"`   // Average auth and digest overhead per request
+}
+
+// addScenario adds a scenario with a dedicated client sharing the auth config
+func (b *bench) addScenario(name string) error {
+	run, ok := scenarios[name]
+	if !ok {
+		return fmt.Errorf("unknown scenario %q, available: %s", name, strings.Join(scenarioNames(), ", "))
+	}
+	s := &stats{}
+	client := &gosip.SPClient{AuthCnfg: b.auth, Hooks: s.hooks()}
+	b.runs = append(b.runs, &scenarioRun{name: name, run: run, sp: api.NewSP(client), stats: s})
+	return nil
+}
+
+// run executes the load profile, workers take scenarios in turns
+func (b *bench) run(stages []stage, onStage func(stage)) *Report {
+	started := time.Now()
+	worker := 0
+	for _, st := range stages {
+		onStage(st)
+		deadline := time.Now().Add(st.duration)
+		var wg sync.WaitGroup
+		for i := 0; i < st.workers; i++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for iteration := 0; time.Now().Before(deadline); iteration++ {
+					r := b.runs[(worker+iteration)%len(b.runs)]
+					opStarted := time.Now()
+					err := r.run(r.sp, b.options, worker, iteration)
+					r.stats.add(time.Since(opStarted), err)
+				}
+			}(worker)
+			worker++
+		}
+		wg.Wait()
+	}
+	elapsed := time.Since(started)
+
+	report := &Report{Duration: elapsed}
+	for _, r := range b.runs {
+		report.Scenarios = append(report.Scenarios, r.stats.report(r.name, elapsed))
+	}
+	return report
+}
+
+// hooks counts requests, retries, throttling and auth overhead
+func (s *stats) hooks() *gosip.HookHandlers {
+	return &gosip.HookHandlers{
+		OnRequest: func(e *gosip.HookEvent) {
+			atomic.AddInt64(&s.requests, 1)
+			atomic.AddInt64(&s.authTime, int64(e.Duration))
+		},
+		OnRetry: func(e *gosip.HookEvent) {
+			atomic.AddInt64(&s.retries, 1)
+		},
+		OnError: func(e *gosip.HookEvent) {
+			if e.StatusCode == 429 || e.StatusCode == 503 {
+				atomic.AddInt64(&s.throttled, 1)
+			}
+		},
+	}
+}
+
+// add registers an operation result
+func (s *stats) add(latency time.Duration, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err != nil {
+		s.errors++
+		return
+	}
+	s.latencies = append(s.latencies, latency)
+}
+
+// report calculates scenario results
+func (s *stats) report(name string, elapsed time.Duration) *ScenarioReport {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	latencies := append([]time.Duration{}, s.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	r := &ScenarioReport{
+		Name:       name,
+		Operations: len(latencies),
+		Errors:     s.errors,
+		Throughput: float64(len(latencies)) / elapsed.Seconds(),
+		P50:        percentile(latencies, 50),
+		P95:        percentile(latencies, 95),
+		P99:        percentile(latencies, 99),
+		Requests:   atomic.LoadInt64(&s.requests),
+		Retries:    atomic.LoadInt64(&s.retries),
+		Throttled:  atomic.LoadInt64(&s.throttled),
+	}
+	if r.Requests > 0 {
+		r.AuthAvg = time.Duration(atomic.LoadInt64(&s.authTime) / r.Requests)
+	}
+	return r
+}
+
+// String formats the report as a table
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Duration: %s\n\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(&b, "%-10s %8s %7s %9s %10s %10s %10s %9s %8s %9s %10s\n",
+		"Scenario", "Ops", "Errors", "Ops/s", "p50", "p95", "p99", "Requests", "Retries", "Throttled", "Auth avg")
+	for _, s := range r.Scenarios {
+		fmt.Fprintf(&b, "%-10s %8d %7d %9.1f %10s %10s %10s %9d %8d %9d %10s\n",
+			s.Name, s.Operations, s.Errors, s.Throughput,
+			s.P50.Round(time.Microsecond), s.P95.Round(time.Microsecond), s.P99.Round(time.Microsecond),
+			s.Requests, s.Retries, s.Throttled, s.AuthAvg.Round(time.Microsecond))
+	}
+	return b.String()
+}
+
+// serverRelative resolves site-relative folder URL
+func serverRelative(siteURL string, folder string) string {
+	if strings.HasPrefix(folder, "/") {
+		return folder
+	}
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return folder
+	}
+	return strings.TrimRight(u.Path, "/") + "/" + folder
+}
+
+// percentile gets nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// parseProfile parses ramp stages, e.g. "1:10s,4:20s", or uses the fixed profile
+func parseProfile(ramp string, concurrency int, duration time.Duration) ([]stage, error) {
+	if ramp == "" {
+		if concurrency < 1 || duration <= 0 {
+			return nil, fmt.Errorf("positive concurrency and duration are expected")
+		}
+		return []stage{{workers: concurrency, duration: duration}}, nil
+	}
+	var stages []stage
+	for _, s := range strings.Split(ramp, ",") {
+		parts := strings.SplitN(strings.TrimSpace(s), ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("incorrect ramp stage %q, workers:duration is expected", s)
+		}
+		workers, err := strconv.Atoi(parts[0])
+		if err != nil || workers < 1 {
+			return nil, fmt.Errorf("incorrect ramp stage %q workers", s)
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("incorrect ramp stage %q duration", s)
+		}
+		stages = append(stages, stage{workers: workers, duration: d})
+	}
+	return stages, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// newFakeServer starts a local server imitating SharePoint endpoints used by the scenarios,
+// responses are static to measure the client overhead rather than the server
+func newFakeServer(latency time.Duration, throttle float64) *httptest.Server {
+	var itemID int64
+	items := `{"d":{"results":[` + strings.TrimSuffix(strings.Repeat(`{"__metadata":{"type":"SP.Data.BenchListItem"},"Id":1,"Title":"Bench"},`, 10), ",") + `]}}`
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		path := strings.ToLower(r.URL.Path)
+
+		if strings.HasSuffix(path, "/_api/contextinfo") {
+			_, _ = fmt.Fprint(w, `{"d":{"GetContextWebInformation":{"FormDigestValue":"FAKE","FormDigestTimeoutSeconds":1800,"LibraryVersion":"16.0.0.0"}}}`)
+			return
+		}
+		if throttle > 0 && rand.Float64() < throttle {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = fmt.Fprint(w, `{"error":{"code":"-2147024860, Microsoft.SharePoint.SPQueryThrottledException","message":{"value":"Throttled"}}}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json;odata=verbose;charset=utf-8")
+		switch {
+		case strings.HasSuffix(path, "/items") && r.Method == "GET":
+			_, _ = fmt.Fprint(w, items)
+		case strings.HasSuffix(path, "/items"):
+			id := atomic.AddInt64(&itemID, 1)
+			_, _ = fmt.Fprintf(w, `{"d":{"__metadata":{"id":"Web/Lists(guid'00000000-0000-0000-0000-000000000000')/Items(%d)","type":"SP.Data.BenchListItem"},"Id":%d,"Title":"Bench"}}`, id, id)
+		case strings.Contains(path, "/files/add("):
+			_, _ = fmt.Fprint(w, `{"d":{"__metadata":{"type":"SP.File"},"Name":"bench.bin","ServerRelativeUrl":"/sites/bench/Shared Documents/bench.bin"}}`)
+		case strings.HasSuffix(path, "/_api/search/postquery"):
+			_, _ = fmt.Fprint(w, `{"d":{"postquery":{"ElapsedTime":1,"PrimaryQueryResult":{"RelevantResults":{"RowCount":0,"TotalRows":0,"Table":{"Rows":{"results":[]}}}}}}}`)
+		case strings.HasSuffix(path, "/_vti_bin/client.svc/processquery"):
+			_, _ = fmt.Fprint(w, `[{"SchemaVersion":"15.0.0.0","LibraryVersion":"16.0.0.0","ErrorInfo":null,"TraceCorrelationId":"00000000-0000-0000-0000-000000000000"},1,{"IsNull":false},3,{"IsNull":false},5,{"_ObjectType_":"SP.Taxonomy.TermStore","Id":"\/Guid(00000000-0000-0000-0000-000000000000)\/","Name":"Bench"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":{"code":"-1, System.ArgumentException","message":{"value":"Not found"}}}`)
+		}
+	}))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth"
+	"github.com/koltyakov/gosip/auth/anon"
+)
+
+func main() {
+	config := flag.String("config", "./config/private.json", "Private config file path")
+	profile := flag.String("profile", "", "Named profile from the credentials file, used instead of the config")
+	fake := flag.Bool("fake", false, "Run against a local fake server instead of a site")
+	fakeLatency := flag.Duration("fake-latency", 0, "Fake server response latency")
+	fakeThrottle := flag.Float64("fake-throttle", 0, "Fake server share of throttled (429) responses, 0..1")
+
+	scenarios := flag.String("scenarios", "read", "Comma-separated scenarios: "+strings.Join(scenarioNames(), ", "))
+	list := flag.String("list", "Bench", "List title for read and write scenarios")
+	folder := flag.String("folder", "Shared Documents", "Folder URL for the upload scenario, relative to the site or server-relative")
+	size := flag.Float64("size", 1, "Upload file size, MB")
+	query := flag.String("query", "*", "Search query text")
+
+	concurrency := flag.Int("concurrency", 4, "Fixed number of concurrent workers")
+	duration := flag.Duration("duration", 30*time.Second, "Fixed profile duration")
+	ramp := flag.String("ramp", "", "Ramp profile stages as workers:duration, e.g. 1:10s,4:20s,8:30s, takes precedence over the fixed profile")
+	asJSON := flag.Bool("json", false, "Print the report as JSON")
+	flag.Parse()
+
+	stages, err := parseProfile(*ramp, *concurrency, *duration)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var authCnfg gosip.AuthCnfg
+	if *fake {
+		server := newFakeServer(*fakeLatency, *fakeThrottle)
+		defer server.Close()
+		authCnfg = &anon.AuthCnfg{SiteURL: server.URL + "/sites/bench"}
+	} else if *profile != "" {
+		authCnfg, err = auth.NewAuthFromProfile(*profile)
+	} else {
+		authCnfg, err = auth.NewAuthFromFile(*config)
+	}
+	if err != nil {
+		log.Fatalf("can't load auth config: %s", err)
+	}
+
+	b := &bench{
+		auth: authCnfg,
+		options: &scenarioOptions{
+			list:   *list,
+			folder: serverRelative(authCnfg.GetSiteURL(), *folder),
+			size:   int(*size * 1024 * 1024),
+			query:  *query,
+		},
+	}
+	for _, name := range strings.Split(*scenarios, ",") {
+		if err := b.addScenario(strings.TrimSpace(name)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	report := b.run(stages, func(stage stage) {
+		if !*asJSON {
+			fmt.Fprintf(os.Stderr, "running %d worker(s) for %s\n", stage.workers, stage.duration)
+		}
+	})
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(report)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/koltyakov/gosip/api"
+)
+
+// scenarioOptions - scenarios settings
+type scenarioOptions struct {
+	list   string // list title for read and write scenarios
+	folder string // site-relative folder URL for uploads
+	size   int    // upload size, bytes
+	query  string // search query text
+}
+
+// scenario runs one operation, worker and iteration numbers help to keep names unique
+type scenario func(sp *api.SP, opts *scenarioOptions, worker int, iteration int) error
+
+// scenarios available to the runner
+var scenarios = map[string]scenario{
+	"read": func(sp *api.SP, opts *scenarioOptions, _ int, _ int) error {
+		_, err := sp.Web().Lists().GetByTitle(opts.list).Items().Select("Id,Title").Top(10).Get()
+		return err
+	},
+	"write": func(sp *api.SP, opts *scenarioOptions, worker int, iteration int) error {
+		body := []byte(fmt.Sprintf(`{"Title":"Bench %d-%d"}`, worker, iteration))
+		_, err := sp.Web().Lists().GetByTitle(opts.list).Items().Add(body)
+		return err
+	},
+	"upload": func(sp *api.SP, opts *scenarioOptions, worker int, _ int) error {
+		// Files are overwritten to keep a number of files equal to workers number
+		name := fmt.Sprintf("bench-%d.bin", worker)
+		_, err := sp.Web().GetFolder(opts.folder).Files().Add(name, bytes.Repeat([]byte("0"), opts.size), true)
+		return err
+	},
+	"search": func(sp *api.SP, opts *scenarioOptions, _ int, _ int) error {
+		_, err := sp.Search().PostQuery(&api.SearchQuery{QueryText: opts.query, RowLimit: 10})
+		return err
+	},
+	"taxonomy": func(sp *api.SP, _ *scenarioOptions, _ int, _ int) error {
+		_, err := sp.Taxonomy().Stores().Default().Select("Id,Name").Get()
+		return err
+	},
+}
+
+// scenarioNames gets sorted scenario names
+func scenarioNames() []string {
+	var names []string
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
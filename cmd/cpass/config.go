@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/koltyakov/gosip/auth"
+	"github.com/koltyakov/gosip/secret"
+)
+
+// configFile - private.json content with preserved properties order
+type configFile struct {
+	path   string
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+// readConfigFile reads private config file
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := parseObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.path = path
+	return c, nil
+}
+
+// parseObject parses JSON object keeping properties order
+func parseObject(data []byte) (*configFile, error) {
+	c := &configFile{values: map[string]json.RawMessage{}}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("can't parse: %w", err)
+		}
+		key := t.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("can't parse: %w", err)
+		}
+		if _, ok := c.values[key]; !ok {
+			c.keys = append(c.keys, key)
+		}
+		c.values[key] = value
+	}
+
+	return c, nil
+}
+
+// write writes config back to the file keeping properties order and file mode
+func (c *configFile) write() error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(c.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return os.WriteFile(c.path, c.marshal(), mode)
+}
+
+// marshal formats config as indented JSON
+func (c *configFile) marshal() []byte {
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	for i, key := range c.keys {
+		k, _ := json.Marshal(key)
+		var v bytes.Buffer
+		if err := json.Indent(&v, c.values[key], "  ", "  "); err != nil {
+			v.Write(c.values[key])
+		}
+		b.WriteString("  " + string(k) + ": " + v.String())
+		if i < len(c.keys)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// compact formats nested object as compact JSON, it's indented with the whole config
+func (c *configFile) compact() json.RawMessage {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, key := range c.keys {
+		k, _ := json.Marshal(key)
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(c.values[key])
+	}
+	b.WriteString("}")
+	return b.Bytes()
+}
+
+// getString gets string property value, nested properties are addressed with a dot separated path
+func (c *configFile) getString(key string) (string, bool) {
+	if parent, child, nested := strings.Cut(key, "."); nested {
+		raw, ok := c.values[parent]
+		if !ok {
+			return "", false
+		}
+		obj, err := parseObject(raw)
+		if err != nil {
+			return "", false
+		}
+		return obj.getString(child)
+	}
+	raw, ok := c.values[key]
+	if !ok {
+		return "", false
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// setString sets string property value, missing parent objects are created
+func (c *configFile) setString(key string, value string) {
+	if parent, child, nested := strings.Cut(key, "."); nested {
+		obj := &configFile{values: map[string]json.RawMessage{}}
+		if raw, ok := c.values[parent]; ok {
+			if o, err := parseObject(raw); err == nil {
+				obj = o
+			}
+		}
+		obj.setString(child, value)
+		c.set(parent, obj.compact())
+		return
+	}
+	raw, _ := json.Marshal(value)
+	c.set(key, raw)
+}
+
+// set sets raw property value keeping properties order
+func (c *configFile) set(key string, raw json.RawMessage) {
+	if _, ok := c.values[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.values[key] = raw
+}
+
+// strategy gets config strategy, the flag value takes precedence over the property
+func (c *configFile) strategy(override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	if strategy, ok := c.getString("strategy"); ok && strategy != "" {
+		return strategy, nil
+	}
+	return "", fmt.Errorf("%s has no \"strategy\" property, provide -strategy flag", c.path)
+}
+
+// secretFields gets names of the config secret properties which are present in the file
+// and are not secret references
+func (c *configFile) secretFields(strategy string) ([]string, error) {
+	names, err := auth.SecretFields(strategy)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	for _, name := range names {
+		value, ok := c.getString(name)
+		if !ok || value == "" || secret.IsRef(value) {
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// confirm asks user for confirmation
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	var answer string
+	_, _ = fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
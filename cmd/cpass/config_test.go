@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "private.json")
+	data := `{
+  "strategy": "obo",
+  "clientSecret": "secret",
+  "certPass": "env:CERT_PASS",
+  "transport": {
+    "proxyUrl": "http://proxy.contoso.com:8080",
+    "proxyPassword": "password"
+  }
+}
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cnfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("SecretFields", func(t *testing.T) {
+		fields, err := cnfg.secretFields("obo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(fields, ",") != "clientSecret,transport.proxyPassword" {
+			t.Errorf("unexpected secret fields: %v", fields)
+		}
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		if value, ok := cnfg.getString("transport.proxyPassword"); !ok || value != "password" {
+			t.Errorf("unexpected nested value: %s", value)
+		}
+		if _, ok := cnfg.getString("transport.missing"); ok {
+			t.Error("missing nested property should not be found")
+		}
+		cnfg.setString("transport.proxyPassword", "encrypted")
+		if err := cnfg.write(); err != nil {
+			t.Fatal(err)
+		}
+		written, err := readConfigFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value, _ := written.getString("transport.proxyPassword"); value != "encrypted" {
+			t.Errorf("nested value is not written: %s", value)
+		}
+		if value, _ := written.getString("transport.proxyUrl"); value != "http://proxy.contoso.com:8080" {
+			t.Errorf("sibling nested value is lost: %s", value)
+		}
+		if strings.Join(written.keys, ",") != "strategy,clientSecret,certPass,transport" {
+			t.Errorf("properties order is not kept: %v", written.keys)
+		}
+		expected := strings.Replace(data, `"password"`, `"encrypted"`, 1)
+		if string(written.marshal()) != expected {
+			t.Errorf("unexpected formatting:\n%s", written.marshal())
+		}
+	})
+}
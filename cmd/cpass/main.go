@@ -1,13 +1,58 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"reflect"
+	"strings"
 
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth"
 	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
 )
 
+const usage = `Usage:
+  cpass -secret "MyP@sSword" [-master key] [-mode encode|decode]
+
+  cpass encrypt -config private.json   Encrypts secret fields of the config in place
+  cpass decrypt -config private.json   Prints the config with decrypted secret fields
+  cpass rekey   -config private.json   Re-encrypts secret fields with a new key or in v2 format
+  cpass verify  -config private.json   Checks that secret fields can be decrypted on this machine
+
+Run "cpass <command> -h" for the command flags.
+`
+
 func main() {
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		legacy()
+		return
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "encrypt":
+		err = encrypt(os.Args[2:])
+	case "decrypt":
+		err = decrypt(os.Args[2:])
+	case "rekey":
+		err = rekey(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// legacy encodes or decodes a single secret string
+func legacy() {
 
 	var rawSecret string
 	var masterKey string
@@ -16,9 +61,14 @@ func main() {
 	flag.StringVar(&rawSecret, "secret", "", "Raw secret string")
 	flag.StringVar(&masterKey, "master", "", "Master key string")
 	flag.StringVar(&mode, "mode", "encode", "Mode: encode/decode")
+	flag.Usage = func() { fmt.Print(usage) }
 	flag.Parse()
 
-	crypt := cpass.Cpass(masterKey)
+	crypt, err := cpass.New(masterKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
 
 	if mode == "encode" {
 		secret, _ := crypt.Encode(rawSecret)
@@ -31,3 +81,314 @@ func main() {
 	}
 
 }
+
+// keyFlags - crypter key flags
+type keyFlags struct {
+	master  string
+	keyFile string
+	kdf     string
+}
+
+// register registers key flags with an optional name prefix
+func (k *keyFlags) register(fs *flag.FlagSet, prefix string, kdf bool) {
+	desc := ""
+	if prefix != "" {
+		desc = "Current "
+	}
+	fs.StringVar(&k.master, prefix+"master", "", desc+"Master key string, machine ID is used when empty")
+	fs.StringVar(&k.keyFile, prefix+"keyfile", "", desc+"Key file path, used instead of the machine ID")
+	if kdf {
+		fs.StringVar(&k.kdf, "kdf", cpass.KDFScrypt, "Key derivation function: scrypt or argon2id")
+	}
+}
+
+// crypter creates crypter from the flags
+func (k *keyFlags) crypter() (*cpass.Crypter, error) {
+	if k.master != "" && k.keyFile != "" {
+		return nil, fmt.Errorf("either master key or key file should be provided")
+	}
+	var crypt *cpass.Crypter
+	var err error
+	if k.keyFile != "" {
+		crypt, err = cpass.FromKeyFile(k.keyFile)
+	} else {
+		crypt, err = cpass.New(k.master)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k.kdf != "" {
+		if err := crypt.SetKDF(k.kdf); err != nil {
+			return nil, err
+		}
+	}
+	return crypt, nil
+}
+
+// parseFlags parses command flags and reads the config
+func parseFlags(fs *flag.FlagSet, args []string, configPath *string, strategy *string) (*configFile, string, error) {
+	fs.StringVar(configPath, "config", "./config/private.json", "Private config file path")
+	fs.StringVar(strategy, "strategy", "", "Auth strategy, when the config has no \"strategy\" property")
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	cnfg, err := readConfigFile(*configPath)
+	if err != nil {
+		return nil, "", err
+	}
+	name, err := cnfg.strategy(*strategy)
+	if err != nil {
+		return nil, "", err
+	}
+	return cnfg, name, nil
+}
+
+// encrypt encrypts plain secret fields in place
+func encrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	var configPath, strategy string
+	var key keyFlags
+	key.register(fs, "", true)
+	cnfg, name, err := parseFlags(fs, args, &configPath, &strategy)
+	if err != nil {
+		return err
+	}
+	crypt, err := key.crypter()
+	if err != nil {
+		return err
+	}
+
+	fields, err := cnfg.secretFields(name)
+	if err != nil {
+		return err
+	}
+
+	encrypted := 0
+	for _, field := range fields {
+		value, _ := cnfg.getString(field)
+		if crypt.Verify(value) == nil {
+			fmt.Printf("%s: already encrypted\n", field)
+			continue
+		}
+		if !plainText(crypt, value) {
+			fmt.Fprintf(os.Stderr, "%s: encrypted with another key, skipped, use \"cpass rekey\" to re-encrypt\n", field)
+			continue
+		}
+		encoded, err := crypt.Encode(value)
+		if err != nil {
+			return fmt.Errorf("can't encrypt %s: %w", field, err)
+		}
+		cnfg.setString(field, encoded)
+		encrypted++
+		fmt.Printf("%s: encrypted\n", field)
+	}
+
+	if encrypted == 0 {
+		fmt.Println("nothing to encrypt")
+		return nil
+	}
+	return cnfg.write()
+}
+
+// decrypt prints config with decrypted secret fields
+func decrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	var configPath, strategy string
+	var key keyFlags
+	var yes bool
+	key.register(fs, "", false)
+	fs.BoolVar(&yes, "yes", false, "Skip the confirmation")
+	cnfg, name, err := parseFlags(fs, args, &configPath, &strategy)
+	if err != nil {
+		return err
+	}
+	crypt, err := key.crypter()
+	if err != nil {
+		return err
+	}
+
+	if !yes && !confirm("Decrypted secrets will be printed to the output, continue?") {
+		return fmt.Errorf("cancelled")
+	}
+
+	fields, err := cnfg.secretFields(name)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		value, _ := cnfg.getString(field)
+		if err := crypt.Verify(value); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: can't decrypt: %s\n", field, err)
+			continue
+		}
+		decoded, _ := crypt.Decode(value)
+		cnfg.setString(field, decoded)
+	}
+
+	fmt.Print(string(cnfg.marshal()))
+	return nil
+}
+
+// rekey re-encrypts secret fields with a new key
+func rekey(args []string) error {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	var configPath, strategy string
+	var oldKey, newKey keyFlags
+	oldKey.register(fs, "old-", false)
+	newKey.register(fs, "", true)
+	cnfg, name, err := parseFlags(fs, args, &configPath, &strategy)
+	if err != nil {
+		return err
+	}
+	oldCrypt, err := oldKey.crypter()
+	if err != nil {
+		return err
+	}
+	newCrypt, err := newKey.crypter()
+	if err != nil {
+		return err
+	}
+
+	fields, err := cnfg.secretFields(name)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		value, _ := cnfg.getString(field)
+		if plainText(oldCrypt, value) {
+			encoded, err := newCrypt.Encode(value)
+			if err != nil {
+				return fmt.Errorf("can't encrypt %s: %w", field, err)
+			}
+			cnfg.setString(field, encoded)
+			fmt.Printf("%s: plain text, encrypted\n", field)
+			continue
+		}
+		rekeyed, err := cpass.Rekey(value, oldCrypt, newCrypt)
+		if err != nil {
+			return fmt.Errorf("can't rekey %s: %w", field, err)
+		}
+		cnfg.setString(field, rekeyed)
+		fmt.Printf("%s: re-encrypted\n", field)
+	}
+
+	if len(fields) == 0 {
+		fmt.Println("nothing to re-encrypt")
+		return nil
+	}
+	return cnfg.write()
+}
+
+// verify loads config with the strategy and reports secret fields status
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var configPath, strategy string
+	var key keyFlags
+	key.register(fs, "", false)
+	cnfg, name, err := parseFlags(fs, args, &configPath, &strategy)
+	if err != nil {
+		return err
+	}
+	crypt, err := key.crypter()
+	if err != nil {
+		return err
+	}
+
+	// Strategies bind to a key file with the environment variable
+	if key.keyFile != "" {
+		_ = os.Setenv(cpass.KeyFileEnv, key.keyFile)
+	}
+
+	var authCnfg gosip.AuthCnfg
+	if strategy == "" && key.master == "" {
+		authCnfg, err = auth.NewAuthFromFile(configPath)
+	} else {
+		if authCnfg, err = auth.NewAuthByStrategy(name); err == nil {
+			if m, ok := authCnfg.(interface{ SetMasterkey(string) }); ok {
+				m.SetMasterkey(key.master)
+			}
+			err = authCnfg.ReadConfig(configPath)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("can't load config: %w", err)
+	}
+	fmt.Printf("%s: loaded with %s strategy\n", configPath, authCnfg.GetStrategy())
+
+	names, err := auth.SecretFields(name)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, field := range names {
+		value, ok := cnfg.getString(field)
+		switch {
+		case !ok || value == "":
+			continue
+		case secret.IsRef(value):
+			fmt.Printf("%s: secret reference, resolved: %t\n", field, loadedValue(authCnfg, field) != value)
+		case crypt.Verify(value) == nil:
+			format := "v1"
+			if cpass.IsV2(value) {
+				format = "v2"
+			}
+			fmt.Printf("%s: ok, decrypts on this machine (%s)\n", field, format)
+		case cpass.IsV2(value) || loadedValue(authCnfg, field) == value && looksEncoded(value):
+			fmt.Printf("%s: FAILED, can't be decrypted on this machine\n", field)
+			failed++
+		default:
+			fmt.Printf("%s: plain text, run \"cpass encrypt\"\n", field)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d secret field(s) can't be decrypted", failed)
+	}
+	return nil
+}
+
+// loadedValue gets strategy config field value by JSON property name,
+// nested properties are addressed with a dot separated path
+func loadedValue(cnfg interface{}, name string) string {
+	v := reflect.ValueOf(cnfg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	name, child, nested := strings.Cut(name, ".")
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" || strings.Split(f.Tag.Get("json"), ",")[0] != name {
+			continue
+		}
+		if nested {
+			return loadedValue(v.Field(i).Interface(), child)
+		}
+		if v.Field(i).Kind() == reflect.String {
+			return v.Field(i).String()
+		}
+	}
+	return ""
+}
+
+// plainText checks if a value is not encrypted, neither with the key nor with another one
+func plainText(crypt *cpass.Crypter, value string) bool {
+	err := crypt.Verify(value)
+	if err == nil || cpass.IsV2(value) {
+		return false
+	}
+	// Legacy values decrypted with another key end up with the value
+	return !(errors.Is(err, cpass.ErrKeyMismatch) && looksEncoded(value))
+}
+
+// looksEncoded checks if a value looks like legacy cpass ciphertext
+func looksEncoded(value string) bool {
+	return len(value) >= 32 && strings.Trim(value, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_=") == ""
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/koltyakov/gosip/cpass"
+)
+
+func TestPlainText(t *testing.T) {
+	crypt := cpass.Cpass("MASTER_KEY")
+	encoded, err := crypt.Encode("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := cpass.Cpass("OTHER_KEY").Encode("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := base64.URLEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)) // v1 value of another key
+
+	cases := map[string]bool{
+		"secret":                true,
+		"P@ssw0rd~with.symbols": true,
+		encoded:                 false,
+		other:                   false,
+		legacy:                  false,
+	}
+	for value, expected := range cases {
+		if res := plainText(crypt, value); res != expected {
+			t.Errorf("%s: expected plain text %t, got %t", value, expected, res)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type edmxGenCnfg struct {
+	Source     string   // EDMX file path
+	Types      []string // Full type names, e.g. SP.Web
+	Suffix     string   // Struct names suffix, e.g. Info for WebInfo
+	Navigation bool     // Generate navigation getters for queryable structs declared in the package
+	Out        string   // Output file name
+}
+
+// EDMX CSDL subset, both OData v3 (associations) and v4 (typed navigation properties) are supported
+type edmx struct {
+	Schemas []*edmxSchema `xml:"DataServices>Schema"`
+}
+
+type edmxSchema struct {
+	Namespace    string             `xml:"Namespace,attr"`
+	EntityTypes  []*edmxType        `xml:"EntityType"`
+	ComplexTypes []*edmxType        `xml:"ComplexType"`
+	Associations []*edmxAssociation `xml:"Association"`
+}
+
+type edmxType struct {
+	Name       string          `xml:"Name,attr"`
+	BaseType   string          `xml:"BaseType,attr"`
+	Properties []*edmxProperty `xml:"Property"`
+	Navigation []*edmxNavProp  `xml:"NavigationProperty"`
+
+	namespace string
+	complex   bool
+}
+
+type edmxProperty struct {
+	Name string `xml:"Name,attr"`
+	Type string `xml:"Type,attr"`
+}
+
+type edmxNavProp struct {
+	Name         string `xml:"Name,attr"`
+	Type         string `xml:"Type,attr"`         // v4
+	Relationship string `xml:"Relationship,attr"` // v3
+	ToRole       string `xml:"ToRole,attr"`       // v3
+}
+
+type edmxAssociation struct {
+	Name string `xml:"Name,attr"`
+	Ends []struct {
+		Role         string `xml:"Role,attr"`
+		Type         string `xml:"Type,attr"`
+		Multiplicity string `xml:"Multiplicity,attr"`
+	} `xml:"End"`
+}
+
+// edmPrimitives maps Edm types to Go types, 64-bit integers, GUIDs and dates are serialized as strings
+var edmPrimitives = map[string]string{
+	"Edm.String":         "string",
+	"Edm.Boolean":        "bool",
+	"Edm.Byte":           "int",
+	"Edm.SByte":          "int",
+	"Edm.Int16":          "int",
+	"Edm.Int32":          "int",
+	"Edm.Int64":          "string",
+	"Edm.Single":         "float64",
+	"Edm.Double":         "float64",
+	"Edm.Decimal":        "float64",
+	"Edm.Guid":           "string",
+	"Edm.DateTime":       "string",
+	"Edm.DateTimeOffset": "string",
+	"Edm.Time":           "string",
+	"Edm.Duration":       "string",
+	"Edm.Binary":         "[]byte",
+	"Edm.Stream":         "string",
+}
+
+// initialisms are written upper case in Go names, e.g. ServerRelativeUrl -> ServerRelativeURL
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "CSS": true, "HTML": true, "HTTP": true, "XML": true,
+	"JSON": true, "API": true, "GUID": true, "UI": true, "SQL": true, "IP": true,
+}
+
+// edmxGenerator resolves EDMX types to Go structs
+type edmxGenerator struct {
+	c       *edmxGenCnfg
+	types   map[string]*edmxType        // by full name
+	assocs  map[string]*edmxAssociation // by full name
+	names   map[string]string           // Go struct names by full type name
+	queue   []string
+	visited map[string]bool
+	imports map[string]bool
+}
+
+func generateEDMX(c *edmxGenCnfg) error {
+	data, err := os.ReadFile(c.Source)
+	if err != nil {
+		return err
+	}
+	doc := &edmx{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return fmt.Errorf("can't parse EDMX: %w", err)
+	}
+
+	g := &edmxGenerator{
+		c:       c,
+		types:   map[string]*edmxType{},
+		assocs:  map[string]*edmxAssociation{},
+		names:   map[string]string{},
+		visited: map[string]bool{},
+		imports: map[string]bool{},
+	}
+	for _, schema := range doc.Schemas {
+		for _, t := range schema.EntityTypes {
+			t.namespace = schema.Namespace
+			g.types[schema.Namespace+"."+t.Name] = t
+		}
+		for _, t := range schema.ComplexTypes {
+			t.namespace = schema.Namespace
+			t.complex = true
+			g.types[schema.Namespace+"."+t.Name] = t
+		}
+		for _, a := range schema.Associations {
+			g.assocs[schema.Namespace+"."+a.Name] = a
+		}
+	}
+	if len(g.types) == 0 {
+		return fmt.Errorf("no entity or complex types found in %s", c.Source)
+	}
+
+	for _, name := range c.Types {
+		name = strings.TrimSpace(name)
+		if _, ok := g.types[name]; !ok {
+			return fmt.Errorf("type %s is not found in %s", name, c.Source)
+		}
+		g.enqueue(name)
+	}
+
+	// Complex types referenced by properties are added to the queue while generating
+	structs := ""
+	for i := 0; i < len(g.queue); i++ {
+		structs += g.structGen(g.queue[i])
+	}
+
+	getters := ""
+	if c.Navigation {
+		if getters, err = g.navigationGen(); err != nil {
+			return err
+		}
+		if getters != "" {
+			g.imports["fmt"] = true
+		}
+	}
+
+	pkgPath, _ := filepath.Abs("./")
+	code := fmt.Sprintf("// Code generated by `%s`; DO NOT EDIT.\n\n", commandLine())
+	code += fmt.Sprintf("package %s\n", filepath.Base(pkgPath))
+	if len(g.imports) > 0 {
+		var packages []string
+		for k := range g.imports {
+			packages = append(packages, fmt.Sprintf("%q", k))
+		}
+		sort.Strings(packages)
+		code += "\nimport (\n" + strings.Join(packages, "\n") + "\n)\n"
+	}
+	code += structs + getters
+
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return fmt.Errorf("can't format generated code: %w", err)
+	}
+
+	fmt.Printf("Generated %s (%d bytes)\n", filepath.Join("./", c.Out), len(formatted))
+
+	return os.WriteFile(filepath.Join("./", c.Out), formatted, 0644)
+}
+
+// enqueue adds a type to generate
+func (g *edmxGenerator) enqueue(fullName string) string {
+	if !g.visited[fullName] {
+		g.visited[fullName] = true
+		g.queue = append(g.queue, fullName)
+	}
+	return g.structName(fullName)
+}
+
+// structName gets unique Go struct name for the type, e.g. WebInfo for SP.Web
+func (g *edmxGenerator) structName(fullName string) string {
+	if name, ok := g.names[fullName]; ok {
+		return name
+	}
+	t := g.types[fullName]
+	name := goName(t.Name) + g.c.Suffix
+	for _, taken := range g.names {
+		if taken == name {
+			// Same type names in different namespaces, e.g. SP.Publishing.Navigation
+			name = goName(strings.ReplaceAll(t.namespace, ".", "_")+"_"+t.Name) + g.c.Suffix
+			break
+		}
+	}
+	g.names[fullName] = name
+	return name
+}
+
+// properties gets type properties including base types properties
+func (g *edmxGenerator) properties(t *edmxType) []*edmxProperty {
+	var props []*edmxProperty
+	if base, ok := g.types[t.BaseType]; ok && base != t {
+		props = append(props, g.properties(base)...)
+	}
+	return append(props, t.Properties...)
+}
+
+// navigation gets type navigation properties including base types ones,
+// properties redeclared by the type replace base type ones
+func (g *edmxGenerator) navigation(t *edmxType) []*edmxNavProp {
+	var nav []*edmxNavProp
+	if base, ok := g.types[t.BaseType]; ok && base != t {
+		nav = append(nav, g.navigation(base)...)
+	}
+	for _, n := range t.Navigation {
+		redeclared := false
+		for i, b := range nav {
+			if b.Name == n.Name {
+				nav[i], redeclared = n, true
+				break
+			}
+		}
+		if !redeclared {
+			nav = append(nav, n)
+		}
+	}
+	return nav
+}
+
+func (g *edmxGenerator) structGen(fullName string) string {
+	t := g.types[fullName]
+	name := g.structName(fullName)
+	kind := "entity"
+	if t.complex {
+		kind = "complex"
+	}
+	code := fmt.Sprintf("\n// %s - %s %s type properties\ntype %s struct {\n", name, fullName, kind, name)
+	used := map[string]bool{}
+	for _, p := range g.properties(t) {
+		field := goName(p.Name)
+		if used[field] {
+			continue
+		}
+		used[field] = true
+		goType, comment := g.goType(p.Type)
+		code += fmt.Sprintf("%s %s `json:\"%s\"`", field, goType, p.Name)
+		if comment != "" {
+			code += " // " + comment
+		}
+		code += "\n"
+	}
+	return code + "}\n"
+}
+
+// goType maps EDM type to Go type, the comment keeps original type for string-serialized values
+func (g *edmxGenerator) goType(edmType string) (string, string) {
+	if strings.HasPrefix(edmType, "Collection(") {
+		item, comment := g.goType(strings.TrimSuffix(strings.TrimPrefix(edmType, "Collection("), ")"))
+		return "[]" + item, comment
+	}
+	if goType, ok := edmPrimitives[edmType]; ok {
+		comment := ""
+		if goType == "string" && edmType != "Edm.String" {
+			comment = edmType
+		}
+		return goType, comment
+	}
+	if t, ok := g.types[edmType]; ok && t.complex {
+		return "*" + g.enqueue(edmType), ""
+	}
+	g.imports["encoding/json"] = true
+	return "json.RawMessage", edmType
+}
+
+// navigationGen generates navigation getters for the generated entity types
+// which have queryable structs (e.g. Web for SP.Web) and target constructors (e.g. NewLists) in the package
+func (g *edmxGenerator) navigationGen() (string, error) {
+	pkg, err := scanPackage("./", g.c.Out)
+	if err != nil {
+		return "", err
+	}
+	code := ""
+	generated := map[string]bool{} // types with the same Go name, e.g. SP.Web and SP.Publishing.Web, share getters
+	for _, fullName := range g.queue {
+		t := g.types[fullName]
+		ent := goName(t.Name)
+		if t.complex || !pkg.queryables[ent] {
+			continue
+		}
+		inst := instanceOf(ent)
+		for _, nav := range g.navigation(t) {
+			method := goName(nav.Name)
+			if pkg.methods[ent+"."+method] || generated[ent+"."+method] {
+				continue // declared by hand or already generated
+			}
+			target, collection := g.navTarget(nav)
+			if target == nil {
+				continue
+			}
+			constructor := pkg.constructor(goName(target.Name), collection)
+			if constructor == "" {
+				continue
+			}
+			generated[ent+"."+method] = true
+			kind := "queryable object"
+			if collection {
+				kind = "queryable collection"
+			}
+			code += `
+				// ` + method + ` gets ` + strings.TrimPrefix(constructor, "New") + ` API instance ` + kind + `
+				func (` + inst + ` *` + ent + `) ` + method + `() *` + strings.TrimPrefix(constructor, "New") + ` {
+					return ` + constructor + `(
+						` + inst + `.client,
+						fmt.Sprintf("%s/` + nav.Name + `", ` + inst + `.endpoint),
+						` + inst + `.config,
+					)
+				}
+			`
+		}
+	}
+	return code, nil
+}
+
+// navTarget resolves navigation property target type and multiplicity
+func (g *edmxGenerator) navTarget(nav *edmxNavProp) (*edmxType, bool) {
+	if nav.Type != "" {
+		name := nav.Type
+		collection := strings.HasPrefix(name, "Collection(")
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "Collection("), ")")
+		return g.types[name], collection
+	}
+	assoc, ok := g.assocs[nav.Relationship]
+	if !ok {
+		return nil, false
+	}
+	for _, end := range assoc.Ends {
+		if end.Role == nav.ToRole {
+			return g.types[end.Type], end.Multiplicity == "*"
+		}
+	}
+	return nil, false
+}
+
+// goPackage - declarations of the package the code is generated into
+type goPackage struct {
+	queryables   map[string]bool // structs with client, endpoint and config fields
+	methods      map[string]bool // Type.Method
+	constructors map[string]bool // NewX functions
+}
+
+// scanPackage parses package declarations skipping the generated file
+func scanPackage(dir string, skip string) (*goPackage, error) {
+	pkg := &goPackage{queryables: map[string]bool{}, methods: map[string]bool{}, constructors: map[string]bool{}}
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if filepath.Base(file) == skip || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					if strings.HasPrefix(d.Name.Name, "New") {
+						pkg.constructors[d.Name.Name] = true
+					}
+					continue
+				}
+				recv := d.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok {
+					pkg.methods[ident.Name+"."+d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok {
+						continue
+					}
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					fields := map[string]bool{}
+					for _, field := range st.Fields.List {
+						for _, name := range field.Names {
+							fields[name.Name] = true
+						}
+					}
+					if fields["client"] && fields["endpoint"] && fields["config"] {
+						pkg.queryables[ts.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// constructor finds the target constructor, collections are named in plural, e.g. NewLists, NewProperties
+func (pkg *goPackage) constructor(name string, collection bool) string {
+	candidates := []string{name}
+	if collection {
+		candidates = []string{name + "s", name + "es"}
+		if strings.HasSuffix(name, "y") {
+			candidates = append(candidates, strings.TrimSuffix(name, "y")+"ies")
+		}
+	}
+	for _, candidate := range candidates {
+		if pkg.queryables[candidate] && pkg.constructors["New"+candidate] {
+			return "New" + candidate
+		}
+	}
+	return ""
+}
+
+// goName converts EDM name to exported Go identifier, e.g. OData__UIVersionString -> ODataUIVersionString
+func goName(name string) string {
+	// Split into words on underscores and lower-to-upper case transitions
+	var words []string
+	word := []rune{}
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = []rune{}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(word[len(word)-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(word[len(word)-1]))) {
+			words = append(words, string(word))
+			word = []rune{}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+
+	res := ""
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			res += upper
+			continue
+		}
+		res += strings.ToUpper(w[:1]) + w[1:]
+	}
+	if res == "" || unicode.IsDigit([]rune(res)[0]) {
+		res = "X" + res
+	}
+	return res
+}
+
+// commandLine gets generator command for the generated file header
+func commandLine() string {
+	args := append([]string{"ggen"}, os.Args[1:]...)
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const edmxFixture = `<?xml version="1.0" encoding="utf-8"?>
+<edmx:Edmx Version="1.0" xmlns:edmx="http://schemas.microsoft.com/ado/2007/06/edmx">
+  <edmx:DataServices>
+    <Schema Namespace="SP" xmlns="http://schemas.microsoft.com/ado/2009/11/edm">
+      <EntityType Name="SecurableObject">
+        <Property Name="HasUniqueRoleAssignments" Type="Edm.Boolean" />
+        <NavigationProperty Name="RoleAssignments" Relationship="SP.SP_SecurableObject_RoleAssignments" ToRole="RoleAssignments" />
+      </EntityType>
+      <EntityType Name="Web" BaseType="SP.SecurableObject">
+        <Property Name="Id" Type="Edm.Guid" />
+        <Property Name="ServerRelativeUrl" Type="Edm.String" />
+        <NavigationProperty Name="RoleAssignments" Relationship="SP.SP_SecurableObject_RoleAssignments" ToRole="RoleAssignments" />
+        <NavigationProperty Name="Lists" Type="Collection(SP.List)" />
+        <NavigationProperty Name="Author" Relationship="SP.SP_Web_Author" ToRole="Author" />
+      </EntityType>
+      <EntityType Name="List">
+        <Property Name="Title" Type="Edm.String" />
+      </EntityType>
+      <EntityType Name="RoleAssignment">
+        <Property Name="PrincipalId" Type="Edm.Int32" />
+      </EntityType>
+      <EntityType Name="User">
+        <Property Name="LoginName" Type="Edm.String" />
+      </EntityType>
+      <Association Name="SP_SecurableObject_RoleAssignments">
+        <End Type="SP.RoleAssignment" Role="RoleAssignments" Multiplicity="*" />
+        <End Type="SP.SecurableObject" Role="RoleAssignmentsPartner" Multiplicity="0..1" />
+      </Association>
+      <Association Name="SP_Web_Author">
+        <End Type="SP.User" Role="Author" Multiplicity="0..1" />
+        <End Type="SP.Web" Role="AuthorPartner" Multiplicity="0..1" />
+      </Association>
+    </Schema>
+    <Schema Namespace="SP.Publishing" xmlns="http://schemas.microsoft.com/ado/2009/11/edm">
+      <EntityType Name="Web">
+        <NavigationProperty Name="Lists" Type="Collection(SP.List)" />
+      </EntityType>
+    </Schema>
+  </edmx:DataServices>
+</edmx:Edmx>`
+
+// packageFixture declares queryable structs and constructors navigation getters are generated for
+const packageFixture = `package api
+
+type Web struct {
+	client   interface{}
+	endpoint string
+	config   interface{}
+}
+
+type Lists struct {
+	client   interface{}
+	endpoint string
+	config   interface{}
+}
+
+type RoleAssignments struct {
+	client   interface{}
+	endpoint string
+	config   interface{}
+}
+
+type User struct {
+	client   interface{}
+	endpoint string
+	config   interface{}
+}
+
+func NewWeb(client interface{}, endpoint string, config interface{}) *Web { return nil }
+
+func NewLists(client interface{}, endpoint string, config interface{}) *Lists { return nil }
+
+func NewRoleAssignments(client interface{}, endpoint string, config interface{}) *RoleAssignments { return nil }
+
+func NewUser(client interface{}, endpoint string, config interface{}) *User { return nil }
+
+func (web *Web) Author() *User { return nil }
+`
+
+func TestGoName(t *testing.T) {
+	cases := map[string]string{
+		"ServerRelativeUrl":        "ServerRelativeURL",
+		"OData__UIVersionString":   "ODataUIVersionString",
+		"HasUniqueRoleAssignments": "HasUniqueRoleAssignments",
+		"Id":                       "ID",
+		"HTMLContent":              "HTMLContent",
+		"first_name":               "FirstName",
+		"1stItem":                  "X1stItem",
+	}
+	for name, expected := range cases {
+		if res := goName(name); res != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, res)
+		}
+	}
+}
+
+func TestEDMX(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "api") // generated package is named by the folder
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "metadata.xml")
+	if err := os.WriteFile(source, []byte(edmxFixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "api.go"), []byte(packageFixture), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	c := &edmxGenCnfg{
+		Source:     source,
+		Types:      []string{"SP.Web", "SP.Publishing.Web"},
+		Suffix:     "Info",
+		Navigation: true,
+		Out:        "web_gen.go",
+	}
+
+	t.Run("NavTarget", func(t *testing.T) {
+		g := &edmxGenerator{c: c, types: map[string]*edmxType{}, assocs: map[string]*edmxAssociation{}}
+		g.types["SP.List"] = &edmxType{Name: "List"}
+		g.types["SP.User"] = &edmxType{Name: "User"}
+		g.assocs["SP.SP_Web_Author"] = &edmxAssociation{Ends: []struct {
+			Role         string `xml:"Role,attr"`
+			Type         string `xml:"Type,attr"`
+			Multiplicity string `xml:"Multiplicity,attr"`
+		}{{Role: "Author", Type: "SP.User", Multiplicity: "0..1"}, {Role: "AuthorPartner", Type: "SP.Web", Multiplicity: "0..1"}}}
+
+		if target, collection := g.navTarget(&edmxNavProp{Type: "Collection(SP.List)"}); target == nil || target.Name != "List" || !collection {
+			t.Errorf("v4 collection target is expected, got %v, %t", target, collection)
+		}
+		if target, collection := g.navTarget(&edmxNavProp{Relationship: "SP.SP_Web_Author", ToRole: "Author"}); target == nil || target.Name != "User" || collection {
+			t.Errorf("v3 association target is expected, got %v, %t", target, collection)
+		}
+		if target, _ := g.navTarget(&edmxNavProp{Relationship: "SP.Missing", ToRole: "Author"}); target != nil {
+			t.Errorf("unknown association should not resolve, got %v", target)
+		}
+	})
+
+	t.Run("Constructor", func(t *testing.T) {
+		pkg, err := scanPackage("./", c.Out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cases := []struct {
+			name       string
+			collection bool
+			expected   string
+		}{
+			{"List", true, "NewLists"},
+			{"RoleAssignment", true, "NewRoleAssignments"},
+			{"User", false, "NewUser"},
+			{"Web", false, "NewWeb"},
+			{"List", false, ""},
+			{"Folder", true, ""},
+		}
+		for _, tc := range cases {
+			if res := pkg.constructor(tc.name, tc.collection); res != tc.expected {
+				t.Errorf("%s (collection %t): expected %q, got %q", tc.name, tc.collection, tc.expected, res)
+			}
+		}
+	})
+
+	t.Run("Generate", func(t *testing.T) {
+		if err := generateEDMX(c); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(c.Out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := string(data)
+
+		for _, expected := range []string{"type WebInfo struct", "type SPPublishingWebInfo struct", "ServerRelativeURL ", "ID "} {
+			if !strings.Contains(code, expected) {
+				t.Errorf("%q is expected in the generated code:\n%s", expected, code)
+			}
+		}
+		for method, count := range map[string]int{"RoleAssignments": 1, "Lists": 1, "Author": 0} {
+			re := regexp.MustCompile(`func \(\w+ \*Web\) ` + method + `\(\)`)
+			if n := len(re.FindAllString(code, -1)); n != count {
+				t.Errorf("%s getter is generated %d times, expected %d:\n%s", method, n, count, code)
+			}
+		}
+	})
+}
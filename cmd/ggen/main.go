@@ -24,8 +24,31 @@ func main() {
 	coll := flag.Bool("coll", false, "Is collection entity")
 	mods := flag.String("mods", "", "Modifiers comma separated list")
 	helpers := flag.String("helpers", "", "Helpers comma separated list")
+	edmxSource := flag.String("edmx", "", "EDMX ($metadata) file path, generates structs for -types")
+	types := flag.String("types", "", "EDMX entity and complex types comma separated list, e.g. SP.Web,SP.List")
+	suffix := flag.String("suffix", "Info", "EDMX struct names suffix")
+	nav := flag.Bool("nav", false, "Generate EDMX navigation getters for queryable structs declared in the package")
+	out := flag.String("out", "edmx_gen.go", "EDMX output file name")
 	flag.Parse()
 
+	if *edmxSource != "" {
+		if *types == "" {
+			fmt.Println("no EDMX types are provided, use -types, e.g. -types SP.Web,SP.List")
+			os.Exit(1)
+		}
+		if err := generateEDMX(&edmxGenCnfg{
+			Source:     *edmxSource,
+			Types:      strings.Split(*types, ","),
+			Suffix:     *suffix,
+			Navigation: *nav,
+			Out:        *out,
+		}); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *ent == "" {
 		fmt.Printf("can't generate %+v as no entity is provided, skipping...\n", os.Args)
 		return
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/koltyakov/gosip/api"
+	"github.com/koltyakov/gosip/auth"
+	"github.com/koltyakov/gosip/diag"
+	"github.com/koltyakov/gosip/proxy"
+)
+
+// headerFlags - repeatable "Name: value" header flags
+type headerFlags map[string]string
+
+func (h headerFlags) String() string { return fmt.Sprint(map[string]string(h)) }
+
+func (h headerFlags) Set(value string) error {
+	name, val, found := strings.Cut(value, ":")
+	if !found || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header should be in \"Name: value\" format")
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	return nil
+}
+
+// apiCmd sends a raw REST request
+func apiCmd(args []string) error {
+	fs := flag.NewFlagSet("api", flag.ExitOnError)
+	var a authFlags
+	var body string
+	headers := headerFlags{}
+	a.register(fs)
+	fs.StringVar(&body, "body", "", "Request body, \"@path\" reads a file, \"-\" reads stdin")
+	fs.Var(headers, "header", "Request header in \"Name: value\" format, can be repeated")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip api get|post|patch|delete <endpoint> [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+	endpoint := args[1]
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		endpoint = strings.TrimRight(client.AuthCnfg.GetSiteURL(), "/") + "/" + strings.TrimLeft(endpoint, "/")
+	}
+	payload, err := readInput(body)
+	if err != nil {
+		return err
+	}
+
+	conf := &api.RequestConfig{Headers: headers}
+	httpClient := api.NewHTTPClient(client)
+	var data []byte
+	switch strings.ToLower(args[0]) {
+	case "get":
+		data, err = httpClient.Get(endpoint, conf)
+	case "post":
+		data, err = httpClient.Post(endpoint, bytes.NewReader(payload), conf)
+	case "patch", "merge":
+		data, err = httpClient.Update(endpoint, bytes.NewReader(payload), conf)
+	case "delete":
+		data, err = httpClient.Delete(endpoint, conf)
+	default:
+		return fmt.Errorf("unsupported method: %s", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	return writeRaw(os.Stdout, data)
+}
+
+// listsCmd prints site lists
+func listsCmd(args []string) error {
+	fs := flag.NewFlagSet("lists", flag.ExitOnError)
+	var a authFlags
+	var q queryFlags
+	a.register(fs)
+	q.register(fs)
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	sp, err := a.sp()
+	if err != nil {
+		return err
+	}
+	lists := sp.Web().Lists()
+	if q.sel != "" {
+		lists.Select(q.sel)
+	}
+	if q.expand != "" {
+		lists.Expand(q.expand)
+	}
+	if q.filter != "" {
+		lists.Filter(q.filter)
+	}
+	if q.top > 0 {
+		lists.Top(q.top)
+	}
+	if field, asc := q.orderField(); field != "" {
+		lists.OrderBy(field, asc)
+	}
+
+	data, err := lists.Get()
+	if err != nil {
+		return err
+	}
+	rows, err := collectionRows(data, api.NormalizeODataCollection)
+	if err != nil {
+		return err
+	}
+	return writeRows(os.Stdout, rows, q.columns(), q.format)
+}
+
+// itemsCmd prints list items
+func itemsCmd(args []string) error {
+	fs := flag.NewFlagSet("items", flag.ExitOnError)
+	var a authFlags
+	var q queryFlags
+	var all bool
+	a.register(fs)
+	q.register(fs)
+	fs.BoolVar(&all, "all", false, "Get all pages, -top defines page size")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip items <list title or server-relative URL> [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	sp, err := a.sp()
+	if err != nil {
+		return err
+	}
+	list := sp.Web().Lists().GetByTitle(args[0])
+	if strings.HasPrefix(args[0], "/") {
+		list = sp.Web().GetList(args[0])
+	}
+	items := list.Items()
+	if q.sel != "" {
+		items.Select(q.sel)
+	}
+	if q.expand != "" {
+		items.Expand(q.expand)
+	}
+	if q.filter != "" {
+		items.Filter(q.filter)
+	}
+	if q.top > 0 {
+		items.Top(q.top)
+	}
+	if field, asc := q.orderField(); field != "" {
+		items.OrderBy(field, asc)
+	}
+
+	page, err := items.GetPaged()
+	if err != nil {
+		return err
+	}
+	var rows []map[string]interface{}
+	for {
+		pageRows, err := collectionRows(page.Items, api.NormalizeODataCollection)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, pageRows...)
+		if !all || !page.HasNextPage() {
+			break
+		}
+		if page, err = page.GetNextPage(); err != nil {
+			return err
+		}
+	}
+	return writeRows(os.Stdout, rows, q.columns(), q.format)
+}
+
+// uploadCmd uploads a local file to a folder
+func uploadCmd(args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	var a authFlags
+	var name string
+	var chunkSize int
+	var overwrite, ensure bool
+	a.register(fs)
+	fs.StringVar(&name, "name", "", "Target file name, the local file name by default")
+	fs.IntVar(&chunkSize, "chunk", 10, "Chunk size in megabytes, smaller files are uploaded in a single request")
+	fs.BoolVar(&overwrite, "overwrite", true, "Overwrite existing file")
+	fs.BoolVar(&ensure, "ensure", false, "Create the target folder when missing")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip upload <local file> <server-relative folder URL> [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	if name == "" {
+		name = filepath.Base(args[0])
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	sp, err := a.sp()
+	if err != nil {
+		return err
+	}
+	web := sp.Web()
+	if ensure {
+		if _, err := web.EnsureFolder(args[1]); err != nil {
+			return fmt.Errorf("can't ensure folder: %w", err)
+		}
+	}
+
+	file, err := web.GetFolder(args[1]).Files().AddChunked(name, f, &api.AddChunkedOptions{
+		Overwrite: overwrite,
+		ChunkSize: chunkSize * 1024 * 1024,
+		Progress: func(data *api.FileUploadProgressData) bool {
+			if data.BlockNumber > 0 {
+				fmt.Fprintf(os.Stderr, "%s: %d bytes uploaded\n", data.Stage, data.FileOffset)
+			}
+			return true
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(file.Data().ServerRelativeURL)
+	return nil
+}
+
+// downloadCmd downloads a file by its server-relative URL
+func downloadCmd(args []string) error {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	var a authFlags
+	a.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip download <server-relative file URL> [local path or - for stdout] [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, 2); err != nil {
+		return err
+	}
+	target := path.Base(args[0])
+	if len(args) == 2 {
+		target = args[1]
+	}
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		target = filepath.Join(target, path.Base(args[0]))
+	}
+
+	sp, err := a.sp()
+	if err != nil {
+		return err
+	}
+	reader, err := sp.Web().GetFile(args[0]).GetReader()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+
+	if target == "-" {
+		_, err = io.Copy(os.Stdout, reader)
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, reader); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// folderCmd manages folders
+func folderCmd(args []string) error {
+	fs := flag.NewFlagSet("folder", flag.ExitOnError)
+	var a authFlags
+	a.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip folder ensure <server-relative folder URL> [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	if args[0] != "ensure" {
+		return fmt.Errorf("unknown folder command: %s", args[0])
+	}
+
+	sp, err := a.sp()
+	if err != nil {
+		return err
+	}
+	folder, err := sp.Web().EnsureFolder(args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Println(folder.Data().ServerRelativeURL)
+	return nil
+}
+
+// recycleCmd lists or restores recycle bin items
+func recycleCmd(args []string) error {
+	fs := flag.NewFlagSet("recycle", flag.ExitOnError)
+	var a authFlags
+	var q queryFlags
+	var site bool
+	a.register(fs)
+	q.register(fs)
+	fs.BoolVar(&site, "site", false, "Use site collection recycle bin instead of the web one")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip recycle list|restore [id ...] [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, -1); err != nil {
+		return err
+	}
+
+	sp, err := a.sp()
+	if err != nil {
+		return err
+	}
+	recycleBin := sp.Web().RecycleBin()
+	if site {
+		recycleBin = sp.Site().RecycleBin()
+	}
+
+	switch args[0] {
+	case "list":
+		if q.sel != "" {
+			recycleBin.Select(q.sel)
+		}
+		if q.filter != "" {
+			recycleBin.Filter(q.filter)
+		}
+		if q.top > 0 {
+			recycleBin.Top(q.top)
+		}
+		if field, asc := q.orderField(); field != "" {
+			recycleBin.OrderBy(field, asc)
+		}
+		data, err := recycleBin.Get()
+		if err != nil {
+			return err
+		}
+		rows, err := collectionRows(data, api.NormalizeODataCollection)
+		if err != nil {
+			return err
+		}
+		return writeRows(os.Stdout, rows, q.columns(), q.format)
+	case "restore":
+		if len(args) < 2 {
+			return fmt.Errorf("no recycle bin item IDs provided")
+		}
+		for _, id := range args[1:] {
+			if err := recycleBin.GetByID(id).Restore(); err != nil {
+				return fmt.Errorf("can't restore %s: %w", id, err)
+			}
+			fmt.Printf("%s: restored\n", id)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown recycle command: %s", args[0])
+}
+
+// searchCmd runs a search query
+func searchCmd(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	var a authFlags
+	var q queryFlags
+	var sourceID string
+	var start int
+	a.register(fs)
+	q.registerOutput(fs)
+	fs.StringVar(&sourceID, "source", "", "Result source ID")
+	fs.IntVar(&start, "start", 0, "First row to return, for paging")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip search <query> [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	sp, err := a.sp()
+	if err != nil {
+		return err
+	}
+	query := &api.SearchQuery{
+		QueryText:        args[0],
+		SourceID:         sourceID,
+		StartRow:         start,
+		RowLimit:         q.top,
+		SelectProperties: q.columns(),
+		TrimDuplicates:   true,
+	}
+	res, err := sp.Search().PostQuery(query)
+	if err != nil {
+		return err
+	}
+
+	var rows []map[string]interface{}
+	for _, result := range res.Results() {
+		row := map[string]interface{}{}
+		for key, value := range result {
+			row[key] = value
+		}
+		rows = append(rows, row)
+	}
+	return writeRows(os.Stdout, rows, q.columns(), q.format)
+}
+
+// csomCmd sends CSOM request XML to ProcessQuery endpoint
+func csomCmd(args []string) error {
+	fs := flag.NewFlagSet("csom", flag.ExitOnError)
+	var a authFlags
+	a.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip csom [request.xml] [flags], the request is read from stdin when no file provided")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, 1); err != nil {
+		return err
+	}
+	input := "-"
+	if len(args) == 1 {
+		input = "@" + args[0]
+	}
+	body, err := readInput(input)
+	if err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+	data, err := api.NewHTTPClient(client).ProcessQuery(client.AuthCnfg.GetSiteURL(), bytes.NewReader(body), nil)
+	if err != nil {
+		return err
+	}
+	return writeRaw(os.Stdout, data)
+}
+
+// checkCmd validates the auth config end to end and prints the diagnosis
+func checkCmd(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	var a authFlags
+	var asJSON bool
+	a.register(fs)
+	fs.BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip check [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var report *diag.CheckReport
+	if a.profile != "" {
+		authCnfg, e := auth.NewAuthFromProfile(a.profile)
+		if e != nil {
+			return fmt.Errorf("can't load auth config: %w", e)
+		}
+		report, err = diag.Check(authCnfg)
+	} else {
+		report, err = diag.CheckFile(a.config)
+	}
+
+	if asJSON {
+		if e := writeJSON(os.Stdout, report); e != nil {
+			return e
+		}
+	} else {
+		fmt.Print(report)
+	}
+	if err != nil {
+		return fmt.Errorf("check failed at %s phase", report.Phases[len(report.Phases)-1].Name)
+	}
+	return nil
+}
+
+// proxyCmd serves a local authenticated reverse proxy to the site
+func proxyCmd(args []string) error {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	var a authFlags
+	var addr, origins string
+	a.register(fs)
+	fs.StringVar(&addr, "addr", "localhost:8080", "Address to listen on")
+	fs.StringVar(&origins, "origins", "", "Comma separated cross-origin callers allowed to use the proxy, e.g. http://localhost:3000")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gosip proxy [flags]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+	p := proxy.New(client)
+	// Requests are accepted for the listen address only, loopback hosts when listening on all interfaces
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" && !net.ParseIP(host).IsUnspecified() {
+		p.Hosts = []string{addr}
+	}
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			p.Origins = append(p.Origins, origin)
+		}
+	}
+	p.OnError = func(r *http.Request, err error) {
+		fmt.Fprintf(os.Stderr, "%s %s: %s\n", r.Method, r.URL, err)
+	}
+	fmt.Fprintf(os.Stderr, "Proxying %s at http://%s\n", client.AuthCnfg.GetSiteURL(), addr)
+	return http.ListenAndServe(addr, p)
+}
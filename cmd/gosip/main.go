@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/api"
+	"github.com/koltyakov/gosip/auth"
+)
+
+const usage = `Usage:
+  gosip <command> [arguments] [flags]
+
+Commands:
+  api get|post|patch|delete <endpoint>   Raw REST call, the endpoint is relative to the site URL or absolute
+  lists                                  Site lists
+  items <list>                           List items, the list is a title or a server-relative URL
+  upload <file> <folder>                 Uploads a local file to a folder, large files are sent in chunks
+  download <file> [path]                 Downloads a file by its server-relative URL
+  folder ensure <folder>                 Creates a folder, including missing parents
+  recycle list|restore [id ...]          Lists or restores recycle bin items
+  search <query>                         Runs a search query
+  csom [request.xml]                     Sends CSOM request XML from a file or stdin to ProcessQuery
+  proxy                                  Serves a local authenticated reverse proxy to the site
+  check                                  Validates the auth config end to end and explains failures
+
+All commands accept -config (private config path, "./config/private.json" by default)
+or -profile (named profile from the credentials file) flags.
+
+Run "gosip <command> -h" for the command flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func([]string) error{
+		"api":      apiCmd,
+		"lists":    listsCmd,
+		"items":    itemsCmd,
+		"upload":   uploadCmd,
+		"download": downloadCmd,
+		"folder":   folderCmd,
+		"recycle":  recycleCmd,
+		"search":   searchCmd,
+		"csom":     csomCmd,
+		"proxy":    proxyCmd,
+		"check":    checkCmd,
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// authFlags - auth config flags
+type authFlags struct {
+	config  string
+	profile string
+}
+
+// register registers auth config flags
+func (a *authFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&a.config, "config", "./config/private.json", "Private config file path")
+	fs.StringVar(&a.profile, "profile", "", "Named profile from the credentials file, used instead of the config")
+}
+
+// client creates SharePoint client from the auth config
+func (a *authFlags) client() (*gosip.SPClient, error) {
+	var authCnfg gosip.AuthCnfg
+	var err error
+	if a.profile != "" {
+		authCnfg, err = auth.NewAuthFromProfile(a.profile)
+	} else {
+		authCnfg, err = auth.NewAuthFromFile(a.config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't load auth config: %w", err)
+	}
+	return &gosip.SPClient{AuthCnfg: authCnfg}, nil
+}
+
+// sp creates fluent API root from the auth config
+func (a *authFlags) sp() (*api.SP, error) {
+	client, err := a.client()
+	if err != nil {
+		return nil, err
+	}
+	return api.NewSP(client), nil
+}
+
+// parseArgs parses flags interleaved with positional arguments, e.g. `items Tasks -top 10`
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// expectArgs checks positional arguments count
+func expectArgs(fs *flag.FlagSet, args []string, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	return nil
+}
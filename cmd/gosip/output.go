@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// queryFlags - OData query and output flags
+type queryFlags struct {
+	filter  string
+	sel     string
+	expand  string
+	orderBy string
+	top     int
+	format  string
+}
+
+// register registers OData query and output flags
+func (q *queryFlags) register(fs *flag.FlagSet) {
+	q.registerOutput(fs)
+	fs.StringVar(&q.filter, "filter", "", "OData $filter expression")
+	fs.StringVar(&q.expand, "expand", "", "Comma-separated fields to expand")
+	fs.StringVar(&q.orderBy, "orderby", "", "Field to order by, \"Field desc\" for descending order")
+}
+
+// registerOutput registers select, top and output format flags only
+func (q *queryFlags) registerOutput(fs *flag.FlagSet) {
+	fs.StringVar(&q.sel, "select", "", "Comma-separated fields to select, also defines CSV and table columns")
+	fs.IntVar(&q.top, "top", 0, "Maximum number of results")
+	fs.StringVar(&q.format, "format", "json", "Output format: json, csv or table")
+}
+
+// orderField gets order by field and direction
+func (q *queryFlags) orderField() (string, bool) {
+	field, dir, _ := strings.Cut(strings.TrimSpace(q.orderBy), " ")
+	return field, !strings.EqualFold(strings.TrimSpace(dir), "desc")
+}
+
+// columns gets output columns from select flag
+func (q *queryFlags) columns() []string {
+	var columns []string
+	for _, c := range strings.Split(q.sel, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// writeRows writes rows in the output format, columns are all row keys when not provided
+func writeRows(w io.Writer, rows []map[string]interface{}, columns []string, format string) error {
+	switch format {
+	case "json":
+		if rows == nil {
+			rows = []map[string]interface{}{}
+		}
+		return writeJSON(w, rows)
+	case "csv":
+		columns = rowColumns(rows, columns)
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := cw.Write(rowValues(row, columns)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		columns = rowColumns(rows, columns)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		for _, row := range rows {
+			values := rowValues(row, columns)
+			for i, v := range values {
+				values[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(v)
+			}
+			_, _ = fmt.Fprintln(tw, strings.Join(values, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format: %s", format)
+}
+
+// writeJSON writes indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeRaw writes response body, JSON is indented
+func writeRaw(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	if json.Indent(&buf, data, "", "  ") == nil {
+		data = append(buf.Bytes(), '\n')
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// rowColumns gets sorted keys of all rows skipping OData metadata properties
+func rowColumns(rows []map[string]interface{}, columns []string) []string {
+	if len(columns) > 0 {
+		return columns
+	}
+	keys := map[string]bool{}
+	for _, row := range rows {
+		for key := range row {
+			if key != "__metadata" && !strings.HasPrefix(key, "odata.") && !strings.HasSuffix(key, "@odata.navigationLinkUrl") {
+				keys[key] = true
+			}
+		}
+	}
+	for key := range keys {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// rowValues gets row values as strings, nested objects are JSON encoded
+func rowValues(row map[string]interface{}, columns []string) []string {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		var value interface{} = row
+		for _, key := range strings.Split(column, "/") {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = m[key]
+		}
+		switch v := value.(type) {
+		case nil:
+		case string:
+			values[i] = v
+		case float64:
+			values[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			values[i] = strconv.FormatBool(v)
+		default:
+			b, _ := json.Marshal(v)
+			values[i] = string(b)
+		}
+	}
+	return values
+}
+
+// collectionRows parses OData collection response to rows
+func collectionRows(data []byte, normalize func([]byte) ([]byte, string)) ([]map[string]interface{}, error) {
+	normalized, _ := normalize(data)
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(normalized, &rows); err != nil {
+		return nil, fmt.Errorf("can't parse response: %w", err)
+	}
+	return rows, nil
+}
+
+// readInput reads request body: "@path" reads a file, "-" reads stdin, other values are used as is
+func readInput(value string) ([]byte, error) {
+	switch {
+	case value == "-":
+		return io.ReadAll(os.Stdin)
+	case strings.HasPrefix(value, "@"):
+		return os.ReadFile(value[1:])
+	}
+	return []byte(value), nil
+}
@@ -8,6 +8,7 @@ import (
 	_ "net/http/pprof"
 	"time"
 
+	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/api"
 	m "github.com/koltyakov/gosip/test/manual"
 )
@@ -15,9 +16,16 @@ import (
 func main() {
 
 	strategy := flag.String("strategy", "saml", "Auth strategy code")
+	profile := flag.String("profile", "", "Credentials file profile name, takes precedence over the strategy")
 	flag.Parse()
 
-	client, err := m.GetTestClient(*strategy)
+	var client *gosip.SPClient
+	var err error
+	if *profile != "" {
+		client, err = m.GetProfileClient(*profile)
+	} else {
+		client, err = m.GetTestClient(*strategy)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/api"
 	m "github.com/koltyakov/gosip/test/manual"
 )
@@ -12,9 +13,16 @@ import (
 func main() {
 
 	strategy := flag.String("strategy", "fba", "Auth strategy code")
+	profile := flag.String("profile", "", "Credentials file profile name, takes precedence over the strategy")
 	flag.Parse()
 
-	client, err := m.GetTestClient(*strategy)
+	var client *gosip.SPClient
+	var err error
+	if *profile != "" {
+		client, err = m.GetProfileClient(*profile)
+	} else {
+		client, err = m.GetTestClient(*strategy)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
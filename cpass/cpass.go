@@ -1,13 +1,47 @@
 package cpass
 
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyFileEnv is environment variable with a key file path which is used instead of the machine ID
+const KeyFileEnv = "CPASS_KEY_FILE"
+
 // Crypter - cpass module structure
 type Crypter struct {
-	encryptionKey []byte
+	encryptionKey []byte // v1 format key
+	material      []byte // v2 format key material
+	kdf           string // v2 format key derivation function
+	err           error  // key loading error, returned by all operations
 }
 
 // Cpass constructor function.
+// Secrets are bound to the master key, when it's empty, to a key file from CPASS_KEY_FILE
+// environment variable or to the machine ID. When CPASS_KEY_FILE is set but the key file
+// can't be used, the crypter fails all operations with the error, secrets are never silently
+// bound to the machine ID instead of the configured key. Use New to get the error upfront.
 func Cpass(masterKey string) *Crypter {
+	c, err := New(masterKey)
+	if err != nil {
+		return &Crypter{err: err}
+	}
+	return c
+}
+
+// New creates Crypter the same way as Cpass, but returns an error when CPASS_KEY_FILE is set
+// and the key file can't be used.
+func New(masterKey string) (*Crypter, error) {
 	if masterKey == "" {
+		if keyFile := os.Getenv(KeyFileEnv); keyFile != "" {
+			c, err := FromKeyFile(keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("cpass: %s is set but the key file can't be used: %w", KeyFileEnv, err)
+			}
+			return c, nil
+		}
 		key, err := getMachineID(false)
 		if err != nil {
 			masterKey = "CPASS_EMPTY_KEY" // TODO: Fallback logic
@@ -15,15 +49,103 @@ func Cpass(masterKey string) *Crypter {
 			masterKey = key
 		}
 	}
-	return &Crypter{hashCipherKey(masterKey)}
+	return &Crypter{
+		encryptionKey: hashCipherKey(masterKey),
+		material:      []byte(masterKey),
+	}, nil
+}
+
+// FromKeyFile creates Crypter bound to a key file content instead of the machine ID,
+// e.g. a key mounted to a container which machine ID changes.
+func FromKeyFile(keyFile string) (*Crypter, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) < 16 {
+		return nil, fmt.Errorf("cpass: key file %s must contain at least 16 bytes", keyFile)
+	}
+	return &Crypter{
+		encryptionKey: hashCipherKey(string(key)),
+		material:      key,
+	}, nil
+}
+
+// SetKDF sets key derivation function used to encode values: KDFScrypt (default) or KDFArgon2id.
+func (c *Crypter) SetKDF(kdf string) error {
+	if _, err := defaultKDFParams(kdf); err != nil {
+		return err
+	}
+	c.kdf = kdf
+	return nil
 }
 
 // Encode encodes a string value to a locally decodable hash.
 func (c *Crypter) Encode(data string) (string, error) {
-	return encrypt(data, c.encryptionKey)
+	if c.err != nil {
+		return data, c.err
+	}
+	k, err := defaultKDFParams(c.kdf)
+	if err != nil {
+		return data, err
+	}
+	return encryptV2(data, c.material, k)
 }
 
 // Decode decodes a locally decodable hash to the original string.
+// Both v2 and legacy v1 formats are supported. As with v1, decoding with an incorrect key
+// ends up with the value, use Verify to check the key.
 func (c *Crypter) Decode(data string) (string, error) {
+	if c.err != nil {
+		return data, c.err
+	}
+	if IsV2(data) {
+		decoded, err := decryptV2(data, c.material)
+		if err == ErrKeyMismatch {
+			return data, nil
+		}
+		return decoded, err
+	}
 	return decrypt(data, c.encryptionKey)
 }
+
+// Verify checks that a value is encoded and can be decoded with the key.
+func (c *Crypter) Verify(data string) error {
+	_, err := c.decodeStrict(data)
+	return err
+}
+
+// IsV2 checks if a value is encoded in the v2 format.
+func IsV2(data string) bool {
+	return strings.HasPrefix(data, v2Prefix)
+}
+
+// Rekey re-encodes a value encoded with the old key using the new key,
+// legacy v1 values are upgraded to the v2 format.
+func Rekey(data string, oldKey *Crypter, newKey *Crypter) (string, error) {
+	decoded, err := oldKey.decodeStrict(data)
+	if err != nil {
+		return data, err
+	}
+	return newKey.Encode(decoded)
+}
+
+// decodeStrict decodes a value failing when it's not encoded or encoded with another key
+func (c *Crypter) decodeStrict(data string) (string, error) {
+	if c.err != nil {
+		return data, c.err
+	}
+	if IsV2(data) {
+		return decryptV2(data, c.material)
+	}
+	decoded, err := decrypt(data, c.encryptionKey)
+	if err != nil {
+		return data, fmt.Errorf("cpass: value is not encoded: %w", err)
+	}
+	// v1 decrypt with incorrect key ends up with the value by design
+	if decoded == data {
+		return data, ErrKeyMismatch
+	}
+	return decoded, nil
+}
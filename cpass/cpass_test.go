@@ -1,6 +1,7 @@
 package cpass
 
 import (
+	"os"
 	"testing"
 )
 
@@ -77,3 +78,113 @@ func TestMasterKeyIsNotEmpty(t *testing.T) {
 		t.Error("got master key helper error")
 	}
 }
+
+func TestLegacyFormatDecoding(t *testing.T) {
+	const secret = "secret"
+	c := Cpass("CUSTOM_KEY")
+
+	legacy, err := encrypt(secret, hashCipherKey("CUSTOM_KEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := c.Decode(legacy)
+	if err != nil {
+		t.Error(err)
+	}
+	if decoded != secret {
+		t.Error("legacy value is not decoded")
+	}
+
+	encoded, _ := c.Encode(secret)
+	if !IsV2(encoded) || IsV2(legacy) {
+		t.Error("values must be encoded in v2 format")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	const secret = "secret"
+	oldKey := Cpass("OLD_KEY")
+	newKey := Cpass("NEW_KEY")
+	_ = newKey.SetKDF(KDFArgon2id)
+
+	legacy, _ := encrypt(secret, hashCipherKey("OLD_KEY"))
+	current, _ := oldKey.Encode(secret)
+
+	for _, value := range []string{legacy, current} {
+		rekeyed, err := Rekey(value, oldKey, newKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := newKey.Verify(rekeyed); err != nil {
+			t.Error(err)
+		}
+		if err := oldKey.Verify(rekeyed); err != ErrKeyMismatch {
+			t.Errorf("old key should not decode rekeyed value: %v", err)
+		}
+		if decoded, _ := newKey.Decode(rekeyed); decoded != secret {
+			t.Error("rekeyed value is not decoded")
+		}
+	}
+
+	if _, err := Rekey(current, newKey, oldKey); err == nil {
+		t.Error("rekey with incorrect old key should not pass")
+	}
+	if _, err := Rekey(legacy, newKey, oldKey); err == nil {
+		t.Error("legacy rekey with incorrect old key should not pass")
+	}
+	if _, err := Rekey("plain", oldKey, newKey); err == nil {
+		t.Error("rekey of a plain value should not pass")
+	}
+	if err := newKey.SetKDF("md5"); err == nil {
+		t.Error("unsupported kdf should not pass")
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	const secret = "secret"
+	keyFile := t.TempDir() + "/cpass.key"
+	_ = os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef\n"), 0600)
+
+	c, err := FromKeyFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := c.Encode(secret)
+
+	t.Setenv(KeyFileEnv, keyFile)
+	if decoded, _ := Cpass("").Decode(encoded); decoded != secret {
+		t.Error("key file from environment is not used")
+	}
+	if err := Cpass("OTHER_KEY").Verify(encoded); err == nil {
+		t.Error("master key should take precedence over the key file")
+	}
+
+	_ = os.WriteFile(keyFile, []byte("short"), 0600)
+	if _, err := FromKeyFile(keyFile); err == nil {
+		t.Error("short key should not pass")
+	}
+	if _, err := FromKeyFile(keyFile + ".missing"); err == nil {
+		t.Error("missing key file should not pass")
+	}
+
+	for _, path := range []string{keyFile, keyFile + ".missing"} {
+		t.Setenv(KeyFileEnv, path)
+		if _, err := New(""); err == nil {
+			t.Errorf("unusable key file %s should not fall back to the machine ID", path)
+		}
+		c := Cpass("")
+		if _, err := c.Encode("secret"); err == nil {
+			t.Errorf("unusable key file %s should fail encoding", path)
+		}
+		if _, err := c.Decode(encoded); err == nil {
+			t.Errorf("unusable key file %s should fail decoding", path)
+		}
+		if err := c.Verify(encoded); err == nil {
+			t.Errorf("unusable key file %s should fail verification", path)
+		}
+	}
+	if err := Cpass("MASTER_KEY").Verify(encoded); err == nil {
+		t.Error("master key should not depend on the key file")
+	}
+}
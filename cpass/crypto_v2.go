@@ -0,0 +1,205 @@
+package cpass
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// v2 format: $cpass$v2$<algorithm>$<kdf>$<kdf params>$<salt>$<nonce + ciphertext>
+// Salt and data parts are base64 URL encoded without padding.
+const v2Prefix = "$cpass$v2$"
+
+// Encryption algorithm of the v2 format
+const algAES256GCM = "aes-256-gcm"
+
+// Key derivation functions
+const (
+	KDFScrypt   = "scrypt"   // scrypt, N=32768, r=8, p=1
+	KDFArgon2id = "argon2id" // Argon2id, t=1, m=64MiB, p=4
+)
+
+// ErrKeyMismatch is returned when a v2 value can't be decrypted with the key
+var ErrKeyMismatch = errors.New("cpass: value is encrypted with another key")
+
+var (
+	derivedKeys   = map[string][]byte{}
+	derivedKeysMu sync.Mutex
+)
+
+// kdfParams - key derivation function with parameters
+type kdfParams struct {
+	name   string
+	params map[string]int
+}
+
+// defaultKDFParams gets default parameters for a key derivation function
+func defaultKDFParams(kdf string) (kdfParams, error) {
+	switch kdf {
+	case KDFScrypt, "":
+		return kdfParams{name: KDFScrypt, params: map[string]int{"N": 32768, "r": 8, "p": 1}}, nil
+	case KDFArgon2id:
+		return kdfParams{name: KDFArgon2id, params: map[string]int{"t": 1, "m": 64 * 1024, "p": 4}}, nil
+	default:
+		return kdfParams{}, fmt.Errorf("cpass: unsupported key derivation function: %s", kdf)
+	}
+}
+
+// String formats parameters for the header
+func (k kdfParams) String() string {
+	var keys []string
+	switch k.name {
+	case KDFScrypt:
+		keys = []string{"N", "r", "p"}
+	case KDFArgon2id:
+		keys = []string{"t", "m", "p"}
+	}
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + strconv.Itoa(k.params[key])
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseKDFParams parses header key derivation function and parameters
+func parseKDFParams(name string, params string) (kdfParams, error) {
+	k, err := defaultKDFParams(name)
+	if err != nil {
+		return k, err
+	}
+	for _, param := range strings.Split(params, ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return k, fmt.Errorf("cpass: invalid %s parameter: %s", name, param)
+		}
+		if _, ok := k.params[kv[0]]; !ok {
+			return k, fmt.Errorf("cpass: unknown %s parameter: %s", name, kv[0])
+		}
+		v, err := strconv.Atoi(kv[1])
+		if err != nil || v <= 0 {
+			return k, fmt.Errorf("cpass: invalid %s parameter: %s", name, param)
+		}
+		k.params[kv[0]] = v
+	}
+	return k, nil
+}
+
+// deriveKey derives a 32 bytes key from key material, derived keys are cached in memory
+func deriveKey(material []byte, salt []byte, k kdfParams) ([]byte, error) {
+	hash := sha256.Sum256(material)
+	cacheKey := k.name + "$" + k.String() + "$" + hex.EncodeToString(salt) + "$" + hex.EncodeToString(hash[:])
+
+	derivedKeysMu.Lock()
+	defer derivedKeysMu.Unlock()
+
+	if key, ok := derivedKeys[cacheKey]; ok {
+		return key, nil
+	}
+
+	var key []byte
+	var err error
+	switch k.name {
+	case KDFScrypt:
+		key, err = scrypt.Key(material, salt, k.params["N"], k.params["r"], k.params["p"], 32)
+	case KDFArgon2id:
+		if k.params["p"] > 255 {
+			return nil, fmt.Errorf("cpass: invalid argon2id parallelism: %d", k.params["p"])
+		}
+		key = argon2.IDKey(material, salt, uint32(k.params["t"]), uint32(k.params["m"]), uint8(k.params["p"]), 32)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(derivedKeys) >= 64 {
+		derivedKeys = map[string][]byte{}
+	}
+	derivedKeys[cacheKey] = key
+	return key, nil
+}
+
+// encryptV2 encrypts a value to the v2 format
+func encryptV2(decoded string, material []byte, k kdfParams) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return decoded, err
+	}
+	key, err := deriveKey(material, salt, k)
+	if err != nil {
+		return decoded, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return decoded, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return decoded, err
+	}
+	header := v2Prefix + algAES256GCM + "$" + k.name + "$" + k.String() + "$" + base64.RawURLEncoding.EncodeToString(salt)
+	// Header is authenticated, so the parameters can't be tampered with
+	data := gcm.Seal(nonce, nonce, []byte(decoded), []byte(header))
+	return header + "$" + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decryptV2 decrypts a value of the v2 format
+func decryptV2(encoded string, material []byte) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(encoded, v2Prefix), "$")
+	if len(parts) != 5 {
+		return encoded, errors.New("cpass: malformed v2 value")
+	}
+	alg, kdf, params, saltPart, dataPart := parts[0], parts[1], parts[2], parts[3], parts[4]
+	if alg != algAES256GCM {
+		return encoded, fmt.Errorf("cpass: unsupported algorithm: %s", alg)
+	}
+	k, err := parseKDFParams(kdf, params)
+	if err != nil {
+		return encoded, err
+	}
+	salt, err := base64.RawURLEncoding.DecodeString(saltPart)
+	if err != nil {
+		return encoded, fmt.Errorf("cpass: malformed salt: %w", err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(dataPart)
+	if err != nil {
+		return encoded, fmt.Errorf("cpass: malformed data: %w", err)
+	}
+	key, err := deriveKey(material, salt, k)
+	if err != nil {
+		return encoded, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return encoded, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return encoded, errors.New("cpass: ciphertext is too short")
+	}
+	header := strings.TrimSuffix(encoded, "$"+dataPart)
+	decoded, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(header))
+	if err != nil {
+		return encoded, ErrKeyMismatch
+	}
+	return string(decoded), nil
+}
+
+// newGCM creates AES-GCM cipher
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
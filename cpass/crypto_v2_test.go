@@ -0,0 +1,60 @@
+package cpass
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptAndDecryptV2(t *testing.T) {
+	material := []byte("MY_MASTER_KEY")
+
+	for _, kdf := range []string{KDFScrypt, KDFArgon2id} {
+		t.Run(kdf, func(t *testing.T) {
+			k, err := defaultKDFParams(kdf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encrypted, err := encryptV2("secret", material, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(encrypted, v2Prefix+algAES256GCM+"$"+kdf+"$") {
+				t.Errorf("unexpected header: %s", encrypted)
+			}
+
+			decrypted, err := decryptV2(encrypted, material)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decrypted != "secret" {
+				t.Errorf("unexpected decrypted value: %s", decrypted)
+			}
+
+			if _, err := decryptV2(encrypted, []byte("INCORRECT_MASTER_KEY")); err != ErrKeyMismatch {
+				t.Errorf("incorrect key should not pass: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecryptV2Tampered(t *testing.T) {
+	k, _ := defaultKDFParams(KDFScrypt)
+	encrypted, err := encryptV2("secret", []byte("MY_MASTER_KEY"), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"params":    strings.Replace(encrypted, "r=8", "r=4", 1),
+		"algorithm": strings.Replace(encrypted, algAES256GCM, "aes-128-gcm", 1),
+		"kdf":       strings.Replace(encrypted, KDFScrypt, "bcrypt", 1),
+		"parts":     encrypted[:strings.LastIndex(encrypted, "$")],
+		"data":      encrypted[:strings.LastIndex(encrypted, "$")+1] + "AAAA",
+	}
+	for name, value := range cases {
+		if _, err := decryptV2(value, []byte("MY_MASTER_KEY")); err == nil {
+			t.Errorf("tampered %s should not pass", name)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package diag
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth"
+	"github.com/koltyakov/gosip/diag/phase"
+)
+
+// Check phases
+const (
+	PhaseConfig  = "config"  // Config schema validation
+	PhaseDNS     = "dns"     // Host name resolution
+	PhaseTLS     = "tls"     // TLS handshake and certificate validation
+	PhaseRealm   = "realm"   // User realm discovery (SharePoint Online)
+	PhaseAuth    = "auth"    // Token or cookie acquisition
+	PhaseCache   = "cache"   // Cached credential read
+	PhaseDigest  = "digest"  // Form digest fetch
+	PhaseRequest = "request" // First REST call
+)
+
+// checkTimeout limits network checks which don't go through the strategy
+const checkTimeout = 15 * time.Second
+
+// Phase - check phase result
+type Phase struct {
+	Name     string        `json:"name"`
+	Target   string        `json:"target,omitempty"` // Host or endpoint the phase is checked against
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Info     string        `json:"info,omitempty"`
+}
+
+// CheckReport - end to end auth check results
+type CheckReport struct {
+	Strategy   string   `json:"strategy"`
+	SiteURL    string   `json:"siteUrl"`
+	Phases     []Phase  `json:"phases"`
+	Credential *Report  `json:"credential,omitempty"` // Received credential inspection
+	Diagnosis  []string `json:"diagnosis,omitempty"`  // Actionable findings
+}
+
+// strategyIdPs - identity provider hosts used by strategies besides the config URLs
+var strategyIdPs = map[string][]string{
+	"saml":       {"login.microsoftonline.com"},
+	"azurecert":  {"login.microsoftonline.com"},
+	"azurecreds": {"login.microsoftonline.com"},
+	"azureenv":   {"login.microsoftonline.com"},
+	"device":     {"login.microsoftonline.com"},
+	"pkce":       {"login.microsoftonline.com"},
+	"obo":        {"login.microsoftonline.com"},
+	"federated":  {"login.microsoftonline.com"},
+	"addin":      {"accounts.accesscontrol.windows.net"},
+}
+
+// errorHints - known error fragments and their explanation
+var errorHints = []struct {
+	fragments []string
+	hint      string
+}{
+	{[]string{"MSIS7007", "ID3082", "MSIS3127"}, "ADFS relying party mismatch: relyingParty should match the realm of SharePoint trusted identity token issuer, e.g. urn:sharepoint:www"},
+	{[]string{"ID3242", "MSIS7068"}, "ADFS rejected the credentials: check username (UPN or domain\\user) and password"},
+	{[]string{"AADSTS50126"}, "Azure AD rejected the credentials: invalid username or password"},
+	{[]string{"AADSTS50034", "AADSTS90002"}, "wrong realm: the user or tenant doesn't exist in Azure AD, check username domain and tenant ID"},
+	{[]string{"AADSTS700016"}, "the app is not found in the tenant, check client ID and tenant ID"},
+	{[]string{"AADSTS7000215", "AADSTS7000222"}, "client secret is invalid or expired, issue a new secret"},
+	{[]string{"AADSTS700027", "AADSTS700024"}, "client assertion is rejected, check the certificate is uploaded to the app and the local clock is accurate"},
+	{[]string{"AADSTS50076", "AADSTS50079", "AADSTS50158"}, "multi-factor authentication is required, user credentials flows can't pass it, use azurecert, device or pkce strategies"},
+	{[]string{"AADSTS50053"}, "the account is locked after too many sign in attempts"},
+	{[]string{"x509: certificate signed by unknown authority"}, "server certificate is issued by an untrusted CA, add the CA to transport.rootCAs"},
+	{[]string{"x509: certificate is valid for"}, "server certificate doesn't match the host name, check the site URL"},
+	{[]string{"x509: certificate has expired"}, "server certificate is expired or local clock is wrong"},
+	{[]string{"no such host"}, "host name can't be resolved, check the URL and DNS settings"},
+	{[]string{"connection refused", "i/o timeout", "context deadline exceeded"}, "host is not reachable, check the network, firewall or proxy settings"},
+	{[]string{"Proxy Authentication Required"}, "proxy requires authentication, set transport.proxyUsername and transport.proxyPassword"},
+	{[]string{"msisAuthCookie is empty"}, "WAP forms authentication failed, check the credentials"},
+	{[]string{"unable to define namespace type"}, "wrong realm: Microsoft Online can't resolve the user's domain, check the username"},
+}
+
+// CheckFile validates private config against its strategy schema and checks the auth end to end
+func CheckFile(privateFile string) (*CheckReport, error) {
+	report := &CheckReport{}
+
+	var name string
+	var authCnfg gosip.AuthCnfg
+	err := report.run(PhaseConfig, privateFile, func() (string, error) {
+		data, err := os.ReadFile(privateFile)
+		if err != nil {
+			return "", err
+		}
+		raw := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return "", fmt.Errorf("config is not a valid JSON object: %w", err)
+		}
+		_ = json.Unmarshal(raw["strategy"], &name)
+		if name == "" {
+			return "", fmt.Errorf("config has no \"strategy\" property")
+		}
+		if authCnfg, err = auth.NewAuthByStrategy(name); err != nil {
+			return "", err
+		}
+		if err := authCnfg.ReadConfig(privateFile); err != nil {
+			return "", fmt.Errorf("can't read %s config: %w", name, err)
+		}
+		report.Diagnosis = append(report.Diagnosis, validateSchema(authCnfg, raw)...)
+		if missing := missingRequired(authCnfg); len(missing) > 0 {
+			return "", fmt.Errorf("required properties are missing: %s", strings.Join(missing, ", "))
+		}
+		return name + " strategy", nil
+	})
+	if err != nil {
+		report.Strategy = name
+		return report, err
+	}
+
+	return report, report.check(authCnfg)
+}
+
+// Check checks strategy auth end to end: network, realm, credentials, digest and first REST call
+func Check(authCnfg gosip.AuthCnfg) (*CheckReport, error) {
+	report := &CheckReport{}
+	return report, report.check(authCnfg)
+}
+
+// OK returns true when all phases passed
+func (r *CheckReport) OK() bool {
+	for _, p := range r.Phases {
+		if p.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the report in a human-readable form
+func (r *CheckReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Strategy:   %s\n", r.Strategy)
+	fmt.Fprintf(&b, "Site URL:   %s\n", r.SiteURL)
+	for _, p := range r.Phases {
+		status := "ok"
+		if p.Error != "" {
+			status = "FAILED"
+		}
+		fmt.Fprintf(&b, "[%-6s] %-8s %-40s %8s", status, p.Name, p.Target, p.Duration.Round(time.Millisecond))
+		if p.Info != "" {
+			fmt.Fprintf(&b, "  %s", p.Info)
+		}
+		b.WriteString("\n")
+		if p.Error != "" {
+			fmt.Fprintf(&b, "         %s\n", p.Error)
+		}
+	}
+	for _, d := range r.Diagnosis {
+		fmt.Fprintf(&b, "Diagnosis:  %s\n", d)
+	}
+	return b.String()
+}
+
+// check runs network and auth phases
+func (r *CheckReport) check(authCnfg gosip.AuthCnfg) error {
+	r.Strategy = authCnfg.GetStrategy()
+	r.SiteURL = authCnfg.GetSiteURL()
+
+	var transport *gosip.TransportConfig
+	if c, ok := authCnfg.(gosip.TransportConfigurer); ok {
+		transport = c.GetTransportConfig()
+	}
+
+	// Direct DNS and TLS checks are not relevant when requests go through a proxy
+	if !r.behindProxy(transport) {
+		for _, host := range r.hosts(authCnfg) {
+			if err := r.checkHost(host, transport); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.Strategy == "saml" {
+		if err := r.checkRealm(authCnfg, transport); err != nil {
+			return err
+		}
+	}
+
+	client := &gosip.SPClient{AuthCnfg: authCnfg}
+
+	if err := r.run(PhaseAuth, r.Strategy, func() (string, error) {
+		return "", phase.Auth(authCnfg)
+	}); err != nil {
+		return err
+	}
+
+	_ = r.run(PhaseCache, r.Strategy, func() (string, error) {
+		duration, err := phase.Cache(authCnfg)
+		if err == nil && duration > 100*time.Millisecond {
+			r.Diagnosis = append(r.Diagnosis, "credential is not cached, each request authenticates again")
+		}
+		return "", err
+	})
+
+	if credential, err := Inspect(authCnfg); err == nil {
+		r.Credential = credential
+		r.Diagnosis = append(r.Diagnosis, credential.Issues...)
+		if r.Strategy == "adfs" && credential.Kind == KindCookie && !hasCookie(credential.Cookies, "FedAuth") {
+			r.Diagnosis = append(r.Diagnosis, "ADFS issued a token but SharePoint didn't set FedAuth cookie, check relyingParty matches the trusted identity token issuer realm")
+		}
+	}
+
+	if err := r.run(PhaseDigest, r.SiteURL, func() (string, error) {
+		return "", phase.Digest(context.Background(), client)
+	}); err != nil {
+		return err
+	}
+
+	return r.run(PhaseRequest, r.SiteURL, func() (string, error) {
+		return r.checkRequest(client)
+	})
+}
+
+// run times a phase, errors are explained with the known hints
+func (r *CheckReport) run(name, target string, fn func() (string, error)) error {
+	start := time.Now()
+	info, err := fn()
+	p := Phase{Name: name, Target: target, Duration: time.Since(start), Info: info}
+	if err != nil {
+		p.Error = err.Error()
+		r.Diagnosis = append(r.Diagnosis, explainError(p.Error)...)
+	}
+	r.Phases = append(r.Phases, p)
+	return err
+}
+
+// hosts gets site and identity provider hosts
+func (r *CheckReport) hosts(authCnfg gosip.AuthCnfg) []*url.URL {
+	var hosts []*url.URL
+	seen := map[string]bool{}
+	add := func(u *url.URL) {
+		if u != nil && u.Host != "" && !seen[u.Host] {
+			seen[u.Host] = true
+			hosts = append(hosts, u)
+		}
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(authCnfg))
+	if v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() || f.Type.Kind() != reflect.String {
+				continue
+			}
+			value := v.Field(i).String()
+			if u, err := url.Parse(value); err == nil && (u.Scheme == "https" || u.Scheme == "http") {
+				add(u)
+			}
+		}
+	}
+	if u, err := url.Parse(r.SiteURL); err == nil {
+		add(u)
+	}
+	for _, host := range strategyIdPs[r.Strategy] {
+		add(&url.URL{Scheme: "https", Host: host})
+	}
+	return hosts
+}
+
+// behindProxy checks if requests go through a proxy
+func (r *CheckReport) behindProxy(transport *gosip.TransportConfig) bool {
+	if transport != nil && transport.ProxyURL != "" {
+		return true
+	}
+	req, err := http.NewRequest("GET", r.SiteURL, nil)
+	if err != nil {
+		return false
+	}
+	proxy, err := http.ProxyFromEnvironment(req)
+	return err == nil && proxy != nil
+}
+
+// checkHost resolves the host and checks TLS handshake for https
+func (r *CheckReport) checkHost(u *url.URL, transport *gosip.TransportConfig) error {
+	hostname := u.Hostname()
+	if err := r.run(PhaseDNS, hostname, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupHost(ctx, hostname)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(addrs, ", "), nil
+	}); err != nil || u.Scheme != "https" {
+		return err
+	}
+
+	return r.run(PhaseTLS, u.Host, func() (string, error) {
+		tlsConfig := &tls.Config{}
+		if tr, err := transport.NewTransport(); err == nil && tr.TLSClientConfig != nil {
+			tlsConfig = tr.TLSClientConfig.Clone()
+		}
+		tlsConfig.ServerName = hostname
+		address := u.Host
+		if u.Port() == "" {
+			address = net.JoinHostPort(hostname, "443")
+		}
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: checkTimeout}, "tcp", address, tlsConfig)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = conn.Close() }()
+		state := conn.ConnectionState()
+		if len(state.PeerCertificates) == 0 {
+			return tls.VersionName(state.Version), nil
+		}
+		cert := state.PeerCertificates[0]
+		if time.Until(cert.NotAfter) < 14*24*time.Hour {
+			r.Diagnosis = append(r.Diagnosis, fmt.Sprintf("%s certificate expires at %s", hostname, cert.NotAfter.Format(time.RFC3339)))
+		}
+		return fmt.Sprintf("%s, expires %s", tls.VersionName(state.Version), cert.NotAfter.Format("2006-01-02")), nil
+	})
+}
+
+// checkRealm resolves SharePoint Online user realm
+func (r *CheckReport) checkRealm(authCnfg gosip.AuthCnfg, transport *gosip.TransportConfig) error {
+	username := stringField(authCnfg, "Username")
+	endpoint := "https://login.microsoftonline.com/GetUserRealm.srf"
+	return r.run(PhaseRealm, endpoint, func() (string, error) {
+		client, err := transport.NewClient()
+		if err != nil {
+			return "", err
+		}
+		client.Timeout = checkTimeout
+		resp, err := client.PostForm(endpoint, url.Values{"login": {username}})
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		realm := &struct {
+			NameSpaceType string `json:"NameSpaceType"`
+			AuthURL       string `json:"AuthURL"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(realm); err != nil {
+			return "", fmt.Errorf("can't parse user realm response: %w", err)
+		}
+		switch realm.NameSpaceType {
+		case "Managed":
+			return "managed", nil
+		case "Federated":
+			return "federated, " + realm.AuthURL, nil
+		}
+		return "", fmt.Errorf("wrong realm: %s domain is unknown to Microsoft Online (%s)", username, realm.NameSpaceType)
+	})
+}
+
+// checkRequest sends the first REST call checking the current user and server clock
+func (r *CheckReport) checkRequest(client *gosip.SPClient) (string, error) {
+	login, resp, err := phase.Request(client)
+	if resp != nil {
+		if date, e := http.ParseTime(resp.Header.Get("Date")); e == nil {
+			if skew := time.Since(date); skew > clockSkew || skew < -clockSkew {
+				r.Diagnosis = append(r.Diagnosis, fmt.Sprintf("clock skew: local clock differs from the server by %s, tokens and signed requests may be rejected", skew.Round(time.Second)))
+			}
+		}
+	}
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized && r.Strategy == "ntlm" {
+			auth := strings.Join(resp.Header.Values("WWW-Authenticate"), ", ")
+			if !strings.Contains(auth, "NTLM") && !strings.Contains(auth, "Negotiate") {
+				r.Diagnosis = append(r.Diagnosis, fmt.Sprintf("server doesn't offer NTLM or Negotiate (%s), Windows authentication is disabled for the web application", auth))
+			} else {
+				r.Diagnosis = append(r.Diagnosis, "NTLM handshake is rejected, check domain, username and password")
+			}
+		}
+		return "", err
+	}
+
+	if r.Strategy == "ntlm" && (login == "" || strings.Contains(strings.ToLower(login), "anonymous")) {
+		r.Diagnosis = append(r.Diagnosis, "NTLM fell back to anonymous access, the site allows anonymous users while the credentials are not accepted")
+	}
+	if login == "" {
+		return "current user is unknown", nil
+	}
+	return login, nil
+}
+
+// validateSchema reports config properties unknown to the strategy
+func validateSchema(authCnfg gosip.AuthCnfg, raw map[string]json.RawMessage) []string {
+	known := map[string]bool{"strategy": true}
+	v := reflect.Indirect(reflect.ValueOf(authCnfg))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			known[strings.ToLower(name)] = true
+		}
+	}
+	var issues []string
+	for key := range raw {
+		if !known[strings.ToLower(key)] {
+			issues = append(issues, fmt.Sprintf("unknown property %q is ignored by %s strategy, check for a typo", key, authCnfg.GetStrategy()))
+		}
+	}
+	sort.Strings(issues)
+	return issues
+}
+
+// missingRequired gets empty properties tagged as required
+func missingRequired(authCnfg gosip.AuthCnfg) []string {
+	var missing []string
+	v := reflect.Indirect(reflect.ValueOf(authCnfg))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.Tag.Get("required") == "true" && v.Field(i).IsZero() {
+			missing = append(missing, strings.Split(f.Tag.Get("json"), ",")[0])
+		}
+	}
+	return missing
+}
+
+// explainError gets hints for known error fragments
+func explainError(err string) []string {
+	var hints []string
+	for _, h := range errorHints {
+		for _, fragment := range h.fragments {
+			if strings.Contains(err, fragment) {
+				hints = append(hints, h.hint)
+				break
+			}
+		}
+	}
+	return hints
+}
+
+// stringField gets a string field value by name
+func stringField(v interface{}, name string) string {
+	f := reflect.Indirect(reflect.ValueOf(v)).FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// hasCookie checks if a cookie is present
+func hasCookie(cookies []CookieInfo, name string) bool {
+	for _, c := range cookies {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package diag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/gosip/auth/token"
+)
+
+func hasDiagnosis(r *CheckReport, substr string) bool {
+	for _, d := range r.Diagnosis {
+		if strings.Contains(d, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheck(t *testing.T) {
+	serverDate := time.Now()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", serverDate.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(strings.ToLower(r.URL.Path), "/_api/contextinfo"):
+			_, _ = w.Write([]byte(`{"d":{"GetContextWebInformation":{"FormDigestValue":"digest","FormDigestTimeoutSeconds":1800}}}`))
+		case strings.HasSuffix(r.URL.Path, "/_api/web/currentuser"):
+			if r.Header.Get("Authorization") != "Bearer valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"LoginName":"i:0#.f|membership|user@contoso.com"}`))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("Passed", func(t *testing.T) {
+		r, err := Check(&token.AuthCnfg{SiteURL: server.URL + "/sites/ok", Token: "valid"})
+		if err != nil {
+			t.Fatalf("%s\n%s", err, r)
+		}
+		if !r.OK() || r.Strategy != "token" {
+			t.Errorf("unexpected report:\n%s", r)
+		}
+		phases := []string{}
+		for _, p := range r.Phases {
+			phases = append(phases, p.Name)
+		}
+		if strings.Join(phases, ",") != "dns,auth,cache,digest,request" {
+			t.Errorf("unexpected phases: %s", phases)
+		}
+		if last := r.Phases[len(r.Phases)-1]; last.Info != "i:0#.f|membership|user@contoso.com" {
+			t.Errorf("unexpected current user: %s", last.Info)
+		}
+		if r.Credential == nil {
+			t.Error("credential should be inspected")
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		r, err := Check(&token.AuthCnfg{SiteURL: server.URL + "/sites/denied", Token: "invalid"})
+		if err == nil || r.OK() {
+			t.Fatalf("check should fail:\n%s", r)
+		}
+		if last := r.Phases[len(r.Phases)-1]; last.Name != PhaseRequest || last.Error == "" {
+			t.Errorf("unexpected failed phase: %+v", last)
+		}
+	})
+
+	t.Run("ClockSkew", func(t *testing.T) {
+		serverDate = time.Now().Add(-time.Hour)
+		defer func() { serverDate = time.Now() }()
+		r, _ := Check(&token.AuthCnfg{SiteURL: server.URL + "/sites/skew", Token: "valid"})
+		if !hasDiagnosis(r, "clock skew") {
+			t.Errorf("clock skew is not detected:\n%s", r)
+		}
+	})
+}
+
+func TestCheckFile(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Schema", func(t *testing.T) {
+		configPath := filepath.Join(dir, "private.token.json")
+		_ = os.WriteFile(configPath, []byte(`{"strategy":"token","siteUrl":"https://contoso.sharepoint.com","tokn":"abc"}`), 0600)
+		r, err := CheckFile(configPath)
+		if err == nil || !strings.Contains(err.Error(), "token") {
+			t.Fatalf("missing required property should fail: %v", err)
+		}
+		if len(r.Phases) != 1 || r.Phases[0].Name != PhaseConfig {
+			t.Errorf("unexpected phases: %+v", r.Phases)
+		}
+		if !hasDiagnosis(r, `"tokn"`) {
+			t.Errorf("unknown property is not reported: %s", r.Diagnosis)
+		}
+	})
+
+	t.Run("Strategy", func(t *testing.T) {
+		configPath := filepath.Join(dir, "private.unknown.json")
+		_ = os.WriteFile(configPath, []byte(`{"strategy":"unknown"}`), 0600)
+		if _, err := CheckFile(configPath); err == nil {
+			t.Error("unknown strategy should fail")
+		}
+	})
+}
+
+func TestExplainError(t *testing.T) {
+	cases := map[string]string{
+		"MSIS7007: The requested relying party trust is unspecified": "relying party",
+		"AADSTS50034: The user account does not exist":               "wrong realm",
+		"x509: certificate signed by unknown authority":              "rootCAs",
+	}
+	for err, hint := range cases {
+		if hints := explainError(err); len(hints) == 0 || !strings.Contains(hints[0], hint) {
+			t.Errorf("%s: unexpected hints %s", err, hints)
+		}
+	}
+}
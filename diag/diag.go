@@ -0,0 +1,175 @@
+/*
+Package diag provides authentication diagnostics helpers
+
+Inspect receives credentials of any strategy and decodes them without validation,
+so the reasons of 401/403 responses can be explained: wrong audience, missing permissions,
+ACS vs AAD tokens, clock skew, expired cookies, etc.
+*/
+package diag
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/gosip"
+)
+
+// Credential kinds
+const (
+	KindJWT    = "jwt"    // OAuth bearer token
+	KindCookie = "cookie" // Authentication cookies
+	KindNone   = "none"   // No reusable credential, e.g. NTLM handshake or anonymous
+	KindOther  = "other"  // Unrecognized credential format
+)
+
+// Report - credentials inspection results
+type Report struct {
+	Strategy  string       `json:"strategy"`            // Auth strategy name
+	SiteURL   string       `json:"siteUrl"`             // Context site URL
+	Kind      string       `json:"kind"`                // Credential kind: jwt, cookie, none, other
+	ExpiresAt time.Time    `json:"expiresAt,omitempty"` // Expiration reported by the strategy
+	Token     *TokenInfo   `json:"token,omitempty"`     // Decoded access token, for jwt kind
+	Cookies   []CookieInfo `json:"cookies,omitempty"`   // Cookie names and expiries, for cookie kind
+	Issues    []string     `json:"issues,omitempty"`    // Detected misconfigurations and hints
+}
+
+// CookieInfo - authentication cookie information
+type CookieInfo struct {
+	Name    string    `json:"name"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// Inspect authenticates with a provided strategy and decodes received credentials
+func Inspect(auth gosip.AuthCnfg) (*Report, error) {
+	report := &Report{
+		Strategy: auth.GetStrategy(),
+		SiteURL:  auth.GetSiteURL(),
+	}
+
+	credential, exp, err := auth.GetAuth()
+	if err != nil {
+		return report, err
+	}
+	if exp > 0 {
+		report.ExpiresAt = time.Unix(exp, 0)
+	}
+
+	credential = strings.TrimSpace(strings.TrimPrefix(credential, "Bearer "))
+
+	switch {
+	case credential == "":
+		report.Kind = KindNone
+		if report.Strategy == "ntlm" {
+			report.Issues = append(report.Issues, "NTLM negotiates per connection, no reusable credential is exposed")
+		}
+	case strings.Count(credential, ".") == 2 && !strings.Contains(credential, "="):
+		info, err := DecodeToken(credential)
+		if err != nil {
+			return report, err
+		}
+		report.Kind = KindJWT
+		report.Token = info
+		report.Issues = append(report.Issues, explainToken(info, report.SiteURL, time.Now())...)
+	case strings.Contains(credential, "="):
+		report.Kind = KindCookie
+		report.Cookies = parseCookies(credential)
+		report.Issues = append(report.Issues, explainCookies(report.Cookies, time.Now())...)
+	default:
+		report.Kind = KindOther
+	}
+
+	return report, nil
+}
+
+// String formats the report in a human-readable form
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Strategy:   %s\n", r.Strategy)
+	fmt.Fprintf(&b, "Site URL:   %s\n", r.SiteURL)
+	fmt.Fprintf(&b, "Credential: %s\n", r.Kind)
+	if !r.ExpiresAt.IsZero() {
+		fmt.Fprintf(&b, "Expires:    %s (in %s)\n", r.ExpiresAt.Format(time.RFC3339), time.Until(r.ExpiresAt).Round(time.Second))
+	}
+	if t := r.Token; t != nil {
+		fmt.Fprintf(&b, "Source:     %s\n", t.Source)
+		fmt.Fprintf(&b, "Identity:   %s\n", t.IdentityType)
+		fmt.Fprintf(&b, "Audience:   %s\n", t.Audience)
+		fmt.Fprintf(&b, "Issuer:     %s\n", t.Issuer)
+		fmt.Fprintf(&b, "Tenant ID:  %s\n", t.TenantID)
+		fmt.Fprintf(&b, "App ID:     %s\n", t.AppID)
+		if t.User != "" {
+			fmt.Fprintf(&b, "User:       %s\n", t.User)
+		}
+		if len(t.Roles) > 0 {
+			fmt.Fprintf(&b, "Roles:      %s\n", strings.Join(t.Roles, ", "))
+		}
+		if len(t.Scopes) > 0 {
+			fmt.Fprintf(&b, "Scopes:     %s\n", strings.Join(t.Scopes, ", "))
+		}
+		fmt.Fprintf(&b, "Valid:      %s - %s\n", t.NotBefore.Format(time.RFC3339), t.ExpiresAt.Format(time.RFC3339))
+	}
+	for _, c := range r.Cookies {
+		if c.Expires.IsZero() {
+			fmt.Fprintf(&b, "Cookie:     %s (session)\n", c.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "Cookie:     %s (expires %s)\n", c.Name, c.Expires.Format(time.RFC3339))
+	}
+	for _, issue := range r.Issues {
+		fmt.Fprintf(&b, "Issue:      %s\n", issue)
+	}
+	return b.String()
+}
+
+// parseCookies parses cookie header or serialized set-cookie values
+func parseCookies(header string) []CookieInfo {
+	var cookies []CookieInfo
+	for _, part := range strings.Split(header, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		name := strings.TrimSpace(kv[0])
+		if name == "" {
+			continue
+		}
+		switch strings.ToLower(name) {
+		case "path", "domain", "max-age", "httponly", "secure", "samesite":
+			continue
+		case "expires":
+			if len(cookies) > 0 && len(kv) == 2 {
+				if t, err := time.Parse(time.RFC1123, kv[1]); err == nil {
+					cookies[len(cookies)-1].Expires = t
+				}
+			}
+			continue
+		}
+		if len(kv) != 2 {
+			continue
+		}
+		cookies = append(cookies, CookieInfo{Name: name})
+	}
+	return cookies
+}
+
+// explainCookies detects expired cookies
+func explainCookies(cookies []CookieInfo, now time.Time) []string {
+	var issues []string
+	for _, c := range cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			issues = append(issues, fmt.Sprintf("cookie %s is expired at %s, clean the auth cache and authenticate again", c.Name, c.Expires.Format(time.RFC3339)))
+		}
+	}
+	if len(cookies) == 0 {
+		issues = append(issues, "no cookies were received, check the credentials")
+	}
+	return issues
+}
+
+// siteHost gets host of a site URL
+func siteHost(siteURL string) string {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
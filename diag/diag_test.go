@@ -0,0 +1,163 @@
+package diag
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/gosip/auth/anon"
+	"github.com/koltyakov/gosip/auth/token"
+)
+
+func fakeJWT(claims map[string]interface{}) string {
+	payload, _ := json.Marshal(claims)
+	return "eyJ0eXAiOiJKV1QiLCJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func hasIssue(r *Report, substr string) bool {
+	for _, issue := range r.Issues {
+		if strings.Contains(issue, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInspect(t *testing.T) {
+	now := time.Now()
+
+	t.Run("AppOnly", func(t *testing.T) {
+		auth := &token.AuthCnfg{
+			SiteURL: "https://contoso.sharepoint.com/sites/test",
+			Token: fakeJWT(map[string]interface{}{
+				"aud":      "https://contoso.sharepoint.com",
+				"iss":      "https://sts.windows.net/tenant/",
+				"tid":      "tenant",
+				"appid":    "client",
+				"appidacr": "2",
+				"idtyp":    "app",
+				"roles":    []string{"Sites.FullControl.All"},
+				"iat":      now.Unix(),
+				"nbf":      now.Unix(),
+				"exp":      now.Add(time.Hour).Unix(),
+			}),
+		}
+		r, err := Inspect(auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Kind != KindJWT || r.Token == nil {
+			t.Fatalf("unexpected credential kind: %s", r.Kind)
+		}
+		if r.Token.TenantID != "tenant" || r.Token.AppID != "client" || r.Token.IdentityType != IdentityApp || r.Token.Source != SourceAAD {
+			t.Errorf("unexpected token info: %+v", r.Token)
+		}
+		if r.Token.AppAuth != "certificate" {
+			t.Errorf("unexpected app auth: %s", r.Token.AppAuth)
+		}
+		if len(r.Issues) != 0 {
+			t.Errorf("unexpected issues: %v", r.Issues)
+		}
+		if !strings.Contains(r.String(), "Sites.FullControl.All") {
+			t.Errorf("roles are missing in the report:\n%s", r)
+		}
+	})
+
+	t.Run("Misconfigured", func(t *testing.T) {
+		auth := &token.AuthCnfg{
+			SiteURL: "https://contoso.sharepoint.com/sites/test",
+			Token: fakeJWT(map[string]interface{}{
+				"aud":      "https://graph.microsoft.com",
+				"tid":      "tenant",
+				"appid":    "client",
+				"appidacr": "1",
+				"iat":      now.Add(time.Hour).Unix(),
+				"nbf":      now.Add(time.Hour).Unix(),
+				"exp":      now.Add(2 * time.Hour).Unix(),
+			}),
+		}
+		r, err := Inspect(auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, issue := range []string{"audience graph.microsoft.com", "clock", "Sites.FullControl.All", "client secret"} {
+			if !hasIssue(r, issue) {
+				t.Errorf("issue \"%s\" is not detected: %v", issue, r.Issues)
+			}
+		}
+	})
+
+	t.Run("ACS", func(t *testing.T) {
+		auth := &token.AuthCnfg{
+			SiteURL: "https://contoso.sharepoint.com/sites/test",
+			Token: fakeJWT(map[string]interface{}{
+				"aud":    "00000003-0000-0ff1-ce00-000000000000/contoso.sharepoint.com@realm",
+				"iss":    "00000001-0000-0000-c000-000000000000@realm",
+				"nameid": "client@realm",
+				"exp":    now.Add(time.Hour).Unix(),
+			}),
+		}
+		r, err := Inspect(auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Token.Source != SourceACS || r.Token.TenantID != "realm" || r.Token.AppID != "client" {
+			t.Errorf("unexpected token info: %+v", r.Token)
+		}
+		if hasIssue(r, "audience") || !hasIssue(r, "appinv.aspx") {
+			t.Errorf("unexpected issues: %v", r.Issues)
+		}
+	})
+
+	t.Run("Delegated", func(t *testing.T) {
+		auth := &token.AuthCnfg{
+			SiteURL: "https://contoso.sharepoint.com",
+			Token: fakeJWT(map[string]interface{}{
+				"aud": "https://contoso.sharepoint.com",
+				"upn": "user@contoso.onmicrosoft.com",
+				"scp": "User.Read",
+				"exp": now.Add(time.Hour).Unix(),
+			}),
+		}
+		r, err := Inspect(auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Token.IdentityType != IdentityUser || r.Token.User != "user@contoso.onmicrosoft.com" {
+			t.Errorf("unexpected token info: %+v", r.Token)
+		}
+		if !hasIssue(r, "AllSites") {
+			t.Errorf("missing scopes are not detected: %v", r.Issues)
+		}
+	})
+
+	t.Run("Cookies", func(t *testing.T) {
+		auth := &token.AuthCnfg{
+			SiteURL: "https://contoso.sharepoint.com",
+			Mode:    token.ModeCookie,
+			Token:   "FedAuth=abc==; Path=/; Expires=Mon, 02 Jan 2006 15:04:05 GMT; HttpOnly; rtFa=def",
+		}
+		r, err := Inspect(auth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Kind != KindCookie || len(r.Cookies) != 2 || r.Cookies[0].Name != "FedAuth" || r.Cookies[1].Name != "rtFa" {
+			t.Fatalf("unexpected cookies: %+v", r.Cookies)
+		}
+		if r.Cookies[0].Expires.Year() != 2006 || !hasIssue(r, "FedAuth is expired") {
+			t.Errorf("cookie expiration is not detected: %+v, %v", r.Cookies, r.Issues)
+		}
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		r, err := Inspect(&anon.AuthCnfg{SiteURL: "https://contoso.sharepoint.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Kind != KindNone {
+			t.Errorf("unexpected credential kind: %s", r.Kind)
+		}
+	})
+}
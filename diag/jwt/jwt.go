@@ -0,0 +1,176 @@
+// Package jwt decodes access token claims without signature validation,
+// it's shared by diag inspection and journal principal resolution
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Token sources
+const (
+	SourceAAD = "aad" // Azure AD (Entra ID) issued token
+	SourceACS = "acs" // SharePoint Add-In (Azure ACS) issued token
+)
+
+// Identity types
+const (
+	IdentityApp  = "app"  // App-only token
+	IdentityUser = "user" // Delegated token on behalf of a user
+)
+
+// acsPrincipal is SharePoint Online principal ID used in ACS tokens audience and issuer
+const acsPrincipal = "00000003-0000-0ff1-ce00-000000000000"
+
+// acsIssuer is Azure ACS issuer principal ID
+const acsIssuer = "00000001-0000-0000-c000-000000000000"
+
+// Token - decoded access token claims
+type Token struct {
+	Audience     string    `json:"audience"`           // aud claim
+	Issuer       string    `json:"issuer"`             // iss claim
+	TenantID     string    `json:"tenantId"`           // tid claim or ACS realm
+	AppID        string    `json:"appId"`              // appid or azp claim
+	AppAuth      string    `json:"appAuth,omitempty"`  // Client authentication method: secret, certificate, public
+	User         string    `json:"user,omitempty"`     // upn, unique_name or email, for delegated tokens
+	Roles        []string  `json:"roles,omitempty"`    // Application permissions
+	Scopes       []string  `json:"scopes,omitempty"`   // Delegated permissions
+	IdentityType string    `json:"identityType"`       // app or user
+	Source       string    `json:"source"`             // aad or acs
+	IssuedAt     time.Time `json:"issuedAt,omitempty"` // iat claim
+	NotBefore    time.Time `json:"notBefore,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt,omitempty"`
+}
+
+// claims - raw JWT claims subset
+type claims struct {
+	Aud        interface{} `json:"aud"`
+	Iss        string      `json:"iss"`
+	Tid        string      `json:"tid"`
+	AppID      string      `json:"appid"`
+	Azp        string      `json:"azp"`
+	AppIDAcr   string      `json:"appidacr"`
+	Azpacr     string      `json:"azpacr"`
+	Upn        string      `json:"upn"`
+	UniqueName string      `json:"unique_name"`
+	Email      string      `json:"email"`
+	Nameid     string      `json:"nameid"`
+	Roles      []string    `json:"roles"`
+	Scp        string      `json:"scp"`
+	IdTyp      string      `json:"idtyp"`
+	Iat        int64       `json:"iat"`
+	Nbf        int64       `json:"nbf"`
+	Exp        int64       `json:"exp"`
+}
+
+// Decode decodes JWT access token claims without signature validation
+func Decode(token string) (*Token, error) {
+	parts := strings.Split(strings.TrimPrefix(token, "Bearer "), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("not a JWT token, expected 3 parts but got %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("can't decode token payload: %w", err)
+	}
+
+	c := &claims{}
+	if err := json.Unmarshal(payload, c); err != nil {
+		return nil, fmt.Errorf("can't parse token claims: %w", err)
+	}
+
+	info := &Token{
+		Issuer:   c.Iss,
+		TenantID: c.Tid,
+		AppID:    c.AppID,
+		Roles:    c.Roles,
+		Source:   SourceAAD,
+	}
+
+	switch aud := c.Aud.(type) {
+	case string:
+		info.Audience = aud
+	case []interface{}:
+		if len(aud) > 0 {
+			info.Audience = fmt.Sprintf("%v", aud[0])
+		}
+	}
+
+	if info.AppID == "" {
+		info.AppID = c.Azp
+	}
+	switch firstOf(c.AppIDAcr, c.Azpacr) {
+	case "0":
+		info.AppAuth = "public"
+	case "1":
+		info.AppAuth = "secret"
+	case "2":
+		info.AppAuth = "certificate"
+	}
+
+	if c.Scp != "" {
+		info.Scopes = strings.Fields(c.Scp)
+	}
+
+	// ACS issuer and audience has "principal@realm" form
+	if strings.HasPrefix(c.Iss, acsIssuer+"@") {
+		info.Source = SourceACS
+		if info.TenantID == "" {
+			info.TenantID = strings.TrimPrefix(c.Iss, acsIssuer+"@")
+		}
+		if info.AppID == "" {
+			// nameid in ACS app-only tokens is "clientId@realm"
+			info.AppID = strings.Split(c.Nameid, "@")[0]
+		}
+	}
+
+	info.User = firstOf(c.Upn, c.UniqueName, c.Email)
+	info.IdentityType = IdentityApp
+	if c.IdTyp == "user" || (c.IdTyp == "" && (len(info.Scopes) > 0 || info.User != "")) {
+		info.IdentityType = IdentityUser
+	}
+
+	if c.Iat > 0 {
+		info.IssuedAt = time.Unix(c.Iat, 0)
+	}
+	if c.Nbf > 0 {
+		info.NotBefore = time.Unix(c.Nbf, 0)
+	}
+	if c.Exp > 0 {
+		info.ExpiresAt = time.Unix(c.Exp, 0)
+	}
+
+	return info, nil
+}
+
+// AudienceHost gets host name the token is issued for
+func (t *Token) AudienceHost() string {
+	aud := t.Audience
+	// ACS audience: 00000003-0000-0ff1-ce00-000000000000/contoso.sharepoint.com@realm
+	if strings.HasPrefix(aud, acsPrincipal+"/") {
+		aud = strings.TrimPrefix(aud, acsPrincipal+"/")
+		return strings.ToLower(strings.Split(aud, "@")[0])
+	}
+	if aud == acsPrincipal {
+		return "" // SharePoint resource by ID, valid for any tenant host
+	}
+	u, err := url.Parse(aud)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(aud)
+	}
+	return strings.ToLower(u.Host)
+}
+
+// firstOf returns first non empty value
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
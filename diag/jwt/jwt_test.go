@@ -0,0 +1,59 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func encode(claims map[string]interface{}) string {
+	payload, _ := json.Marshal(claims)
+	return "eyJ0eXAiOiJKV1QiLCJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("AAD", func(t *testing.T) {
+		token, err := Decode("Bearer " + encode(map[string]interface{}{
+			"aud": []string{"https://contoso.sharepoint.com"},
+			"tid": "tenant",
+			"azp": "client",
+			"upn": "user@contoso.com",
+			"scp": "AllSites.Write User.Read",
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Source != SourceAAD || token.IdentityType != IdentityUser || token.AppID != "client" || token.User != "user@contoso.com" {
+			t.Errorf("unexpected token: %+v", token)
+		}
+		if token.AudienceHost() != "contoso.sharepoint.com" || len(token.Scopes) != 2 {
+			t.Errorf("unexpected audience or scopes: %s, %v", token.AudienceHost(), token.Scopes)
+		}
+	})
+
+	t.Run("ACS", func(t *testing.T) {
+		token, err := Decode(encode(map[string]interface{}{
+			"aud":    acsPrincipal + "/contoso.sharepoint.com@realm",
+			"iss":    acsIssuer + "@realm",
+			"nameid": "client@realm",
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Source != SourceACS || token.TenantID != "realm" || token.AppID != "client" || token.IdentityType != IdentityApp {
+			t.Errorf("unexpected token: %+v", token)
+		}
+		if token.AudienceHost() != "contoso.sharepoint.com" {
+			t.Errorf("unexpected audience host: %s", token.AudienceHost())
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		if _, err := Decode("not-a-token"); err == nil {
+			t.Error("malformed token should not pass")
+		}
+		if _, err := Decode("a.!!!.c"); err == nil {
+			t.Error("malformed payload should not pass")
+		}
+	})
+}
@@ -0,0 +1,89 @@
+/*
+Package phase implements auth check phases of diag end to end checks
+
+The package doesn't depend on auth strategies, so strategy packages tests can use it
+without import cycles, e.g. the digest phase is shared with test helpers, while diag adds
+timing, network checks and explanations on top.
+*/
+package phase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/gosip"
+)
+
+// Auth gets the strategy credential, an empty credential fails for strategies which issue one
+func Auth(authCnfg gosip.AuthCnfg) error {
+	credential, _, err := authCnfg.GetAuth()
+	if err != nil {
+		return err
+	}
+	if credential == "" && !credentialless(authCnfg.GetStrategy()) {
+		return fmt.Errorf("empty credential is received")
+	}
+	return nil
+}
+
+// Cache gets the credential again and returns the read duration, cached credentials are read instantly
+func Cache(authCnfg gosip.AuthCnfg) (time.Duration, error) {
+	start := time.Now()
+	err := Auth(authCnfg)
+	return time.Since(start), err
+}
+
+// Digest gets the form digest twice, the second one is read from the cache
+func Digest(ctx context.Context, client *gosip.SPClient) error {
+	digest, err := gosip.GetDigest(ctx, client)
+	if err != nil {
+		return fmt.Errorf("unable to get digest: %w", err)
+	}
+	if digest == "" {
+		return fmt.Errorf("got empty digest")
+	}
+	if _, err := gosip.GetDigest(ctx, client); err != nil {
+		return fmt.Errorf("unable to get cached digest: %w", err)
+	}
+	return nil
+}
+
+// Request sends the first REST call reading the current user login name, the login is empty when unknown.
+// The response is returned for headers checks, e.g. server clock and auth challenges, its body is closed.
+func Request(client *gosip.SPClient) (string, *http.Response, error) {
+	endpoint := strings.TrimRight(client.AuthCnfg.GetSiteURL(), "/") + "/_api/web/currentuser?$select=LoginName"
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to create a request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json;odata=nometadata")
+	req.Header.Set("X-Gosip-NoRetry", "true")
+
+	resp, err := client.Execute(req)
+	if err != nil {
+		return "", resp, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", resp, fmt.Errorf("unable to read a response: %w", err)
+	}
+	user := &struct {
+		LoginName string `json:"LoginName"`
+	}{}
+	if err := json.Unmarshal(data, user); err != nil {
+		return "", resp, fmt.Errorf("unable to parse a response: %w", err)
+	}
+	return user.LoginName, resp, nil
+}
+
+// credentialless checks if the strategy doesn't issue reusable credentials
+func credentialless(strategy string) bool {
+	return strategy == "ntlm" || strategy == "anonymous"
+}
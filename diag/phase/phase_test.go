@@ -0,0 +1,57 @@
+package phase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth/anon"
+	"github.com/koltyakov/gosip/auth/token"
+)
+
+func TestPhases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(strings.ToLower(r.URL.Path), "/_api/contextinfo"):
+			_, _ = w.Write([]byte(`{"d":{"GetContextWebInformation":{"FormDigestValue":"digest","FormDigestTimeoutSeconds":1800}}}`))
+		case strings.HasSuffix(r.URL.Path, "/_api/web/currentuser"):
+			if r.Header.Get("Authorization") != "Bearer valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"LoginName":"i:0#.f|membership|user@contoso.com"}`))
+		}
+	}))
+	defer server.Close()
+
+	authCnfg := &token.AuthCnfg{SiteURL: server.URL, Token: "valid"}
+	client := &gosip.SPClient{AuthCnfg: authCnfg}
+
+	if err := Auth(authCnfg); err != nil {
+		t.Error(err)
+	}
+	if _, err := Cache(authCnfg); err != nil {
+		t.Error(err)
+	}
+	if err := Digest(context.Background(), client); err != nil {
+		t.Error(err)
+	}
+	login, resp, err := Request(client)
+	if err != nil || resp == nil || login != "i:0#.f|membership|user@contoso.com" {
+		t.Errorf("unexpected current user: %s, %v", login, err)
+	}
+
+	if err := Auth(&token.AuthCnfg{SiteURL: server.URL}); err == nil {
+		t.Error("empty token should not pass")
+	}
+	if err := Auth(&anon.AuthCnfg{SiteURL: server.URL}); err != nil {
+		t.Errorf("anonymous strategy has no credential: %v", err)
+	}
+	if _, resp, err := Request(&gosip.SPClient{AuthCnfg: &token.AuthCnfg{SiteURL: server.URL, Token: "invalid"}}); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("rejected request should fail with the response: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package diag
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/gosip/diag/jwt"
+)
+
+// Token sources
+const (
+	SourceAAD = jwt.SourceAAD // Azure AD (Entra ID) issued token
+	SourceACS = jwt.SourceACS // SharePoint Add-In (Azure ACS) issued token
+)
+
+// Identity types
+const (
+	IdentityApp  = jwt.IdentityApp  // App-only token
+	IdentityUser = jwt.IdentityUser // Delegated token on behalf of a user
+)
+
+// clockSkew is tolerated difference between local and token issuer clocks
+const clockSkew = 5 * time.Minute
+
+// TokenInfo - decoded access token claims
+type TokenInfo = jwt.Token
+
+// DecodeToken decodes JWT access token claims without signature validation
+func DecodeToken(token string) (*TokenInfo, error) {
+	return jwt.Decode(token)
+}
+
+// explainToken detects common misconfigurations
+func explainToken(t *TokenInfo, siteURL string, now time.Time) []string {
+	var issues []string
+
+	if host, audHost := siteHost(siteURL), t.AudienceHost(); host != "" && audHost != "" && audHost != host {
+		issues = append(issues, fmt.Sprintf(
+			"token audience %s doesn't match site host %s, SharePoint rejects tokens issued for another resource with 401", audHost, host))
+	}
+
+	if !t.NotBefore.IsZero() && t.NotBefore.Sub(now) > clockSkew {
+		issues = append(issues, fmt.Sprintf(
+			"token is not valid until %s, local clock seems to be behind by %s", t.NotBefore.Format(time.RFC3339), t.NotBefore.Sub(now).Round(time.Second)))
+	} else if !t.IssuedAt.IsZero() && t.IssuedAt.Sub(now) > clockSkew {
+		issues = append(issues, fmt.Sprintf(
+			"token is issued in the future, local clock seems to be behind by %s", t.IssuedAt.Sub(now).Round(time.Second)))
+	}
+	if !t.ExpiresAt.IsZero() && t.ExpiresAt.Before(now) {
+		issues = append(issues, fmt.Sprintf(
+			"token is expired at %s, check the local clock or clean the auth cache", t.ExpiresAt.Format(time.RFC3339)))
+	}
+
+	if t.Source == SourceACS {
+		issues = append(issues,
+			"token is issued by Azure ACS (SharePoint Add-In), the app must be granted with appinv.aspx "+
+				"(AllowAppOnlyPolicy=\"true\" for app-only access); ACS is retired for new tenants, consider Azure AD app with a certificate")
+		return issues
+	}
+
+	if t.IdentityType == IdentityApp {
+		if !hasAny(t.Roles, "Sites.FullControl.All", "Sites.Manage.All", "Sites.ReadWrite.All", "Sites.Read.All", "Sites.Selected") {
+			issues = append(issues,
+				"app-only token has no SharePoint application permissions, grant Sites.FullControl.All (or Sites.Selected) "+
+					"application permission for SharePoint API and the admin consent")
+		} else if !hasAny(t.Roles, "Sites.FullControl.All") {
+			issues = append(issues, fmt.Sprintf(
+				"app-only token has %s but not Sites.FullControl.All, some operations like permissions management will fail with 403",
+				strings.Join(t.Roles, ", ")))
+		}
+		if t.AppAuth == "secret" {
+			issues = append(issues,
+				"app-only token is received with a client secret, SharePoint Online accepts Azure AD app-only tokens "+
+					"acquired with a certificate only (\"Unsupported app only token\")")
+		}
+	}
+
+	if t.IdentityType == IdentityUser && len(t.Scopes) > 0 && !hasPrefix(t.Scopes, "AllSites.", "Sites.", "MyFiles.", "user_impersonation") {
+		issues = append(issues, fmt.Sprintf(
+			"delegated token has no SharePoint scopes (%s), grant AllSites.* delegated permissions for SharePoint API", strings.Join(t.Scopes, " ")))
+	}
+
+	return issues
+}
+
+// hasAny checks if any of values present in the list
+func hasAny(list []string, values ...string) bool {
+	for _, l := range list {
+		for _, v := range values {
+			if strings.EqualFold(l, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasPrefix checks if any of the list items starts with any of the prefixes
+func hasPrefix(list []string, prefixes ...string) bool {
+	for _, l := range list {
+		for _, p := range prefixes {
+			if strings.HasPrefix(l, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package gosip
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// csomResponse is a synthetic CSOM ProcessQuery response without errors
+const csomResponse = `[{"SchemaVersion":"15.0.0.0","LibraryVersion":"16.0.0.0","ErrorInfo":null,"TraceCorrelationId":"00000000-0000-0000-0000-000000000000"}]`
+
+// gosipDir is the root package location used to exclude client frames from callers
+var gosipDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// DryRun records mutating requests instead of sending them, reads are sent as usual.
+// Enabled when assigned to SPClient.DryRun:
+//
+//	plan := &gosip.DryRun{}
+//	client := &gosip.SPClient{AuthCnfg: auth, DryRun: plan}
+//	// ... provisioning script
+//	fmt.Println(plan)
+//
+// Intercepted requests get synthetic success responses with an empty object body,
+// so entities depending on created objects' data (e.g. IDs) can't be planned beyond the first step.
+// Graph JSON batches with reads only are sent, batches with writes get a success for each sub-request.
+type DryRun struct {
+	mux        sync.Mutex
+	operations []*DryRunOperation
+}
+
+// DryRunOperation - intercepted request
+type DryRunOperation struct {
+	Method string      `json:"method"`           // Effective method, including X-HTTP-Method tunnelling
+	URL    string      `json:"url"`              // Request URL
+	Body   interface{} `json:"body,omitempty"`   // Decoded JSON body, CSOM XML or text
+	Entity string      `json:"entity,omitempty"` // Calling entity method, e.g. "api.(*Item).Delete"
+	Time   time.Time   `json:"time"`
+}
+
+// Operations gets recorded operations
+func (d *DryRun) Operations() []*DryRunOperation {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	operations := make([]*DryRunOperation, len(d.operations))
+	copy(operations, d.operations)
+	return operations
+}
+
+// Reset clears recorded operations
+func (d *DryRun) Reset() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.operations = nil
+}
+
+// JSON exports recorded operations as JSON
+func (d *DryRun) JSON() ([]byte, error) {
+	return json.MarshalIndent(d.Operations(), "", "  ")
+}
+
+// String exports recorded operations as text
+func (d *DryRun) String() string {
+	operations := d.Operations()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Dry run: %d operation(s)\n", len(operations))
+	for i, op := range operations {
+		fmt.Fprintf(&b, "%d. %s %s", i+1, op.Method, op.URL)
+		if op.Entity != "" {
+			fmt.Fprintf(&b, " [%s]", op.Entity)
+		}
+		b.WriteString("\n")
+		if op.Body == nil {
+			continue
+		}
+		body, ok := op.Body.(string)
+		if !ok {
+			data, _ := json.Marshal(op.Body)
+			body = string(data)
+		}
+		fmt.Fprintf(&b, "   %s\n", strings.TrimSpace(body))
+	}
+	return b.String()
+}
+
+// intercept records mutating request and returns a synthetic response, nil for requests to send
+func (d *DryRun) intercept(req *http.Request) (*http.Response, error) {
+	if isReadMethod(effectiveMethod(req)) {
+		return nil, nil
+	}
+
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	if !mutates(req, body) {
+		return nil, nil
+	}
+	entity := callerEntity()
+
+	if requests, ok := jsonBatch(req, body); ok {
+		return dryRunResponse(req, d.interceptBatch(req, requests, entity)), nil
+	}
+
+	d.record(effectiveMethod(req), req.URL.String(), body, entity)
+
+	respBody := "{}"
+	if isCSOM(req) {
+		respBody = csomResponse
+	} else if strings.Contains(req.Header.Get("Accept"), "odata=verbose") {
+		respBody = `{"d":{}}`
+	}
+	return dryRunResponse(req, respBody), nil
+}
+
+// interceptBatch records mutating Graph JSON batch sub-requests, returns a synthetic batch response
+// with a success for each sub-request, reads in a mixed batch are not sent and get empty objects too
+func (d *DryRun) interceptBatch(req *http.Request, requests []*jsonBatchRequest, entity string) string {
+	base := *req.URL
+	base.Path = strings.TrimSuffix(base.Path, "/$batch")
+	base.RawPath = ""
+	base.RawQuery = ""
+
+	responses := make([]map[string]interface{}, 0, len(requests))
+	for _, r := range requests {
+		if method := strings.ToUpper(r.Method); !isReadMethod(method) {
+			d.record(method, base.String()+"/"+strings.TrimPrefix(r.URL, "/"), r.Body, entity)
+		}
+		responses = append(responses, map[string]interface{}{
+			"id":      r.ID,
+			"status":  200,
+			"headers": map[string]string{"Content-Type": "application/json"},
+			"body":    map[string]interface{}{},
+		})
+	}
+	data, _ := json.Marshal(map[string]interface{}{"responses": responses})
+	return string(data)
+}
+
+// record adds an operation decoding JSON body
+func (d *DryRun) record(method string, url string, body []byte, entity string) {
+	op := &DryRunOperation{
+		Method: method,
+		URL:    url,
+		Entity: entity,
+		Time:   time.Now(),
+	}
+	if len(body) > 0 {
+		var decoded interface{}
+		if err := json.Unmarshal(body, &decoded); err == nil {
+			op.Body = decoded
+		} else {
+			op.Body = string(body)
+		}
+	}
+	d.mux.Lock()
+	d.operations = append(d.operations, op)
+	d.mux.Unlock()
+}
+
+// dryRunResponse creates a synthetic success response
+func dryRunResponse(req *http.Request, respBody string) *http.Response {
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type":   []string{"application/json"},
+			"X-Gosip-DryRun": []string{"true"},
+		},
+		Body:          io.NopCloser(strings.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}
+}
+
+// callerEntity gets the first caller outside of the client and HTTP helpers, e.g. "api.(*Item).Delete"
+func callerEntity() string {
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(3, pc)])
+	for {
+		frame, more := frames.Next()
+		internal := filepath.Dir(frame.File) == gosipDir && !strings.HasSuffix(frame.File, "_test.go")
+		helper := strings.Contains(frame.Function, ".(*HTTPClient).") || strings.HasPrefix(frame.Function, "net/http.")
+		if !internal && !helper && frame.Function != "" {
+			name := frame.Function
+			if i := strings.LastIndex(name, "/"); i != -1 {
+				name = name[i+1:]
+			}
+			return name
+		}
+		if !more {
+			return ""
+		}
+	}
+}
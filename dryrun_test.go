@@ -0,0 +1,160 @@
+package gosip
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type dryRunEntity struct{ client *SPClient }
+
+func (e *dryRunEntity) Delete(url string) (*http.Response, error) {
+	req, _ := http.NewRequest("POST", url, nil)
+	req.Header.Set("X-HTTP-Method", "DELETE")
+	return e.client.Execute(req)
+}
+
+func TestDryRun(t *testing.T) {
+	var mux sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		mux.Unlock()
+		if strings.EqualFold(r.URL.Path, "/_api/ContextInfo") {
+			_, _ = fmt.Fprint(w, `{"d":{"GetContextWebInformation":{"FormDigestValue":"FAKE","FormDigestTimeoutSeconds":120}}}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"value":[]}`)
+	}))
+	defer server.Close()
+
+	plan := &DryRun{}
+	client := &SPClient{AuthCnfg: &AnonymousCnfg{SiteURL: server.URL}, DryRun: plan}
+
+	request := func(method string, path string, body string, headers map[string]string) []byte {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		return data
+	}
+
+	request("GET", "/_api/web", "", nil)
+	request("POST", "/_api/web/lists/getByTitle('Tasks')/GetItems", `{"query":{}}`, nil)
+	if data := request("POST", "/_api/web/lists/getByTitle('Tasks')/items", `{"Title":"New"}`, map[string]string{"Accept": "application/json;odata=verbose"}); string(data) != `{"d":{}}` {
+		t.Errorf("unexpected synthetic response: %s", data)
+	}
+	request("POST", "/_api/web/lists/getByTitle('Tasks')/items(1)", `{"Title":"Updated"}`, map[string]string{"X-HTTP-Method": "MERGE"})
+	request("POST", "/_vti_bin/client.svc/ProcessQuery", `<Request><Actions><Query Id="1" ObjectPathId="0" /></Actions></Request>`, nil)
+	if data := request("POST", "/_vti_bin/client.svc/ProcessQuery", `<Request><Actions><Method Name="DeleteObject" Id="1" ObjectPathId="0" /></Actions></Request>`, nil); !strings.Contains(string(data), `"ErrorInfo":null`) {
+		t.Errorf("unexpected synthetic CSOM response: %s", data)
+	}
+	if _, err := (&dryRunEntity{client: client}).Delete(server.URL + "/_api/web/lists/getByTitle('Tasks')"); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(sent, ",") != "GET /_api/web,POST /_api/ContextInfo,POST /_api/web/lists/getByTitle('Tasks')/GetItems,POST /_vti_bin/client.svc/ProcessQuery" {
+		t.Errorf("unexpected sent requests: %v", sent)
+	}
+
+	operations := plan.Operations()
+	if len(operations) != 4 {
+		t.Fatalf("unexpected operations number: %d", len(operations))
+	}
+	if operations[1].Method != "MERGE" {
+		t.Errorf("tunnelled method is expected, got %s", operations[1].Method)
+	}
+	if body, ok := operations[0].Body.(map[string]interface{}); !ok || body["Title"] != "New" {
+		t.Errorf("decoded JSON body is expected, got %v", operations[0].Body)
+	}
+	if body, ok := operations[2].Body.(string); !ok || !strings.Contains(body, "DeleteObject") {
+		t.Errorf("CSOM body is expected, got %v", operations[2].Body)
+	}
+	if operations[3].Method != "DELETE" || operations[3].Entity != "gosip.(*dryRunEntity).Delete" {
+		t.Errorf("unexpected operation: %s by %s", operations[3].Method, operations[3].Entity)
+	}
+
+	data, err := plan.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []*DryRunOperation
+	if err := json.Unmarshal(data, &exported); err != nil || len(exported) != 4 {
+		t.Errorf("unexpected JSON export: %s", data)
+	}
+	if text := plan.String(); !strings.HasPrefix(text, "Dry run: 4 operation(s)\n1. POST ") || !strings.Contains(text, `{"Title":"Updated"}`) {
+		t.Errorf("unexpected text export: %s", text)
+	}
+
+	plan.Reset()
+	if len(plan.Operations()) != 0 {
+		t.Error("operations should be cleared")
+	}
+}
+
+func TestDryRunBatch(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		_, _ = fmt.Fprint(w, `{"responses":[{"id":"1","status":200,"body":{"name":"root"}}]}`)
+	}))
+	defer server.Close()
+
+	plan := &DryRun{}
+	client := &SPClient{AuthCnfg: &AnonymousCnfg{SiteURL: server.URL}, DryRun: plan}
+
+	batch := func(requests string) []map[string]interface{} {
+		t.Helper()
+		req, _ := http.NewRequest("POST", server.URL+"/v1.0/$batch", strings.NewReader(`{"requests":`+requests+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gosip-NoDigest", "true")
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		result := struct {
+			Responses []map[string]interface{} `json:"responses"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return result.Responses
+	}
+
+	if responses := batch(`[{"id":"1","method":"GET","url":"/sites/root"}]`); len(responses) != 1 || len(sent) != 1 {
+		t.Errorf("read-only batch should be sent: %v", responses)
+	}
+	if len(plan.Operations()) != 0 {
+		t.Error("read-only batch should not be recorded")
+	}
+
+	responses := batch(`[{"id":"1","method":"GET","url":"/sites/root"},{"id":"2","method":"POST","url":"/sites/root/lists","body":{"displayName":"New"}}]`)
+	if len(sent) != 1 {
+		t.Errorf("mutating batch should not be sent: %v", sent)
+	}
+	if len(responses) != 2 || responses[0]["id"] != "1" || responses[1]["id"] != "2" || responses[1]["status"] != float64(200) {
+		t.Errorf("synthetic response for each sub-request is expected: %v", responses)
+	}
+	operations := plan.Operations()
+	if len(operations) != 1 || operations[0].Method != "POST" || operations[0].URL != server.URL+"/v1.0/sites/root/lists" {
+		t.Fatalf("mutating sub-request should be recorded: %v", operations)
+	}
+	if body, ok := operations[0].Body.(map[string]interface{}); !ok || body["displayName"] != "New" {
+		t.Errorf("decoded sub-request body is expected, got %v", operations[0].Body)
+	}
+}
@@ -11,6 +11,8 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/koltyakov/lorca v0.1.9-0.20230410140121-2f2b4c1ec5f4
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	golang.org/x/crypto v0.33.0
+	software.sslmate.com/src/go-pkcs12 v0.4.0
 )
 
 require (
@@ -22,7 +24,6 @@ require (
 	github.com/dimchansky/utfbom v1.1.1 // indirect
 	github.com/golang-jwt/jwt/v4 v4.5.2 // indirect
 	github.com/mitchellh/go-homedir v1.1.0 // indirect
-	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/net v0.21.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 )
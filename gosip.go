@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -68,6 +69,11 @@ type SPClient struct {
 
 	RetryPolicies map[int]int   // allows redefining error state requests retry policies
 	Hooks         *HookHandlers // hook handlers definition
+	DryRun        *DryRun       // records mutating requests instead of sending them when defined
+	Journal       Journaler     // records successful mutating requests when defined
+
+	transportOnce sync.Once // auth config transport settings are applied once before the first request
+	transportErr  error
 }
 
 // SPError represents a SharePoint HTTP error with status code and body
@@ -109,6 +115,14 @@ func (c *SPClient) Execute(req *http.Request) (*http.Response, error) {
 		// else: unknown/large bodies fallback to per-attempt TeeReader buffering
 	}
 
+	// Intercept mutating requests in dry-run mode
+	if c.DryRun != nil {
+		if res, err := c.DryRun.intercept(req); res != nil || err != nil {
+			return res, err
+		}
+	}
+
+	sessionRenewed := false
 	for {
 		reqTime := time.Now()
 
@@ -176,6 +190,23 @@ func (c *SPClient) Execute(req *http.Request) (*http.Response, error) {
 			return resp, err
 		}
 
+		// Re-authenticate once when auth session is invalidated server-side
+		if !sessionRenewed && c.shouldRenewSession(req, resp) {
+			sessionRenewed = true
+			c.onRetry(req, reqTime, resp.StatusCode, nil)
+			// Reset body for next attempt
+			if bodyRebuilder != nil {
+				if rc, e := bodyRebuilder(); e == nil {
+					req.Body = rc
+				} else {
+					return resp, e
+				}
+			} else if usedTee {
+				req.Body = io.NopCloser(bytes.NewReader(bodyBuf.Bytes()))
+			}
+			continue
+		}
+
 		// Wait and retry after a delay for error state responses, due to retry policies
 		if retries := c.getRetryPolicy(resp.StatusCode); retries > 0 {
 			// Register retry in OnError hook for throttling
@@ -220,6 +251,19 @@ func (c *SPClient) Execute(req *http.Request) (*http.Response, error) {
 			c.onError(req, reqTime, resp.StatusCode, outErr)
 		}
 
+		// Journal successful changes
+		if outErr == nil && c.Journal != nil {
+			var reqBody []byte
+			if bodyRebuilder != nil {
+				if rc, e := bodyRebuilder(); e == nil {
+					reqBody, _ = io.ReadAll(rc)
+				}
+			} else if usedTee {
+				reqBody = bodyBuf.Bytes()
+			}
+			c.record(req, reqBody, resp, reqTime)
+		}
+
 		c.onResponse(req, reqTime, resp.StatusCode, outErr)
 		return resp, outErr
 	}
@@ -242,6 +286,16 @@ func (c *SPClient) applyAuth(req *http.Request) (*http.Response, error) {
 		return res, fmt.Errorf("client initialization error, no siteUrl is provided")
 	}
 
+	// Apply transport settings from the config
+	if err := c.applyTransport(); err != nil {
+		res := &http.Response{
+			Status:     "400 Bad Request",
+			StatusCode: 400,
+			Request:    req,
+		}
+		return res, err
+	}
+
 	// Wrap SharePoint authentication
 	err := c.AuthCnfg.SetAuth(req, c)
 	if err != nil {
@@ -261,7 +315,8 @@ func (c *SPClient) applyHeaders(req *http.Request) error {
 	// Inject X-RequestDigest header when needed
 	digestIsRequired := (req.Method == "POST" || req.Method == "PATCH" || req.Method == "MERGE") &&
 		!strings.Contains(strings.ToLower(req.URL.Path), "/_api/contextinfo") &&
-		req.Header.Get("X-RequestDigest") == ""
+		req.Header.Get("X-RequestDigest") == "" &&
+		req.Header.Get("X-Gosip-NoDigest") != "true" // non-SharePoint APIs, e.g. Microsoft Graph
 
 	if digestIsRequired {
 		digest, err := GetDigest(req.Context(), c)
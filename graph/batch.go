@@ -0,0 +1,307 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/gosip"
+)
+
+// batchLimit is a maximum number of requests in a single Graph JSON batch
+const batchLimit = 20
+
+// batchRetries is a number of retries for throttled batch sub-requests
+const batchRetries = 3
+
+// Batch - Graph JSON batch, requests are sent in chunks of 20 keeping dependsOn chains together,
+// throttled sub-requests (429, 503) are retried honoring Retry-After
+// Always use NewBatch constructor instead of &Batch{}
+type Batch struct {
+	client   *gosip.SPClient
+	config   *RequestConfig
+	endpoint string
+	requests []*BatchRequest
+}
+
+// BatchRequest - JSON batch sub-request
+type BatchRequest struct {
+	ID        string            `json:"id"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"` // URL relative to the version root, e.g. "/sites/root"
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body,omitempty"`
+	DependsOn []string          `json:"dependsOn,omitempty"`
+}
+
+// BatchResponse - JSON batch sub-response
+type BatchResponse struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// NewBatch - Batch struct constructor function, endpoint is Graph versioned root URL
+func NewBatch(client *gosip.SPClient, endpoint string, config *RequestConfig) *Batch {
+	return &Batch{client: client, endpoint: endpoint, config: config}
+}
+
+// Add adds a request to the batch, the endpoint can be absolute entity URL or relative to the version root,
+// returns the request ID
+func (batch *Batch) Add(method string, endpoint string, body []byte) string {
+	req := &BatchRequest{
+		ID:     batch.nextID(),
+		Method: strings.ToUpper(method),
+		URL:    strings.TrimPrefix(endpoint, batch.endpoint),
+	}
+	if !strings.HasPrefix(req.URL, "/") {
+		req.URL = "/" + req.URL
+	}
+	if len(body) > 0 {
+		req.Body = body
+		req.Headers = map[string]string{"Content-Type": "application/json"}
+	}
+	batch.requests = append(batch.requests, req)
+	return req.ID
+}
+
+// AddRequest adds a prepared request to the batch, the request ID is generated when empty,
+// returns the request ID or an error when the ID is already used in the batch
+func (batch *Batch) AddRequest(req *BatchRequest) (string, error) {
+	if req.ID == "" {
+		req.ID = batch.nextID()
+	}
+	if batch.request(req.ID) != nil {
+		return "", fmt.Errorf("duplicate batch request ID %s", req.ID)
+	}
+	batch.requests = append(batch.requests, req)
+	return req.ID, nil
+}
+
+// Len gets the number of batch requests
+func (batch *Batch) Len() int {
+	return len(batch.requests)
+}
+
+// Execute sends the batch, returns responses in requests order
+func (batch *Batch) Execute() ([]*BatchResponse, error) {
+	chunks, err := batch.chunks()
+	if err != nil {
+		return nil, err
+	}
+	responses := map[string]*BatchResponse{}
+	for _, chains := range chunks {
+		if err := batch.execute(chains, responses); err != nil {
+			return nil, err
+		}
+	}
+	results := make([]*BatchResponse, 0, len(batch.requests))
+	for _, req := range batch.requests {
+		resp, ok := responses[req.ID]
+		if !ok {
+			return nil, fmt.Errorf("no batch response received for request %s", req.ID)
+		}
+		results = append(results, resp)
+	}
+	return results, nil
+}
+
+// nextID gets the next free numeric request ID
+func (batch *Batch) nextID() string {
+	for n := len(batch.requests) + 1; ; n++ {
+		if id := strconv.Itoa(n); batch.request(id) == nil {
+			return id
+		}
+	}
+}
+
+// request gets a batch request by ID
+func (batch *Batch) request(id string) *BatchRequest {
+	for _, req := range batch.requests {
+		if req.ID == id {
+			return req
+		}
+	}
+	return nil
+}
+
+// chains groups requests linked with dependsOn, Graph requires dependent requests in the same $batch call,
+// chains are listed in order of their first request, requests keep the batch order
+func (batch *Batch) chains() ([][]*BatchRequest, error) {
+	index := map[string]int{}
+	for i, req := range batch.requests {
+		index[req.ID] = i
+	}
+	parent := make([]int, len(batch.requests))
+	for i := range parent {
+		parent[i] = i
+	}
+	root := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for i, req := range batch.requests {
+		for _, id := range req.DependsOn {
+			j, ok := index[id]
+			if !ok {
+				return nil, fmt.Errorf("batch request %s depends on unknown request %s", req.ID, id)
+			}
+			a, b := root(i), root(j)
+			if a > b {
+				a, b = b, a
+			}
+			parent[b] = a
+		}
+	}
+	var chains [][]*BatchRequest
+	position := map[int]int{}
+	for i, req := range batch.requests {
+		r := root(i)
+		p, ok := position[r]
+		if !ok {
+			p = len(chains)
+			position[r] = p
+			chains = append(chains, nil)
+		}
+		chains[p] = append(chains[p], req)
+	}
+	return chains, nil
+}
+
+// chunks packs request chains into $batch calls of up to 20 requests, a chain is never split
+func (batch *Batch) chunks() ([][][]*BatchRequest, error) {
+	chains, err := batch.chains()
+	if err != nil {
+		return nil, err
+	}
+	var chunks [][][]*BatchRequest
+	size := batchLimit
+	for _, chain := range chains {
+		if len(chain) > batchLimit {
+			return nil, fmt.Errorf("batch request %s dependsOn chain exceeds %d requests", chain[0].ID, batchLimit)
+		}
+		if size+len(chain) > batchLimit {
+			chunks = append(chunks, nil)
+			size = 0
+		}
+		chunks[len(chunks)-1] = append(chunks[len(chunks)-1], chain)
+		size += len(chain)
+	}
+	return chunks, nil
+}
+
+// execute sends a batch chunk retrying throttled chains, a chain is re-sent as a whole
+// only when none of its requests succeeded, otherwise throttled responses are returned as is
+func (batch *Batch) execute(chains [][]*BatchRequest, responses map[string]*BatchResponse) error {
+	for retry := 0; len(chains) > 0; retry++ {
+		var requests []*BatchRequest
+		for _, chain := range chains {
+			requests = append(requests, chain...)
+		}
+		body, err := json.Marshal(map[string]interface{}{"requests": requests})
+		if err != nil {
+			return err
+		}
+		data, err := NewHTTPClient(batch.client).Post(batch.endpoint+"/$batch", bytes.NewBuffer(body), batch.config)
+		if err != nil {
+			return err
+		}
+		result := struct {
+			Responses []*BatchResponse `json:"responses"`
+		}{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("can't parse batch response: %w", err)
+		}
+
+		retryAfter := 0
+		for _, resp := range result.Responses {
+			responses[resp.ID] = resp
+			if resp.Status == 429 || resp.Status == 503 {
+				if after, _ := strconv.Atoi(resp.Header("Retry-After")); after > retryAfter {
+					retryAfter = after
+				}
+			}
+		}
+		if retry >= batchRetries {
+			return nil
+		}
+
+		var pending [][]*BatchRequest
+		for _, chain := range chains {
+			if retriable(chain, responses) {
+				pending = append(pending, chain)
+			}
+		}
+		chains = pending
+		if len(chains) == 0 {
+			return nil
+		}
+
+		sleepTimeout := time.Duration(100<<retry) * time.Millisecond // default, no Retry-After header
+		if retryAfter > 0 {
+			sleepTimeout = time.Duration(retryAfter) * time.Second
+		}
+		if batch.config != nil && batch.config.Context != nil {
+			select {
+			case <-batch.config.Context.Done():
+				return batch.config.Context.Err()
+			case <-time.After(sleepTimeout):
+			}
+		} else {
+			time.Sleep(sleepTimeout)
+		}
+	}
+	return nil
+}
+
+// retriable checks if a chain is throttled and none of its requests were executed,
+// requests depending on a throttled one fail with 424 Failed Dependency
+func retriable(chain []*BatchRequest, responses map[string]*BatchResponse) bool {
+	throttled := false
+	for _, req := range chain {
+		resp, ok := responses[req.ID]
+		if !ok {
+			return false
+		}
+		switch resp.Status {
+		case 429, 503:
+			throttled = true
+		case 424:
+		default:
+			return false
+		}
+	}
+	return throttled
+}
+
+// Header gets sub-response header value, the name is case-insensitive
+func (resp *BatchResponse) Header(name string) string {
+	for key, value := range resp.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Err gets sub-response error, nil for successful responses
+func (resp *BatchResponse) Err() error {
+	if resp.Status < 400 {
+		return nil
+	}
+	e := struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	_ = json.Unmarshal(resp.Body, &e)
+	return fmt.Errorf("batch request %s failed with %d: %s %s", resp.ID, resp.Status, e.Error.Code, e.Error.Message)
+}
@@ -0,0 +1,9 @@
+// Code generated by `ggen -ent Delta -conf`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (delta *Delta) Conf(config *RequestConfig) *Delta {
+	delta.config = config
+	return delta
+}
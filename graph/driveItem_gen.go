@@ -0,0 +1,21 @@
+// Code generated by `ggen -ent DriveItem -conf -mods Select,Expand`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (driveItem *DriveItem) Conf(config *RequestConfig) *DriveItem {
+	driveItem.config = config
+	return driveItem
+}
+
+// Select adds $select OData modifier
+func (driveItem *DriveItem) Select(oDataSelect string) *DriveItem {
+	driveItem.modifiers.AddSelect(oDataSelect)
+	return driveItem
+}
+
+// Expand adds $expand OData modifier
+func (driveItem *DriveItem) Expand(oDataExpand string) *DriveItem {
+	driveItem.modifiers.AddExpand(oDataExpand)
+	return driveItem
+}
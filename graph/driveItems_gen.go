@@ -0,0 +1,39 @@
+// Code generated by `ggen -ent DriveItems -conf -coll -mods Select,Expand,Filter,Top,OrderBy`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (driveItems *DriveItems) Conf(config *RequestConfig) *DriveItems {
+	driveItems.config = config
+	return driveItems
+}
+
+// Select adds $select OData modifier
+func (driveItems *DriveItems) Select(oDataSelect string) *DriveItems {
+	driveItems.modifiers.AddSelect(oDataSelect)
+	return driveItems
+}
+
+// Expand adds $expand OData modifier
+func (driveItems *DriveItems) Expand(oDataExpand string) *DriveItems {
+	driveItems.modifiers.AddExpand(oDataExpand)
+	return driveItems
+}
+
+// Filter adds $filter OData modifier
+func (driveItems *DriveItems) Filter(oDataFilter string) *DriveItems {
+	driveItems.modifiers.AddFilter(oDataFilter)
+	return driveItems
+}
+
+// Top adds $top OData modifier
+func (driveItems *DriveItems) Top(oDataTop int) *DriveItems {
+	driveItems.modifiers.AddTop(oDataTop)
+	return driveItems
+}
+
+// OrderBy adds $orderby OData modifier
+func (driveItems *DriveItems) OrderBy(oDataOrderBy string, ascending bool) *DriveItems {
+	driveItems.modifiers.AddOrderBy(oDataOrderBy, ascending)
+	return driveItems
+}
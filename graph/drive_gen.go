@@ -0,0 +1,21 @@
+// Code generated by `ggen -ent Drive -conf -mods Select,Expand`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (drive *Drive) Conf(config *RequestConfig) *Drive {
+	drive.config = config
+	return drive
+}
+
+// Select adds $select OData modifier
+func (drive *Drive) Select(oDataSelect string) *Drive {
+	drive.modifiers.AddSelect(oDataSelect)
+	return drive
+}
+
+// Expand adds $expand OData modifier
+func (drive *Drive) Expand(oDataExpand string) *Drive {
+	drive.modifiers.AddExpand(oDataExpand)
+	return drive
+}
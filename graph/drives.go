@@ -0,0 +1,280 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/api"
+)
+
+//go:generate ggen -ent Drives -conf -coll -mods Select,Filter,Top,OrderBy
+//go:generate ggen -ent Drive -conf -mods Select,Expand
+//go:generate ggen -ent DriveItems -conf -coll -mods Select,Expand,Filter,Top,OrderBy
+//go:generate ggen -ent DriveItem -conf -mods Select,Expand
+
+// Drives represent Graph Drives API queryable collection struct
+// Always use NewDrives constructor instead of &Drives{}
+type Drives struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// Drive represents Graph Drive (document library) API queryable object struct
+// Always use NewDrive constructor instead of &Drive{}
+type Drive struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// DriveItems represent Graph DriveItems API queryable collection struct
+// Always use NewDriveItems constructor instead of &DriveItems{}
+type DriveItems struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// DriveItem represents Graph DriveItem (file or folder) API queryable object struct
+// Always use NewDriveItem constructor instead of &DriveItem{}
+type DriveItem struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// DriveInfo - drive API response payload structure
+type DriveInfo struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	DriveType   string `json:"driveType"`
+	WebURL      string `json:"webUrl"`
+}
+
+// DriveItemInfo - drive item API response payload structure
+type DriveItemInfo struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	Size                 int64  `json:"size"`
+	WebURL               string `json:"webUrl"`
+	ETag                 string `json:"eTag"`
+	CreatedDateTime      string `json:"createdDateTime"`
+	LastModifiedDateTime string `json:"lastModifiedDateTime"`
+	File                 *struct {
+		MimeType string `json:"mimeType"`
+	} `json:"file,omitempty"`
+	Folder *struct {
+		ChildCount int `json:"childCount"`
+	} `json:"folder,omitempty"`
+	ParentReference *struct {
+		DriveID string `json:"driveId"`
+		ID      string `json:"id"`
+		Path    string `json:"path"`
+	} `json:"parentReference,omitempty"`
+	Deleted *struct {
+		State string `json:"state"`
+	} `json:"deleted,omitempty"` // Set for removed items in delta query results
+}
+
+// DriveResp - drive response type with helper processor methods
+type DriveResp []byte
+
+// DriveItemResp - drive item response type with helper processor methods
+type DriveItemResp []byte
+
+// NewDrives - Drives struct constructor function
+func NewDrives(client *gosip.SPClient, endpoint string, config *RequestConfig) *Drives {
+	return &Drives{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (drives *Drives) ToURL() string {
+	return toURL(drives.endpoint, drives.modifiers)
+}
+
+// Get gets drives collection page
+func (drives *Drives) Get() (*Page, error) {
+	return getPage(drives.client, drives.ToURL(), drives.config)
+}
+
+// GetByID gets a drive by its ID
+func (drives *Drives) GetByID(driveID string) *Drive {
+	return NewDrive(drives.client, fmt.Sprintf("%s/%s", drives.endpoint, driveID), drives.config)
+}
+
+// NewDrive - Drive struct constructor function
+func NewDrive(client *gosip.SPClient, endpoint string, config *RequestConfig) *Drive {
+	return &Drive{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (drive *Drive) ToURL() string {
+	return toURL(drive.endpoint, drive.modifiers)
+}
+
+// Get gets drive data object
+func (drive *Drive) Get() (DriveResp, error) {
+	return NewHTTPClient(drive.client).Get(drive.ToURL(), drive.config)
+}
+
+// Root gets drive root folder
+func (drive *Drive) Root() *DriveItem {
+	return NewDriveItem(drive.client, drive.endpoint+"/root", drive.config)
+}
+
+// GetItemByID gets a drive item by its ID
+func (drive *Drive) GetItemByID(itemID string) *DriveItem {
+	return NewDriveItem(drive.client, fmt.Sprintf("%s/items/%s", drive.endpoint, itemID), drive.config)
+}
+
+// GetItemByPath gets a drive item by its path relative to the drive root, e.g. "Reports/2024/report.pdf"
+func (drive *Drive) GetItemByPath(path string) *DriveItem {
+	if strings.Trim(path, "/") == "" {
+		return drive.Root()
+	}
+	return NewDriveItem(drive.client, fmt.Sprintf("%s/root:/%s:", drive.endpoint, escapePath(path)), drive.config)
+}
+
+// Delta gets drive changes delta query
+func (drive *Drive) Delta() *Delta {
+	return NewDelta(drive.client, drive.endpoint+"/root/delta", drive.config)
+}
+
+// NewDriveItems - DriveItems struct constructor function
+func NewDriveItems(client *gosip.SPClient, endpoint string, config *RequestConfig) *DriveItems {
+	return &DriveItems{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (driveItems *DriveItems) ToURL() string {
+	return toURL(driveItems.endpoint, driveItems.modifiers)
+}
+
+// Get gets drive items collection page
+func (driveItems *DriveItems) Get() (*Page, error) {
+	return getPage(driveItems.client, driveItems.ToURL(), driveItems.config)
+}
+
+// NewDriveItem - DriveItem struct constructor function
+func NewDriveItem(client *gosip.SPClient, endpoint string, config *RequestConfig) *DriveItem {
+	return &DriveItem{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (driveItem *DriveItem) ToURL() string {
+	return toURL(driveItem.endpoint, driveItem.modifiers)
+}
+
+// Get gets drive item data object
+func (driveItem *DriveItem) Get() (DriveItemResp, error) {
+	return NewHTTPClient(driveItem.client).Get(driveItem.ToURL(), driveItem.config)
+}
+
+// Update updates drive item metadata, e.g. `{"name":"renamed.docx"}`
+func (driveItem *DriveItem) Update(body []byte) (DriveItemResp, error) {
+	return NewHTTPClient(driveItem.client).Patch(driveItem.endpoint, bytes.NewBuffer(body), driveItem.config)
+}
+
+// Delete deletes drive item to the recycle bin
+func (driveItem *DriveItem) Delete() error {
+	_, err := NewHTTPClient(driveItem.client).Delete(driveItem.endpoint, driveItem.config)
+	return err
+}
+
+// Children gets folder children
+func (driveItem *DriveItem) Children() *DriveItems {
+	return NewDriveItems(driveItem.client, driveItem.endpoint+"/children", driveItem.config)
+}
+
+// AddFolder creates a child folder, fails when an item with the same name exists
+func (driveItem *DriveItem) AddFolder(name string) (DriveItemResp, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"name":                              name,
+		"folder":                            map[string]interface{}{},
+		"@microsoft.graph.conflictBehavior": "fail",
+	})
+	return NewHTTPClient(driveItem.client).Post(driveItem.endpoint+"/children", bytes.NewBuffer(body), driveItem.config)
+}
+
+// Upload uploads a file to the folder, simple upload supports files up to 250 MB
+func (driveItem *DriveItem) Upload(name string, content io.Reader) (DriveItemResp, error) {
+	endpoint := fmt.Sprintf("%s:/%s:/content", strings.TrimSuffix(driveItem.endpoint, ":"), escapePath(name))
+	conf := patchConfigHeaders(driveItem.config, map[string]string{"Content-Type": "application/octet-stream"})
+	return NewHTTPClient(driveItem.client).Put(endpoint, content, conf)
+}
+
+// Download gets file content
+func (driveItem *DriveItem) Download() ([]byte, error) {
+	reader, err := driveItem.GetReader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return io.ReadAll(reader)
+}
+
+// GetReader gets file content reader, the reader should be closed by the caller
+func (driveItem *DriveItem) GetReader() (io.ReadCloser, error) {
+	conf := patchConfigHeaders(driveItem.config, map[string]string{"Accept": "*/*"})
+	resp, err := NewHTTPClient(driveItem.client).Do("GET", driveItem.endpoint+"/content", nil, conf)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Data response helper
+func (driveResp *DriveResp) Data() *DriveInfo {
+	data := &DriveInfo{}
+	_ = json.Unmarshal(*driveResp, data)
+	return data
+}
+
+// Data response helper
+func (driveItemResp *DriveItemResp) Data() *DriveItemInfo {
+	data := &DriveItemInfo{}
+	_ = json.Unmarshal(*driveItemResp, data)
+	return data
+}
+
+// patchConfigHeaders copies request config with additional headers, config headers take precedence
+func patchConfigHeaders(config *RequestConfig, headers map[string]string) *RequestConfig {
+	patched := &RequestConfig{Headers: headers}
+	if config != nil {
+		patched.Context = config.Context
+		for key, value := range config.Headers {
+			patched.Headers[key] = value
+		}
+	}
+	return patched
+}
@@ -0,0 +1,33 @@
+// Code generated by `ggen -ent Drives -conf -coll -mods Select,Filter,Top,OrderBy`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (drives *Drives) Conf(config *RequestConfig) *Drives {
+	drives.config = config
+	return drives
+}
+
+// Select adds $select OData modifier
+func (drives *Drives) Select(oDataSelect string) *Drives {
+	drives.modifiers.AddSelect(oDataSelect)
+	return drives
+}
+
+// Filter adds $filter OData modifier
+func (drives *Drives) Filter(oDataFilter string) *Drives {
+	drives.modifiers.AddFilter(oDataFilter)
+	return drives
+}
+
+// Top adds $top OData modifier
+func (drives *Drives) Top(oDataTop int) *Drives {
+	drives.modifiers.AddTop(oDataTop)
+	return drives
+}
+
+// OrderBy adds $orderby OData modifier
+func (drives *Drives) OrderBy(oDataOrderBy string, ascending bool) *Drives {
+	drives.modifiers.AddOrderBy(oDataOrderBy, ascending)
+	return drives
+}
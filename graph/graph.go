@@ -0,0 +1,123 @@
+/*
+Package graph implements Microsoft Graph API client for SharePoint Online sites, drives and lists
+
+The client reuses gosip auth strategies which can acquire tokens for other resources (gosip.ResourceAuthCnfg),
+SPClient retries and throttling handling, hooks and transport settings.
+
+	sp := &gosip.SPClient{AuthCnfg: auth}
+	graph, err := graph.NewGraph(sp)
+	if err != nil {
+		log.Fatal(err)
+	}
+	site, err := graph.Sites().GetByURL("https://contoso.sharepoint.com/sites/test").Get()
+*/
+package graph
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/api"
+)
+
+//go:generate ggen -ent Graph -conf
+
+// RequestConfig - request config, custom headers and context
+type RequestConfig = api.RequestConfig
+
+// ODataMods - OData query modifiers
+type ODataMods = api.ODataMods
+
+// Graph - Microsoft Graph API root struct
+// Always use NewGraph constructor instead of &Graph{}
+type Graph struct {
+	client   *gosip.SPClient
+	config   *RequestConfig
+	endpoint string
+}
+
+// NewGraph creates Graph client from SharePoint client, the auth config should implement gosip.ResourceAuthCnfg.
+// Graph host is resolved from the site cloud, e.g. `graph.microsoft.us` for `*.sharepoint.us` sites.
+func NewGraph(client *gosip.SPClient) (*Graph, error) {
+	auth, ok := client.AuthCnfg.(gosip.ResourceAuthCnfg)
+	// Add-in only tokens are issued for SharePoint hosts only
+	if !ok || client.AuthCnfg.GetStrategy() == "addin" {
+		return nil, fmt.Errorf("%s strategy can't acquire Microsoft Graph tokens", client.AuthCnfg.GetStrategy())
+	}
+	endpoint := graphEndpoint(client.AuthCnfg.GetSiteURL())
+	return newGraph(&gosip.SPClient{
+		Client:        client.Client,
+		AuthCnfg:      gosip.NewResourceAuth(auth, endpoint),
+		RetryPolicies: client.RetryPolicies,
+		Hooks:         client.Hooks,
+		DryRun:        client.DryRun,
+		Journal:       client.Journal,
+	}, endpoint+"/v1.0"), nil
+}
+
+// newGraph creates Graph root for the versioned endpoint
+func newGraph(client *gosip.SPClient, endpoint string) *Graph {
+	return &Graph{client: client, endpoint: endpoint}
+}
+
+// ToURL gets Graph versioned endpoint URL
+func (graph *Graph) ToURL() string {
+	return graph.endpoint
+}
+
+// Beta gets Graph root for the beta endpoint
+func (graph *Graph) Beta() *Graph {
+	endpoint := graph.endpoint[:strings.LastIndex(graph.endpoint, "/")] + "/beta"
+	return &Graph{client: graph.client, config: graph.config, endpoint: endpoint}
+}
+
+// Client gets Graph HTTP client
+func (graph *Graph) Client() *gosip.SPClient {
+	return graph.client
+}
+
+// Sites gets Sites API queryable collection
+func (graph *Graph) Sites() *Sites {
+	return NewSites(graph.client, graph.endpoint+"/sites", graph.config)
+}
+
+// Batch creates JSON batch
+func (graph *Graph) Batch() *Batch {
+	return NewBatch(graph.client, graph.endpoint, graph.config)
+}
+
+// graphEndpoint gets Graph host for the SharePoint site cloud
+func graphEndpoint(siteURL string) string {
+	u, _ := url.Parse(siteURL)
+	host := ""
+	if u != nil {
+		host = strings.ToLower(u.Host)
+	}
+	switch {
+	case strings.HasSuffix(host, ".sharepoint-mil.us"):
+		return "https://dod-graph.microsoft.us"
+	case strings.HasSuffix(host, ".sharepoint.us"):
+		return "https://graph.microsoft.us"
+	case strings.HasSuffix(host, ".sharepoint.cn"):
+		return "https://microsoftgraph.chinacloudapi.cn"
+	case strings.HasSuffix(host, ".sharepoint.de"):
+		return "https://graph.microsoft.de"
+	}
+	return "https://graph.microsoft.com"
+}
+
+// toURL appends OData modifiers to the endpoint
+func toURL(endpoint string, modifiers *ODataMods) string {
+	apiURL, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	query := apiURL.Query()
+	for k, v := range modifiers.Get() {
+		query.Set(k, api.TrimMultiline(v))
+	}
+	apiURL.RawQuery = query.Encode()
+	return apiURL.String()
+}
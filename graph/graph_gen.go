@@ -0,0 +1,9 @@
+// Code generated by `ggen -ent Graph -conf`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (graph *Graph) Conf(config *RequestConfig) *Graph {
+	graph.config = config
+	return graph
+}
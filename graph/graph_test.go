@@ -0,0 +1,331 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth/addin"
+)
+
+type resourceCnfg struct {
+	SiteURL string
+}
+
+func (c *resourceCnfg) ReadConfig(string) error         { return nil }
+func (c *resourceCnfg) ParseConfig([]byte) error        { return nil }
+func (c *resourceCnfg) WriteConfig(string) error        { return nil }
+func (c *resourceCnfg) SetMasterkey(string)             {}
+func (c *resourceCnfg) GetSiteURL() string              { return c.SiteURL }
+func (c *resourceCnfg) GetStrategy() string             { return "resource" }
+func (c *resourceCnfg) GetAuth() (string, int64, error) { return c.GetResourceAuth(c.SiteURL) }
+
+func (c *resourceCnfg) SetAuth(req *http.Request, client *gosip.SPClient) error {
+	token, _, _ := c.GetAuth()
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (c *resourceCnfg) GetResourceAuth(resource string) (string, int64, error) {
+	return "token@" + resource, 0, nil
+}
+
+type anonymousCnfg struct {
+	SiteURL string
+}
+
+func (c *anonymousCnfg) ReadConfig(string) error                      { return nil }
+func (c *anonymousCnfg) ParseConfig([]byte) error                     { return nil }
+func (c *anonymousCnfg) WriteConfig(string) error                     { return nil }
+func (c *anonymousCnfg) SetMasterkey(string)                          {}
+func (c *anonymousCnfg) GetSiteURL() string                           { return c.SiteURL }
+func (c *anonymousCnfg) GetStrategy() string                          { return "anonymous" }
+func (c *anonymousCnfg) GetAuth() (string, int64, error)              { return "", 0, nil }
+func (c *anonymousCnfg) SetAuth(*http.Request, *gosip.SPClient) error { return nil }
+
+func TestNewGraph(t *testing.T) {
+	if _, err := NewGraph(&gosip.SPClient{AuthCnfg: &anonymousCnfg{SiteURL: "https://contoso.sharepoint.com"}}); err == nil {
+		t.Error("error expected for a strategy without resource tokens")
+	}
+	if _, err := NewGraph(&gosip.SPClient{AuthCnfg: &addin.AuthCnfg{SiteURL: "https://contoso.sharepoint.com"}}); err == nil {
+		t.Error("error expected for add-in only strategy")
+	}
+
+	auth := &resourceCnfg{SiteURL: "https://contoso.sharepoint.us/sites/test"}
+	graph, err := NewGraph(&gosip.SPClient{AuthCnfg: auth})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.ToURL() != "https://graph.microsoft.us/v1.0" {
+		t.Errorf("unexpected endpoint: %s", graph.ToURL())
+	}
+	if graph.Beta().ToURL() != "https://graph.microsoft.us/beta" {
+		t.Errorf("unexpected beta endpoint: %s", graph.Beta().ToURL())
+	}
+	token, _, err := graph.Client().AuthCnfg.GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token@https://graph.microsoft.us" {
+		t.Errorf("unexpected token: %s", token)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	var mux sync.Mutex
+	var requests []string
+	batchCalls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		defer mux.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		if r.Header.Get("X-RequestDigest") != "" || strings.Contains(strings.ToLower(r.URL.Path), "contextinfo") {
+			w.WriteHeader(400)
+			return
+		}
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(401)
+			return
+		}
+		base := "http://" + r.Host + "/v1.0"
+		switch r.URL.Path {
+		case "/v1.0/sites/contoso.sharepoint.com:/sites/test:":
+			_, _ = fmt.Fprint(w, `{"id":"contoso.sharepoint.com,1,2","name":"test"}`)
+		case "/v1.0/sites/root/drive/root/children":
+			if r.URL.Query().Get("page") == "" {
+				_, _ = fmt.Fprintf(w, `{"value":[{"id":"1"}],"@odata.nextLink":"%s/sites/root/drive/root/children?page=2"}`, base)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"value":[{"id":"2","folder":{"childCount":1}}]}`)
+		case "/v1.0/sites/root/lists/Tasks/items/delta":
+			if r.URL.Query().Get("page") == "" {
+				_, _ = fmt.Fprintf(w, `{"value":[{"id":"1"}],"@odata.nextLink":"%s/sites/root/lists/Tasks/items/delta?page=2"}`, base)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"value":[{"id":"2","deleted":{"state":"deleted"}}],"@odata.deltaLink":"%s/sites/root/lists/Tasks/items/delta?token=abc"}`, base)
+		case "/v1.0/sites/root/lists/Tasks/items":
+			body, _ := io.ReadAll(r.Body)
+			_, _ = fmt.Fprintf(w, `{"id":"3",%s`, strings.TrimPrefix(string(body), "{"))
+		case "/v1.0/sites/root/drive/root:/Docs/a b.txt:/content":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = fmt.Fprint(w, `content`)
+		case "/v1.0/$batch":
+			batchCalls++
+			req := struct {
+				Requests []*BatchRequest `json:"requests"`
+			}{}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			resp := struct {
+				Responses []*BatchResponse `json:"responses"`
+			}{}
+			for i := len(req.Requests) - 1; i >= 0; i-- {
+				status := 200
+				if req.Requests[i].ID == "2" && batchCalls == 1 {
+					status = 429
+				}
+				resp.Responses = append(resp.Responses, &BatchResponse{
+					ID:      req.Requests[i].ID,
+					Status:  status,
+					Headers: map[string]string{"Retry-After": "0"},
+					Body:    json.RawMessage(fmt.Sprintf(`{"url":%q}`, req.Requests[i].URL)),
+				})
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	auth := &resourceCnfg{SiteURL: "https://contoso.sharepoint.com/sites/test"}
+	client := &gosip.SPClient{AuthCnfg: gosip.NewResourceAuth(auth, server.URL)}
+	graph := newGraph(client, server.URL+"/v1.0")
+
+	t.Run("Site", func(t *testing.T) {
+		site, err := graph.Sites().GetByURL("https://contoso.sharepoint.com/sites/test").Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if site.Data().Name != "test" {
+			t.Errorf("unexpected site: %s", site)
+		}
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		page, err := graph.Sites().Root().Drive().Root().Children().Top(1).Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !page.HasNextPage() {
+			t.Fatal("next page expected")
+		}
+		values, err := page.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var items []*DriveItemInfo
+		if err := json.Unmarshal(mustMarshal(values), &items); err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 2 || items[1].Folder == nil {
+			t.Errorf("unexpected items: %s", mustMarshal(values))
+		}
+	})
+
+	t.Run("Delta", func(t *testing.T) {
+		changes, deltaLink, err := graph.Sites().Root().Lists().GetByID("Tasks").Items().Delta().Changes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 2 {
+			t.Errorf("unexpected changes: %d", len(changes))
+		}
+		if !strings.HasSuffix(deltaLink, "token=abc") {
+			t.Errorf("unexpected delta link: %s", deltaLink)
+		}
+	})
+
+	t.Run("NoDigest", func(t *testing.T) {
+		item, err := graph.Sites().Root().Lists().GetByID("Tasks").Items().Add(map[string]interface{}{"Title": "New"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Data().ID != "3" || item.Data().Fields["Title"] != "New" {
+			t.Errorf("unexpected item: %s", item)
+		}
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		data, err := graph.Sites().Root().Drive().GetItemByPath("/Docs/a b.txt").Download()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "content" {
+			t.Errorf("unexpected content: %s", data)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		batch := graph.Batch()
+		for i := 0; i < 25; i++ {
+			batch.Add("GET", graph.Sites().GetByID(fmt.Sprintf("site%d", i)).ToURL(), nil)
+		}
+		responses, err := batch.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if batchCalls != 3 {
+			t.Errorf("unexpected batch calls: %d", batchCalls)
+		}
+		if len(responses) != 25 {
+			t.Fatalf("unexpected responses: %d", len(responses))
+		}
+		for i, resp := range responses {
+			if resp.Err() != nil {
+				t.Error(resp.Err())
+			}
+			if string(resp.Body) != fmt.Sprintf(`{"url":"/sites/site%d"}`, i) {
+				t.Errorf("unexpected response %s: %s", resp.ID, resp.Body)
+			}
+		}
+	})
+}
+
+func TestBatchChains(t *testing.T) {
+	sent := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Requests []*BatchRequest `json:"requests"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Requests) > batchLimit {
+			w.WriteHeader(400)
+			return
+		}
+		status := map[string]int{}
+		for _, sub := range req.Requests {
+			status[sub.ID] = 200
+		}
+		resp := struct {
+			Responses []*BatchResponse `json:"responses"`
+		}{}
+		for _, sub := range req.Requests {
+			sent[sub.ID]++
+			for _, id := range sub.DependsOn {
+				if _, ok := status[id]; !ok {
+					status[sub.ID] = 400 // dependency is not in the same call
+				} else if status[id] != 200 {
+					status[sub.ID] = 424
+				}
+			}
+			if (sub.ID == "a" && sent[sub.ID] == 1) || sub.ID == "d" {
+				status[sub.ID] = 429
+			}
+			resp.Responses = append(resp.Responses, &BatchResponse{ID: sub.ID, Status: status[sub.ID]})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	auth := &resourceCnfg{SiteURL: "https://contoso.sharepoint.com/sites/test"}
+	graph := newGraph(&gosip.SPClient{AuthCnfg: gosip.NewResourceAuth(auth, server.URL)}, server.URL+"/v1.0")
+
+	batch := graph.Batch()
+	for i := 0; i < 19; i++ {
+		batch.Add("GET", fmt.Sprintf("/sites/site%d", i), nil)
+	}
+	for _, req := range []*BatchRequest{
+		{ID: "a", Method: "POST", URL: "/sites/root/lists"},
+		{ID: "b", Method: "POST", URL: "/sites/root/lists", DependsOn: []string{"a"}},
+		{ID: "c", Method: "POST", URL: "/sites/root/lists"},
+		{ID: "d", Method: "POST", URL: "/sites/root/lists", DependsOn: []string{"c"}},
+	} {
+		if _, err := batch.AddRequest(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := batch.AddRequest(&BatchRequest{ID: "1"}); err == nil {
+		t.Error("duplicate request ID should be rejected")
+	}
+
+	responses, err := batch.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]int{}
+	for _, resp := range responses {
+		statuses[resp.ID] = resp.Status
+	}
+	if statuses["a"] != 200 || statuses["b"] != 200 || sent["a"] != 2 || sent["b"] != 2 {
+		t.Errorf("throttled chain should be re-sent as a whole: %v %v", statuses, sent)
+	}
+	if statuses["d"] != 429 || sent["c"] != 1 || sent["d"] != 1 {
+		t.Errorf("partially executed chain should not be retried: %v %v", statuses, sent)
+	}
+
+	ids := graph.Batch()
+	if _, err := ids.AddRequest(&BatchRequest{ID: "2", Method: "GET", URL: "/sites/root"}); err != nil {
+		t.Fatal(err)
+	}
+	if id := ids.Add("GET", "/sites/root", nil); id != "3" {
+		t.Errorf("generated ID should not collide with custom ones: %s", id)
+	}
+	if _, err := ids.AddRequest(&BatchRequest{ID: "4", Method: "GET", URL: "/sites/root", DependsOn: []string{"x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ids.Execute(); err == nil {
+		t.Error("unknown dependsOn request should fail")
+	}
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+)
+
+// HTTPClient - Graph HTTP methods helper
+type HTTPClient struct {
+	client *gosip.SPClient
+}
+
+// NewHTTPClient creates an instance of Graph HTTP client
+func NewHTTPClient(client *gosip.SPClient) *HTTPClient {
+	return &HTTPClient{client: client}
+}
+
+// Get - generic GET request wrapper
+func (client *HTTPClient) Get(endpoint string, conf *RequestConfig) ([]byte, error) {
+	return client.Execute("GET", endpoint, nil, conf)
+}
+
+// Post - generic POST request wrapper
+func (client *HTTPClient) Post(endpoint string, body io.Reader, conf *RequestConfig) ([]byte, error) {
+	return client.Execute("POST", endpoint, body, conf)
+}
+
+// Patch - generic PATCH request wrapper
+func (client *HTTPClient) Patch(endpoint string, body io.Reader, conf *RequestConfig) ([]byte, error) {
+	return client.Execute("PATCH", endpoint, body, conf)
+}
+
+// Put - generic PUT request wrapper
+func (client *HTTPClient) Put(endpoint string, body io.Reader, conf *RequestConfig) ([]byte, error) {
+	return client.Execute("PUT", endpoint, body, conf)
+}
+
+// Delete - generic DELETE request wrapper
+func (client *HTTPClient) Delete(endpoint string, conf *RequestConfig) ([]byte, error) {
+	return client.Execute("DELETE", endpoint, nil, conf)
+}
+
+// Execute sends a request and reads the response body
+func (client *HTTPClient) Execute(method string, endpoint string, body io.Reader, conf *RequestConfig) ([]byte, error) {
+	resp, err := client.Do(method, endpoint, body, conf)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return io.ReadAll(resp.Body)
+}
+
+// Do sends a request, the response body should be closed by the caller
+func (client *HTTPClient) Do(method string, endpoint string, body io.Reader, conf *RequestConfig) (*http.Response, error) {
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a request: %w", err)
+	}
+	if conf != nil && conf.Context != nil {
+		req = req.WithContext(conf.Context)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// Graph requests don't use SharePoint form digest
+	req.Header.Set("X-Gosip-NoDigest", "true")
+
+	// Apply custom headers last to allow overrides
+	if conf != nil && conf.Headers != nil {
+		for key, value := range conf.Headers {
+			req.Header.Set(key, value)
+		}
+	}
+
+	resp, err := client.client.Execute(req)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		return nil, fmt.Errorf("unable to request api: %w", err)
+	}
+	return resp, nil
+}
+
+// escapePath escapes drive item path segments for path-based addressing
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,21 @@
+// Code generated by `ggen -ent ListItem -conf -mods Select,Expand`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (listItem *ListItem) Conf(config *RequestConfig) *ListItem {
+	listItem.config = config
+	return listItem
+}
+
+// Select adds $select OData modifier
+func (listItem *ListItem) Select(oDataSelect string) *ListItem {
+	listItem.modifiers.AddSelect(oDataSelect)
+	return listItem
+}
+
+// Expand adds $expand OData modifier
+func (listItem *ListItem) Expand(oDataExpand string) *ListItem {
+	listItem.modifiers.AddExpand(oDataExpand)
+	return listItem
+}
@@ -0,0 +1,39 @@
+// Code generated by `ggen -ent ListItems -conf -coll -mods Select,Expand,Filter,Top,OrderBy`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (listItems *ListItems) Conf(config *RequestConfig) *ListItems {
+	listItems.config = config
+	return listItems
+}
+
+// Select adds $select OData modifier
+func (listItems *ListItems) Select(oDataSelect string) *ListItems {
+	listItems.modifiers.AddSelect(oDataSelect)
+	return listItems
+}
+
+// Expand adds $expand OData modifier
+func (listItems *ListItems) Expand(oDataExpand string) *ListItems {
+	listItems.modifiers.AddExpand(oDataExpand)
+	return listItems
+}
+
+// Filter adds $filter OData modifier
+func (listItems *ListItems) Filter(oDataFilter string) *ListItems {
+	listItems.modifiers.AddFilter(oDataFilter)
+	return listItems
+}
+
+// Top adds $top OData modifier
+func (listItems *ListItems) Top(oDataTop int) *ListItems {
+	listItems.modifiers.AddTop(oDataTop)
+	return listItems
+}
+
+// OrderBy adds $orderby OData modifier
+func (listItems *ListItems) OrderBy(oDataOrderBy string, ascending bool) *ListItems {
+	listItems.modifiers.AddOrderBy(oDataOrderBy, ascending)
+	return listItems
+}
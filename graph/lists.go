@@ -0,0 +1,244 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/api"
+)
+
+//go:generate ggen -ent Lists -conf -coll -mods Select,Expand,Filter,Top,OrderBy
+//go:generate ggen -ent List -conf -mods Select,Expand
+//go:generate ggen -ent ListItems -conf -coll -mods Select,Expand,Filter,Top,OrderBy
+//go:generate ggen -ent ListItem -conf -mods Select,Expand
+
+// Lists represent Graph Lists API queryable collection struct
+// Always use NewLists constructor instead of &Lists{}
+type Lists struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// List represents Graph List API queryable object struct
+// Always use NewList constructor instead of &List{}
+type List struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// ListItems represent Graph ListItems API queryable collection struct
+// Always use NewListItems constructor instead of &ListItems{}
+type ListItems struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// ListItem represents Graph ListItem API queryable object struct
+// Always use NewListItem constructor instead of &ListItem{}
+type ListItem struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// ListInfo - list API response payload structure
+type ListInfo struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	DisplayName          string `json:"displayName"`
+	Description          string `json:"description"`
+	WebURL               string `json:"webUrl"`
+	CreatedDateTime      string `json:"createdDateTime"`
+	LastModifiedDateTime string `json:"lastModifiedDateTime"`
+	List                 *struct {
+		Template string `json:"template"`
+		Hidden   bool   `json:"hidden"`
+	} `json:"list,omitempty"`
+}
+
+// ListItemInfo - list item API response payload structure
+type ListItemInfo struct {
+	ID                   string                 `json:"id"`
+	ETag                 string                 `json:"eTag"`
+	WebURL               string                 `json:"webUrl"`
+	CreatedDateTime      string                 `json:"createdDateTime"`
+	LastModifiedDateTime string                 `json:"lastModifiedDateTime"`
+	Fields               map[string]interface{} `json:"fields,omitempty"` // Populated with `Expand("fields")`
+	Deleted              *struct {
+		State string `json:"state"`
+	} `json:"deleted,omitempty"` // Set for removed items in delta query results
+}
+
+// ListResp - list response type with helper processor methods
+type ListResp []byte
+
+// ListItemResp - list item response type with helper processor methods
+type ListItemResp []byte
+
+// NewLists - Lists struct constructor function
+func NewLists(client *gosip.SPClient, endpoint string, config *RequestConfig) *Lists {
+	return &Lists{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (lists *Lists) ToURL() string {
+	return toURL(lists.endpoint, lists.modifiers)
+}
+
+// Get gets lists collection page
+func (lists *Lists) Get() (*Page, error) {
+	return getPage(lists.client, lists.ToURL(), lists.config)
+}
+
+// GetByID gets a list by its ID or name
+func (lists *Lists) GetByID(listID string) *List {
+	return NewList(lists.client, fmt.Sprintf("%s/%s", lists.endpoint, listID), lists.config)
+}
+
+// Add creates a list, e.g. `{"displayName":"Tasks","list":{"template":"genericList"}}`
+func (lists *Lists) Add(body []byte) (ListResp, error) {
+	return NewHTTPClient(lists.client).Post(lists.endpoint, bytes.NewBuffer(body), lists.config)
+}
+
+// NewList - List struct constructor function
+func NewList(client *gosip.SPClient, endpoint string, config *RequestConfig) *List {
+	return &List{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (list *List) ToURL() string {
+	return toURL(list.endpoint, list.modifiers)
+}
+
+// Get gets list data object
+func (list *List) Get() (ListResp, error) {
+	return NewHTTPClient(list.client).Get(list.ToURL(), list.config)
+}
+
+// Update updates list properties
+func (list *List) Update(body []byte) (ListResp, error) {
+	return NewHTTPClient(list.client).Patch(list.endpoint, bytes.NewBuffer(body), list.config)
+}
+
+// Delete deletes the list
+func (list *List) Delete() error {
+	_, err := NewHTTPClient(list.client).Delete(list.endpoint, list.config)
+	return err
+}
+
+// Items gets list items API queryable collection
+func (list *List) Items() *ListItems {
+	return NewListItems(list.client, list.endpoint+"/items", list.config)
+}
+
+// Drive gets the document library drive
+func (list *List) Drive() *Drive {
+	return NewDrive(list.client, list.endpoint+"/drive", list.config)
+}
+
+// NewListItems - ListItems struct constructor function
+func NewListItems(client *gosip.SPClient, endpoint string, config *RequestConfig) *ListItems {
+	return &ListItems{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (items *ListItems) ToURL() string {
+	return toURL(items.endpoint, items.modifiers)
+}
+
+// Get gets list items collection page
+func (items *ListItems) Get() (*Page, error) {
+	return getPage(items.client, items.ToURL(), items.config)
+}
+
+// GetByID gets a list item by its ID
+func (items *ListItems) GetByID(itemID string) *ListItem {
+	return NewListItem(items.client, fmt.Sprintf("%s/%s", items.endpoint, itemID), items.config)
+}
+
+// Add creates a list item with the fields values
+func (items *ListItems) Add(fields map[string]interface{}) (ListItemResp, error) {
+	body, err := json.Marshal(map[string]interface{}{"fields": fields})
+	if err != nil {
+		return nil, err
+	}
+	return NewHTTPClient(items.client).Post(items.endpoint, bytes.NewBuffer(body), items.config)
+}
+
+// Delta gets list items changes delta query
+func (items *ListItems) Delta() *Delta {
+	return NewDelta(items.client, items.endpoint+"/delta", items.config)
+}
+
+// NewListItem - ListItem struct constructor function
+func NewListItem(client *gosip.SPClient, endpoint string, config *RequestConfig) *ListItem {
+	return &ListItem{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (item *ListItem) ToURL() string {
+	return toURL(item.endpoint, item.modifiers)
+}
+
+// Get gets list item data object
+func (item *ListItem) Get() (ListItemResp, error) {
+	return NewHTTPClient(item.client).Get(item.ToURL(), item.config)
+}
+
+// UpdateFields updates list item fields values
+func (item *ListItem) UpdateFields(fields map[string]interface{}) ([]byte, error) {
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return NewHTTPClient(item.client).Patch(item.endpoint+"/fields", bytes.NewBuffer(body), item.config)
+}
+
+// Delete deletes the list item
+func (item *ListItem) Delete() error {
+	_, err := NewHTTPClient(item.client).Delete(item.endpoint, item.config)
+	return err
+}
+
+// Data response helper
+func (listResp *ListResp) Data() *ListInfo {
+	data := &ListInfo{}
+	_ = json.Unmarshal(*listResp, data)
+	return data
+}
+
+// Data response helper
+func (listItemResp *ListItemResp) Data() *ListItemInfo {
+	data := &ListItemInfo{}
+	_ = json.Unmarshal(*listItemResp, data)
+	return data
+}
@@ -0,0 +1,39 @@
+// Code generated by `ggen -ent Lists -conf -coll -mods Select,Expand,Filter,Top,OrderBy`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (lists *Lists) Conf(config *RequestConfig) *Lists {
+	lists.config = config
+	return lists
+}
+
+// Select adds $select OData modifier
+func (lists *Lists) Select(oDataSelect string) *Lists {
+	lists.modifiers.AddSelect(oDataSelect)
+	return lists
+}
+
+// Expand adds $expand OData modifier
+func (lists *Lists) Expand(oDataExpand string) *Lists {
+	lists.modifiers.AddExpand(oDataExpand)
+	return lists
+}
+
+// Filter adds $filter OData modifier
+func (lists *Lists) Filter(oDataFilter string) *Lists {
+	lists.modifiers.AddFilter(oDataFilter)
+	return lists
+}
+
+// Top adds $top OData modifier
+func (lists *Lists) Top(oDataTop int) *Lists {
+	lists.modifiers.AddTop(oDataTop)
+	return lists
+}
+
+// OrderBy adds $orderby OData modifier
+func (lists *Lists) OrderBy(oDataOrderBy string, ascending bool) *Lists {
+	lists.modifiers.AddOrderBy(oDataOrderBy, ascending)
+	return lists
+}
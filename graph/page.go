@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/koltyakov/gosip"
+)
+
+// Page - Graph collection response page
+type Page struct {
+	Value     []json.RawMessage `json:"value"`
+	NextLink  string            `json:"@odata.nextLink,omitempty"`  // Next page URL, empty for the last page
+	DeltaLink string            `json:"@odata.deltaLink,omitempty"` // Delta query state, received with the last delta page
+
+	client *gosip.SPClient
+	config *RequestConfig
+}
+
+// getPage requests a collection page
+func getPage(client *gosip.SPClient, endpoint string, config *RequestConfig) (*Page, error) {
+	data, err := NewHTTPClient(client).Get(endpoint, config)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{client: client, config: config}
+	if err := json.Unmarshal(data, page); err != nil {
+		return nil, fmt.Errorf("can't parse collection response: %w", err)
+	}
+	return page, nil
+}
+
+// HasNextPage returns true if next page exists
+func (page *Page) HasNextPage() bool {
+	return page.NextLink != ""
+}
+
+// GetNextPage gets next collection page
+func (page *Page) GetNextPage() (*Page, error) {
+	if page.NextLink == "" {
+		return nil, fmt.Errorf("unable to get next page")
+	}
+	return getPage(page.client, page.NextLink, page.config)
+}
+
+// All gets values of the page and all the next pages
+func (page *Page) All() ([]json.RawMessage, error) {
+	values := page.Value
+	for p := page; p.HasNextPage(); {
+		next, err := p.GetNextPage()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, next.Value...)
+		p = next
+	}
+	return values, nil
+}
+
+// Unmarshal decodes the page values into a slice, e.g. *[]DriveItemInfo
+func (page *Page) Unmarshal(v interface{}) error {
+	data, err := json.Marshal(page.Value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+//go:generate ggen -ent Delta -conf
+
+// Delta - delta query, tracks changes since the previous query
+// Always use NewDelta constructor instead of &Delta{}
+type Delta struct {
+	client   *gosip.SPClient
+	config   *RequestConfig
+	endpoint string
+}
+
+// NewDelta - Delta struct constructor function
+func NewDelta(client *gosip.SPClient, endpoint string, config *RequestConfig) *Delta {
+	return &Delta{client: client, endpoint: endpoint, config: config}
+}
+
+// ToURL gets delta query URL
+func (delta *Delta) ToURL() string {
+	return delta.endpoint
+}
+
+// FromLink resumes delta query from a delta link saved after the previous query
+func (delta *Delta) FromLink(deltaLink string) *Delta {
+	if deltaLink != "" {
+		delta.endpoint = deltaLink
+	}
+	return delta
+}
+
+// Iterate calls the handler for each page of changes, returns delta link for the next query
+func (delta *Delta) Iterate(handler func(changes []json.RawMessage) error) (string, error) {
+	page, err := getPage(delta.client, delta.endpoint, delta.config)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if err := handler(page.Value); err != nil {
+			return "", err
+		}
+		if !page.HasNextPage() {
+			break
+		}
+		if page, err = page.GetNextPage(); err != nil {
+			return "", err
+		}
+	}
+	if page.DeltaLink == "" {
+		return "", fmt.Errorf("delta link is not received")
+	}
+	return page.DeltaLink, nil
+}
+
+// Changes gets all changes, returns delta link for the next query
+func (delta *Delta) Changes() ([]json.RawMessage, string, error) {
+	var changes []json.RawMessage
+	deltaLink, err := delta.Iterate(func(page []json.RawMessage) error {
+		changes = append(changes, page...)
+		return nil
+	})
+	return changes, deltaLink, err
+}
+
+// Latest gets delta link for the current state skipping existing items
+func (delta *Delta) Latest() (string, error) {
+	u, err := url.Parse(delta.endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", "latest")
+	u.RawQuery = query.Encode()
+	return NewDelta(delta.client, u.String(), delta.config).Iterate(func([]json.RawMessage) error { return nil })
+}
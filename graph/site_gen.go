@@ -0,0 +1,21 @@
+// Code generated by `ggen -ent Site -conf -mods Select,Expand`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (site *Site) Conf(config *RequestConfig) *Site {
+	site.config = config
+	return site
+}
+
+// Select adds $select OData modifier
+func (site *Site) Select(oDataSelect string) *Site {
+	site.modifiers.AddSelect(oDataSelect)
+	return site
+}
+
+// Expand adds $expand OData modifier
+func (site *Site) Expand(oDataExpand string) *Site {
+	site.modifiers.AddExpand(oDataExpand)
+	return site
+}
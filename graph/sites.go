@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/api"
+)
+
+//go:generate ggen -ent Sites -conf -coll -mods Select,Filter,Top
+//go:generate ggen -ent Site -conf -mods Select,Expand
+
+// Sites represent Graph Sites API queryable collection struct
+// Always use NewSites constructor instead of &Sites{}
+type Sites struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// Site represents Graph Site API queryable object struct
+// Always use NewSite constructor instead of &Site{}
+type Site struct {
+	client    *gosip.SPClient
+	config    *RequestConfig
+	endpoint  string
+	modifiers *ODataMods
+}
+
+// SiteInfo - site API response payload structure
+type SiteInfo struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	DisplayName          string `json:"displayName"`
+	Description          string `json:"description"`
+	WebURL               string `json:"webUrl"`
+	CreatedDateTime      string `json:"createdDateTime"`
+	LastModifiedDateTime string `json:"lastModifiedDateTime"`
+}
+
+// SiteResp - site response type with helper processor methods
+type SiteResp []byte
+
+// NewSites - Sites struct constructor function
+func NewSites(client *gosip.SPClient, endpoint string, config *RequestConfig) *Sites {
+	return &Sites{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (sites *Sites) ToURL() string {
+	return toURL(sites.endpoint, sites.modifiers)
+}
+
+// Get gets sites collection page
+func (sites *Sites) Get() (*Page, error) {
+	return getPage(sites.client, sites.ToURL(), sites.config)
+}
+
+// Search adds sites search query, "*" lists all sites
+func (sites *Sites) Search(query string) *Sites {
+	sites.modifiers.Get()["search"] = query
+	return sites
+}
+
+// Root gets tenant root site
+func (sites *Sites) Root() *Site {
+	return NewSite(sites.client, sites.endpoint+"/root", sites.config)
+}
+
+// GetByID gets a site by its ID, e.g. "contoso.sharepoint.com,{site-guid},{web-guid}"
+func (sites *Sites) GetByID(siteID string) *Site {
+	return NewSite(sites.client, fmt.Sprintf("%s/%s", sites.endpoint, siteID), sites.config)
+}
+
+// GetByPath gets a site by host name and server-relative path, e.g. ("contoso.sharepoint.com", "/sites/test")
+func (sites *Sites) GetByPath(hostname string, path string) *Site {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return NewSite(sites.client, fmt.Sprintf("%s/%s", sites.endpoint, hostname), sites.config)
+	}
+	return NewSite(sites.client, fmt.Sprintf("%s/%s:/%s:", sites.endpoint, hostname, escapePath(path)), sites.config)
+}
+
+// GetByURL gets a site by its absolute URL
+func (sites *Sites) GetByURL(siteURL string) *Site {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return sites.GetByPath(siteURL, "")
+	}
+	return sites.GetByPath(u.Host, u.Path)
+}
+
+// Delta gets sites delta query
+func (sites *Sites) Delta() *Delta {
+	return NewDelta(sites.client, sites.endpoint+"/delta", sites.config)
+}
+
+// NewSite - Site struct constructor function
+func NewSite(client *gosip.SPClient, endpoint string, config *RequestConfig) *Site {
+	return &Site{
+		client:    client,
+		endpoint:  endpoint,
+		config:    config,
+		modifiers: api.NewODataMods(),
+	}
+}
+
+// ToURL gets endpoint with modificators raw URL
+func (site *Site) ToURL() string {
+	return toURL(site.endpoint, site.modifiers)
+}
+
+// Get gets site data object
+func (site *Site) Get() (SiteResp, error) {
+	return NewHTTPClient(site.client).Get(site.ToURL(), site.config)
+}
+
+// Drive gets site default document library drive
+func (site *Site) Drive() *Drive {
+	return NewDrive(site.client, site.endpoint+"/drive", site.config)
+}
+
+// Drives gets site document library drives
+func (site *Site) Drives() *Drives {
+	return NewDrives(site.client, site.endpoint+"/drives", site.config)
+}
+
+// Lists gets site lists
+func (site *Site) Lists() *Lists {
+	return NewLists(site.client, site.endpoint+"/lists", site.config)
+}
+
+// Sites gets subsites
+func (site *Site) Sites() *Sites {
+	return NewSites(site.client, site.endpoint+"/sites", site.config)
+}
+
+// Data response helper
+func (siteResp *SiteResp) Data() *SiteInfo {
+	data := &SiteInfo{}
+	_ = json.Unmarshal(*siteResp, data)
+	return data
+}
@@ -0,0 +1,27 @@
+// Code generated by `ggen -ent Sites -conf -coll -mods Select,Filter,Top`; DO NOT EDIT.
+
+package graph
+
+// Conf receives custom request config definition, e.g. custom headers, custom OData mod
+func (sites *Sites) Conf(config *RequestConfig) *Sites {
+	sites.config = config
+	return sites
+}
+
+// Select adds $select OData modifier
+func (sites *Sites) Select(oDataSelect string) *Sites {
+	sites.modifiers.AddSelect(oDataSelect)
+	return sites
+}
+
+// Filter adds $filter OData modifier
+func (sites *Sites) Filter(oDataFilter string) *Sites {
+	sites.modifiers.AddFilter(oDataFilter)
+	return sites
+}
+
+// Top adds $top OData modifier
+func (sites *Sites) Top(oDataTop int) *Sites {
+	sites.modifiers.AddTop(oDataTop)
+	return sites
+}
@@ -0,0 +1,54 @@
+package gosip
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Journaler records successful mutating requests, see journal package for a hash-chained implementation
+type Journaler interface {
+	Record(event *JournalEvent) error
+}
+
+// JournalEvent - successful mutating request details
+type JournalEvent struct {
+	AuthCnfg     AuthCnfg       // Client auth config
+	Request      *http.Request  // Sent request, with authorization headers applied
+	Method       string         // Effective method, including X-HTTP-Method tunnelling
+	RequestBody  []byte         // Request payload
+	Response     *http.Response // Received response, the body is already read to ResponseBody
+	ResponseBody []byte         // Response payload
+	Time         time.Time      // Response time
+}
+
+// record passes successful mutating request to the journal,
+// journal errors are reported with OnError hook and don't fail the request as the change is already made
+func (c *SPClient) record(req *http.Request, reqBody []byte, resp *http.Response, startAt time.Time) {
+	if c.Journal == nil || !mutates(req, reqBody) {
+		return
+	}
+	var respBody []byte
+	if resp.Body != nil {
+		data, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			c.onError(req, startAt, resp.StatusCode, err)
+		}
+		respBody = data
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	err := c.Journal.Record(&JournalEvent{
+		AuthCnfg:     c.AuthCnfg,
+		Request:      req,
+		Method:       effectiveMethod(req),
+		RequestBody:  reqBody,
+		Response:     resp,
+		ResponseBody: respBody,
+		Time:         time.Now(),
+	})
+	if err != nil {
+		c.onError(req, startAt, resp.StatusCode, err)
+	}
+}
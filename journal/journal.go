@@ -0,0 +1,274 @@
+/*
+Package journal implements tamper-evident audit journal of changes made with gosip clients
+
+Each successful mutating request is appended as a JSON line with the principal, operation,
+target URL, payload hash, response entity URI and correlation ID.
+Entries are hash-chained, each entry hash covers the previous entry hash,
+so modified, removed or reordered lines are detected with Verify.
+
+	j, err := journal.Open("./audit.jsonl")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer j.Close()
+	client := &gosip.SPClient{AuthCnfg: auth, Journal: j}
+*/
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/api"
+	"github.com/koltyakov/gosip/diag/jwt"
+)
+
+// Entry - journal line
+type Entry struct {
+	Seq           int64     `json:"seq"`                     // Entry number starting from 1
+	Time          time.Time `json:"time"`                    // Response time
+	Principal     Principal `json:"principal"`               // Identity the change is made with
+	Operation     string    `json:"operation"`               // Effective method, e.g. POST, MERGE, DELETE
+	URL           string    `json:"url"`                     // Target URL
+	Status        int       `json:"status"`                  // Response status code
+	PayloadHash   string    `json:"payloadHash,omitempty"`   // SHA-256 of the request payload, hex
+	EntityURI     string    `json:"entityUri,omitempty"`     // Created or changed entity URI from the response
+	CorrelationID string    `json:"correlationId,omitempty"` // SharePoint or Graph request ID
+	PrevHash      string    `json:"prevHash"`                // Previous entry hash, empty for the first entry
+	Hash          string    `json:"hash"`                    // Entry hash, SHA-256 of the entry with empty hash, hex
+}
+
+// Principal - identity the change is made with
+type Principal struct {
+	Strategy string `json:"strategy"`           // Auth strategy name
+	Identity string `json:"identity,omitempty"` // User name or "app:{clientId}" for app-only auth
+	TenantID string `json:"tenantId,omitempty"` // Tenant ID, for token-based strategies
+}
+
+// Sink receives journal lines
+type Sink interface {
+	Write(line []byte) error
+}
+
+// Journal - hash-chained journal of changes, implements gosip.Journaler
+// Always use New or Open constructors instead of &Journal{}
+type Journal struct {
+	mux   sync.Mutex
+	sinks []Sink
+	seq   int64
+	head  string
+}
+
+// New creates journal writing to the sinks, the chain starts from scratch
+func New(sinks ...Sink) *Journal {
+	return &Journal{sinks: sinks}
+}
+
+// Open opens file journal, existing journal is verified and the chain is continued
+func Open(path string) (*Journal, error) {
+	last, err := VerifyFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sink, err := NewFileSink(path)
+	if err != nil {
+		return nil, err
+	}
+	j := New(sink)
+	if last != nil {
+		j.seq = last.Seq
+		j.head = last.Hash
+	}
+	return j, nil
+}
+
+// Record appends successful mutating request entry, satisfies gosip.Journaler interface
+func (j *Journal) Record(event *gosip.JournalEvent) error {
+	entry := &Entry{
+		Time:          event.Time.UTC(),
+		Principal:     principalOf(event),
+		Operation:     event.Method,
+		URL:           event.Request.URL.String(),
+		Status:        event.Response.StatusCode,
+		EntityURI:     api.ExtractEntityURI(event.ResponseBody),
+		CorrelationID: correlationID(event),
+	}
+	if len(event.RequestBody) > 0 {
+		sum := sha256.Sum256(event.RequestBody)
+		entry.PayloadHash = hex.EncodeToString(sum[:])
+	}
+	if entry.EntityURI == "" {
+		entry.EntityURI = event.Response.Header.Get("Location")
+	}
+	return j.Append(entry)
+}
+
+// Append chains and writes the entry to all sinks, the chain advances when at least one sink accepts the entry
+func (j *Journal) Append(entry *Entry) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	entry.Seq = j.seq + 1
+	entry.PrevHash = j.head
+	entry.Hash = ""
+	hash, err := entryHash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	var errs []string
+	for _, sink := range j.sinks {
+		if err := sink.Write(line); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	// Once a sink has the entry, the chain continues from it, failed sinks get a gap which Verify reports
+	if len(errs) < len(j.sinks) || len(j.sinks) == 0 {
+		j.seq = entry.Seq
+		j.head = entry.Hash
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("can't write journal entry %d to %d of %d sinks: %s", entry.Seq, len(errs), len(j.sinks), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Close closes sinks which implement io.Closer
+func (j *Journal) Close() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	var errs []string
+	for _, sink := range j.sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("can't close journal: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Verify checks the journal chain, returns the last entry, nil for an empty journal
+func Verify(r io.Reader) (*Entry, error) {
+	var last *Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		entry := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return last, fmt.Errorf("line %d: can't parse entry: %w", line, err)
+		}
+		prevHash, seq := "", int64(1)
+		if last != nil {
+			prevHash, seq = last.Hash, last.Seq+1
+		}
+		if entry.Seq != seq {
+			return last, fmt.Errorf("line %d: entry %d is expected, got %d", line, seq, entry.Seq)
+		}
+		if entry.PrevHash != prevHash {
+			return last, fmt.Errorf("line %d: chain is broken, previous hash doesn't match", line)
+		}
+		hash := entry.Hash
+		entry.Hash = ""
+		expected, err := entryHash(entry)
+		if err != nil {
+			return last, err
+		}
+		if hash != expected {
+			return last, fmt.Errorf("line %d: entry hash doesn't match its content", line)
+		}
+		entry.Hash = hash
+		last = entry
+	}
+	return last, scanner.Err()
+}
+
+// VerifyFile checks the file journal chain, returns the last entry, nil for an empty journal
+func VerifyFile(path string) (*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return Verify(f)
+}
+
+// entryHash gets entry hash, the entry Hash should be empty
+func entryHash(entry *Entry) (string, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// principalOf resolves the identity from the request access token or the auth config
+func principalOf(event *gosip.JournalEvent) Principal {
+	p := Principal{Strategy: event.AuthCnfg.GetStrategy()}
+	authorization := event.Request.Header.Get("Authorization")
+	if token := strings.TrimPrefix(authorization, "Bearer "); token != authorization {
+		if info, err := jwt.Decode(token); err == nil {
+			p.TenantID = info.TenantID
+			p.Identity = info.User
+			if p.Identity == "" && info.AppID != "" {
+				p.Identity = "app:" + info.AppID
+			}
+			return p
+		}
+	}
+	if username := stringField(event.AuthCnfg, "Username"); username != "" {
+		p.Identity = username
+		if domain := stringField(event.AuthCnfg, "Domain"); domain != "" && !strings.Contains(username, "@") {
+			p.Identity = domain + "\\" + username
+		}
+	} else if clientID := stringField(event.AuthCnfg, "ClientID"); clientID != "" {
+		p.Identity = "app:" + clientID
+	}
+	return p
+}
+
+// correlationID gets SharePoint or Graph response request ID
+func correlationID(event *gosip.JournalEvent) string {
+	for _, header := range []string{"SPRequestGuid", "request-id", "X-Ms-Request-Id"} {
+		if id := event.Response.Header.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// stringField gets auth config string field value by name
+func stringField(v interface{}, name string) string {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+	f := value.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
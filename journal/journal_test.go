@@ -0,0 +1,170 @@
+package journal
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/gosip"
+)
+
+type userCnfg struct {
+	SiteURL  string
+	Username string
+	Domain   string
+}
+
+func (c *userCnfg) ReadConfig(string) error                      { return nil }
+func (c *userCnfg) ParseConfig([]byte) error                     { return nil }
+func (c *userCnfg) GetSiteURL() string                           { return c.SiteURL }
+func (c *userCnfg) GetStrategy() string                          { return "ntlm" }
+func (c *userCnfg) GetAuth() (string, int64, error)              { return "", 0, nil }
+func (c *userCnfg) SetAuth(*http.Request, *gosip.SPClient) error { return nil }
+
+func TestJournal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("SPRequestGuid", "guid-"+r.Method)
+		if strings.EqualFold(r.URL.Path, "/_api/ContextInfo") {
+			_, _ = fmt.Fprint(w, `{"d":{"GetContextWebInformation":{"FormDigestValue":"FAKE","FormDigestTimeoutSeconds":120}}}`)
+			return
+		}
+		if r.Method == "POST" && r.Header.Get("X-HTTP-Method") == "" {
+			_, _ = fmt.Fprintf(w, `{"d":{"__metadata":{"id":"%s/_api/Web/Lists(guid'1')/Items(1)"}}}`, "http://"+r.Host)
+			return
+		}
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	var mirror bytes.Buffer
+
+	execute := func(j *Journal, method string, body string, headers map[string]string) {
+		t.Helper()
+		client := &gosip.SPClient{
+			AuthCnfg: &userCnfg{SiteURL: server.URL, Username: "user", Domain: "contoso"},
+			Journal:  j,
+		}
+		req, _ := http.NewRequest(method, server.URL+"/_api/web/lists/getByTitle('Tasks')/items", strings.NewReader(body))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	j, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.sinks = append(j.sinks, NewWriterSink(&mirror))
+	execute(j, "GET", "", nil)
+	execute(j, "POST", `{"Title":"New"}`, nil)
+	execute(j, "POST", `{"Title":"Updated"}`, map[string]string{"X-HTTP-Method": "MERGE"})
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := VerifyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || last.Seq != 2 || last.Operation != "MERGE" {
+		t.Fatalf("unexpected last entry: %+v", last)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !bytes.Equal(data, mirror.Bytes()) {
+		t.Error("writer sink should receive the same lines")
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, expected := range []string{
+		`"principal":{"strategy":"ntlm","identity":"contoso\\user"}`,
+		`"operation":"POST"`,
+		`"entityUri":"` + server.URL + `/_api/Web/Lists(guid'1')/Items(1)"`,
+		`"correlationId":"guid-POST"`,
+		`"payloadHash":"`,
+	} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("%s is expected in %s", expected, lines[0])
+		}
+	}
+
+	t.Run("Resume", func(t *testing.T) {
+		j, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		execute(j, "DELETE", "", nil)
+		_ = j.Close()
+		last, err := VerifyFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last.Seq != 3 || last.Operation != "DELETE" {
+			t.Errorf("unexpected last entry: %+v", last)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		data, _ := os.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+		modified := strings.Replace(lines[1], `"operation":"MERGE"`, `"operation":"POST"`, 1)
+		if _, err := Verify(strings.NewReader(strings.Join([]string{lines[0], modified, lines[2]}, "\n"))); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("modified entry should be detected, got %v", err)
+		}
+
+		if _, err := Verify(strings.NewReader(strings.Join([]string{lines[0], lines[2]}, "\n"))); err == nil {
+			t.Error("removed entry should be detected")
+		}
+
+		if _, err := Open(filepath.Join(t.TempDir(), "missing", "audit.jsonl")); err == nil {
+			t.Error("error is expected for a missing folder")
+		}
+	})
+}
+
+type failingSink struct{ failures int }
+
+func (s *failingSink) Write([]byte) error {
+	if s.failures > 0 {
+		s.failures--
+		return fmt.Errorf("sink is unavailable")
+	}
+	return nil
+}
+
+func TestSinkFailure(t *testing.T) {
+	var buf bytes.Buffer
+	failing := &failingSink{failures: 1}
+	j := New(failing, NewWriterSink(&buf))
+
+	if err := j.Append(&Entry{Operation: "POST"}); err == nil || !strings.Contains(err.Error(), "1 of 2 sinks") {
+		t.Errorf("sink error should be returned, got %v", err)
+	}
+	if err := j.Append(&Entry{Operation: "DELETE"}); err != nil {
+		t.Fatal(err)
+	}
+	last, err := Verify(&buf)
+	if err != nil {
+		t.Fatalf("accepted entries should keep the chain: %s", err)
+	}
+	if last.Seq != 2 {
+		t.Errorf("unexpected last entry: %+v", last)
+	}
+
+	failed := New(&failingSink{failures: 1})
+	_ = failed.Append(&Entry{Operation: "POST"})
+	if failed.seq != 0 || failed.head != "" {
+		t.Error("the chain should not advance when no sink accepted the entry")
+	}
+}
@@ -0,0 +1,58 @@
+package journal
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink writes journal lines to io.Writer, e.g. os.Stdout or a network connection
+type WriterSink struct {
+	mux sync.Mutex
+	w   io.Writer
+}
+
+// NewWriterSink creates io.Writer sink
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write writes journal line
+func (s *WriterSink) Write(line []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err := s.w.Write(line)
+	return err
+}
+
+// FileSink appends journal lines to a file, each line is synced to disk
+type FileSink struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens or creates file sink, use Open to continue an existing file journal chain
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write appends journal line
+func (s *FileSink) Write(line []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, err := s.file.Write(line); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.file.Close()
+}
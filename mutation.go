@@ -0,0 +1,102 @@
+package gosip
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// readOnlyPosts are REST endpoints queried with POST without side effects,
+// they are neither intercepted in dry-run mode nor journaled
+var readOnlyPosts = []string{
+	"/_api/contextinfo",
+	"/getitems",
+	"/renderlistdataasstream",
+	"/renderlistdata",
+	"/getchanges",
+	"/_api/search/postquery",
+}
+
+// effectiveMethod gets request method including X-HTTP-Method tunnelling, e.g. DELETE for POST with X-HTTP-Method: DELETE
+func effectiveMethod(req *http.Request) string {
+	method := strings.ToUpper(req.Method)
+	if tunnelled := req.Header.Get("X-HTTP-Method"); tunnelled != "" {
+		method = strings.ToUpper(tunnelled)
+	}
+	return method
+}
+
+// isReadMethod checks if the method doesn't change data
+func isReadMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS"
+}
+
+// mutates checks if the request changes data, the body is only needed for CSOM requests
+func mutates(req *http.Request, body []byte) bool {
+	method := effectiveMethod(req)
+	if isReadMethod(method) {
+		return false
+	}
+	path := strings.ToLower(req.URL.Path)
+	for _, readOnly := range readOnlyPosts {
+		if method == "POST" && strings.HasSuffix(path, readOnly) {
+			return false
+		}
+	}
+	if isCSOM(req) {
+		return csomMutates(body)
+	}
+	if requests, ok := jsonBatch(req, body); ok {
+		for _, r := range requests {
+			if !isReadMethod(strings.ToUpper(r.Method)) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// jsonBatchRequest - Graph JSON batch sub-request fields used to detect and plan mutations
+type jsonBatchRequest struct {
+	ID     string          `json:"id"`
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// jsonBatch gets Graph JSON batch sub-requests, ok is false for other requests
+func jsonBatch(req *http.Request, body []byte) ([]*jsonBatchRequest, bool) {
+	if req.Method != "POST" || !strings.HasSuffix(req.URL.Path, "/$batch") {
+		return nil, false
+	}
+	batch := struct {
+		Requests []*jsonBatchRequest `json:"requests"`
+	}{}
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch.Requests) == 0 {
+		return nil, false
+	}
+	return batch.Requests, true
+}
+
+// isCSOM checks if the request is CSOM ProcessQuery call
+func isCSOM(req *http.Request) bool {
+	return strings.HasSuffix(strings.ToLower(req.URL.Path), "/_vti_bin/client.svc/processquery")
+}
+
+// csomMutates checks if CSOM package actions change data, queries only read objects
+func csomMutates(body []byte) bool {
+	actions := string(body)
+	if start := strings.Index(actions, "<Actions>"); start != -1 {
+		actions = actions[start:]
+		if end := strings.Index(actions, "</Actions>"); end != -1 {
+			actions = actions[:end]
+		}
+	}
+	for _, action := range []string{"<Method ", "<StaticMethod ", "<SetProperty ", "<SetStaticProperty "} {
+		if strings.Contains(actions, action) {
+			return true
+		}
+	}
+	return false
+}
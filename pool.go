@@ -0,0 +1,237 @@
+package gosip
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPoolIdleTimeout is a default time after which unused pool clients are evicted
+const DefaultPoolIdleTimeout = 10 * time.Minute
+
+// PoolOptions - client pool settings
+type PoolOptions struct {
+	MaxConnsPerSite int           // Limits connections per site, 0 is unlimited
+	IdleTimeout     time.Duration // Evicts clients not requested for the duration, DefaultPoolIdleTimeout by default
+
+	// NewAuth creates auth config for sites on another host than the base config site,
+	// by default resource auth configs are shared, other configs are copied with SiteURL replaced (optional)
+	NewAuth func(siteURL string) (AuthCnfg, error)
+
+	RetryPolicies map[int]int   // Retry policies for pool clients (optional)
+	Hooks         *HookHandlers // Hook handlers for pool clients (optional)
+	DryRun        *DryRun       // Dry-run plan shared by pool clients (optional)
+	Journal       Journaler     // Journal shared by pool clients (optional)
+}
+
+// ClientPool - clients for multiple sites sharing one authentication context
+//
+// Sites on the same host reuse the base auth config and its cached credentials,
+// e.g. AAD tokens and auth cookies which are host-scoped, while form digests are kept per site.
+// Sites on other hosts, e.g. `contoso-my.sharepoint.com`, get their own auth config,
+// or a token for the host when the base config implements ResourceAuthCnfg.
+// NTLM auth is connection-bound, so each site gets its own config.
+type ClientPool struct {
+	auth    AuthCnfg
+	options PoolOptions
+
+	mux     sync.Mutex
+	auths   map[string]AuthCnfg // auth configs by host
+	clients map[string]*poolEntry
+}
+
+type poolEntry struct {
+	client   *SPClient
+	lastUsed time.Time
+}
+
+// NewClientPool creates a pool of clients sharing base auth config
+func NewClientPool(auth AuthCnfg, options *PoolOptions) *ClientPool {
+	p := &ClientPool{
+		auth:    auth,
+		auths:   map[string]AuthCnfg{},
+		clients: map[string]*poolEntry{},
+	}
+	if options != nil {
+		p.options = *options
+	}
+	if p.options.IdleTimeout == 0 {
+		p.options.IdleTimeout = DefaultPoolIdleTimeout
+	}
+	if u, err := url.Parse(auth.GetSiteURL()); err == nil {
+		p.auths[strings.ToLower(u.Host)] = auth
+	}
+	return p
+}
+
+// Get gets or creates a client for the site URL
+func (p *ClientPool) Get(siteURL string) (*SPClient, error) {
+	u, err := url.Parse(strings.TrimRight(siteURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("absolute site URL is expected: %s", siteURL)
+	}
+	u.Host = strings.ToLower(u.Host)
+	key := u.String()
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	now := time.Now()
+	p.evict(now)
+
+	if entry, ok := p.clients[key]; ok {
+		entry.lastUsed = now
+		return entry.client, nil
+	}
+
+	auth, err := p.hostAuth(u.Host, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var transportCnfg *TransportConfig
+	if c, ok := auth.(TransportConfigurer); ok {
+		transportCnfg = c.GetTransportConfig()
+	}
+	transport, err := transportCnfg.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	if p.options.MaxConnsPerSite > 0 {
+		transport.MaxConnsPerHost = p.options.MaxConnsPerSite
+		transport.MaxIdleConnsPerHost = p.options.MaxConnsPerSite
+	}
+	var timeout time.Duration
+	if transportCnfg != nil {
+		if timeout, err = parseTimeout("timeout", transportCnfg.Timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &SPClient{
+		Client:        http.Client{Transport: transport, Timeout: timeout},
+		AuthCnfg:      &siteAuth{AuthCnfg: auth, siteURL: key},
+		RetryPolicies: p.options.RetryPolicies,
+		Hooks:         p.options.Hooks,
+		DryRun:        p.options.DryRun,
+		Journal:       p.options.Journal,
+	}
+	p.clients[key] = &poolEntry{client: client, lastUsed: now}
+	return client, nil
+}
+
+// Len gets the number of pooled clients
+func (p *ClientPool) Len() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return len(p.clients)
+}
+
+// Close evicts all clients and closes their idle connections
+func (p *ClientPool) Close() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	for key, entry := range p.clients {
+		entry.client.CloseIdleConnections()
+		delete(p.clients, key)
+	}
+}
+
+// evict removes clients which are idle longer than the timeout
+func (p *ClientPool) evict(now time.Time) {
+	for key, entry := range p.clients {
+		if now.Sub(entry.lastUsed) > p.options.IdleTimeout {
+			entry.client.CloseIdleConnections()
+			delete(p.clients, key)
+		}
+	}
+}
+
+// hostAuth gets auth config shared by the host sites
+func (p *ClientPool) hostAuth(host string, siteURL string) (AuthCnfg, error) {
+	// NTLM negotiator is bound to a client transport and can't be shared
+	shared := p.auth.GetStrategy() != "ntlm"
+	if auth, ok := p.auths[host]; ok && shared {
+		return auth, nil
+	}
+
+	var auth AuthCnfg
+	var err error
+	if p.options.NewAuth != nil {
+		auth, err = p.options.NewAuth(siteURL)
+	} else if r, ok := p.auth.(ResourceAuthCnfg); ok && shared {
+		auth = NewResourceAuth(r, siteURL)
+	} else {
+		auth, err = cloneAuth(p.auth, siteURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't create auth config for %s: %w", siteURL, err)
+	}
+	if shared {
+		p.auths[host] = auth
+	}
+	return auth, nil
+}
+
+// cloneAuth copies exported auth config fields replacing SiteURL,
+// unexported fields hold cached credentials and are not copied
+func cloneAuth(auth AuthCnfg, siteURL string) (AuthCnfg, error) {
+	v := reflect.ValueOf(auth)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T auth config can't be copied, provide PoolOptions.NewAuth", auth)
+	}
+	clone := reflect.New(v.Elem().Type())
+	for i := 0; i < v.Elem().NumField(); i++ {
+		if v.Elem().Type().Field(i).IsExported() {
+			clone.Elem().Field(i).Set(v.Elem().Field(i))
+		}
+	}
+	field := clone.Elem().FieldByName("SiteURL")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return nil, fmt.Errorf("%T auth config has no SiteURL, provide PoolOptions.NewAuth", auth)
+	}
+	field.SetString(siteURL)
+	return clone.Interface().(AuthCnfg), nil
+}
+
+// siteAuth - shared auth config bound to a pool client site
+type siteAuth struct {
+	AuthCnfg
+	siteURL string
+}
+
+// GetSiteURL gets the pool client site URL
+func (a *siteAuth) GetSiteURL() string {
+	return a.siteURL
+}
+
+// GetTransportConfig gets the shared config transport settings
+func (a *siteAuth) GetTransportConfig() *TransportConfig {
+	if c, ok := a.AuthCnfg.(TransportConfigurer); ok {
+		return c.GetTransportConfig()
+	}
+	return nil
+}
+
+// IsSessionExpired checks the session with the shared config
+func (a *siteAuth) IsSessionExpired(resp *http.Response) bool {
+	if r, ok := a.AuthCnfg.(SessionRenewer); ok {
+		return r.IsSessionExpired(resp)
+	}
+	return false
+}
+
+// CleanAuthCache evicts the shared config cached credentials
+func (a *siteAuth) CleanAuthCache() error {
+	if r, ok := a.AuthCnfg.(SessionRenewer); ok {
+		return r.CleanAuthCache()
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package gosip
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientPool(t *testing.T) {
+	var mux sync.Mutex
+	digests := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := strings.Split(r.URL.Path, "/_api/")[0]
+		if strings.HasSuffix(r.URL.Path, "/_api/ContextInfo") {
+			_, _ = fmt.Fprintf(w, `{"d":{"GetContextWebInformation":{"FormDigestValue":"digest%s","FormDigestTimeoutSeconds":120}}}`, site)
+			return
+		}
+		mux.Lock()
+		digests[site] = r.Header.Get("X-RequestDigest")
+		mux.Unlock()
+		_, _ = fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	base := &AnonymousCnfg{SiteURL: server.URL + "/sites/base"}
+
+	t.Run("SharedAuth", func(t *testing.T) {
+		pool := NewClientPool(base, &PoolOptions{MaxConnsPerSite: 4})
+		defer pool.Close()
+
+		sites := []string{"/sites/a", "/sites/b"}
+		for _, site := range sites {
+			client, err := pool.Get(server.URL + site + "/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.AuthCnfg.(*siteAuth).AuthCnfg != base {
+				t.Error("same host sites should share auth config")
+			}
+			if client.AuthCnfg.GetSiteURL() != server.URL+site {
+				t.Errorf("unexpected site URL: %s", client.AuthCnfg.GetSiteURL())
+			}
+			if client.Transport.(*http.Transport).MaxConnsPerHost != 4 {
+				t.Error("connections per site should be limited")
+			}
+			req, _ := http.NewRequest("POST", client.AuthCnfg.GetSiteURL()+"/_api/web", nil)
+			if _, err := client.Execute(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, site := range sites {
+			if digests[site] != "digest"+site {
+				t.Errorf("%s: digest should be requested per site, got %s", site, digests[site])
+			}
+		}
+
+		again, _ := pool.Get(server.URL + "/sites/a")
+		if c, _ := pool.Get(server.URL + "/sites/a"); c != again || pool.Len() != 2 {
+			t.Error("site client should be reused")
+		}
+	})
+
+	t.Run("OtherHost", func(t *testing.T) {
+		pool := NewClientPool(base, nil)
+		client, err := pool.Get("https://contoso-my.sharepoint.com/personal/user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth := client.AuthCnfg.(*siteAuth).AuthCnfg.(*AnonymousCnfg)
+		if auth == base || auth.SiteURL != "https://contoso-my.sharepoint.com/personal/user" {
+			t.Errorf("other host should get own auth config: %+v", auth)
+		}
+		other, _ := pool.Get("https://contoso-my.sharepoint.com/personal/another")
+		if other.AuthCnfg.(*siteAuth).AuthCnfg != auth {
+			t.Error("other host sites should share auth config")
+		}
+		if _, err := pool.Get("/sites/relative"); err == nil {
+			t.Error("relative site URL should fail")
+		}
+	})
+
+	t.Run("NTLM", func(t *testing.T) {
+		pool := NewClientPool(&AnonymousCnfg{SiteURL: server.URL, Strategy: "ntlm"}, nil)
+		a, _ := pool.Get(server.URL + "/sites/a")
+		b, _ := pool.Get(server.URL + "/sites/b")
+		if a.AuthCnfg.(*siteAuth).AuthCnfg == b.AuthCnfg.(*siteAuth).AuthCnfg {
+			t.Error("NTLM auth config should not be shared")
+		}
+	})
+
+	t.Run("Evict", func(t *testing.T) {
+		pool := NewClientPool(base, &PoolOptions{IdleTimeout: 10 * time.Millisecond})
+		_, _ = pool.Get(server.URL + "/sites/a")
+		time.Sleep(20 * time.Millisecond)
+		_, _ = pool.Get(server.URL + "/sites/b")
+		if pool.Len() != 1 {
+			t.Errorf("idle client should be evicted, pool size is %d", pool.Len())
+		}
+	})
+}
@@ -0,0 +1,302 @@
+/*
+Package proxy implements a local authenticated reverse proxy to a SharePoint site
+
+The proxy lets local tools and single page applications call SharePoint REST, CSOM and static resources
+without handling auth. Requests are forwarded with the configured strategy credentials,
+X-RequestDigest is added for writes, and absolute site URLs in text responses point back to the proxy.
+
+	client := &gosip.SPClient{AuthCnfg: auth}
+	log.Fatal(http.ListenAndServe("localhost:8080", proxy.New(client)))
+
+Server-relative paths are forwarded as is, site-relative `/_api`, `/_vti_bin` and `/_layouts` paths
+are resolved against the configured site URL, e.g. `http://localhost:8080/_api/web` is the site's web.
+
+Only requests addressed to the proxy itself are served: the Host header must match Hosts
+(loopback names by default), and cross-origin browser requests are rejected unless their
+origin is listed in Origins, so other web pages can't act with the proxy credentials.
+*/
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+)
+
+// maxRewriteSize is a text response size limit for URL rewriting, larger responses are streamed as is
+const maxRewriteSize = 20 << 20
+
+// hopHeaders are connection specific headers which are not forwarded
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// siteRelative are path prefixes which are resolved against the site URL
+var siteRelative = []string{"/_api/", "/_vti_bin/", "/_layouts/"}
+
+// Proxy - authenticated reverse proxy to SharePoint site
+type Proxy struct {
+	Client *gosip.SPClient // SharePoint client with auth config
+
+	// Hosts are accepted Host header values, e.g. the listen address "localhost:8080",
+	// a value without port matches any port, loopback hosts are accepted when empty
+	Hosts []string
+	// Origins are cross-origin callers allowed to use the proxy, e.g. "http://localhost:3000"
+	Origins []string
+
+	// OnError is called when a request can't be forwarded, errors are logged by the caller (optional)
+	OnError func(r *http.Request, err error)
+}
+
+// New creates a proxy for the client site
+func New(client *gosip.SPClient) *Proxy {
+	return &Proxy{Client: client}
+}
+
+// ServeHTTP forwards a request to SharePoint
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.allowedHost(r.Host) {
+		p.fail(w, r, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+		return
+	}
+	if !p.allowedOrigin(r) {
+		p.fail(w, r, http.StatusForbidden, fmt.Errorf("cross-origin request from %q is not allowed", r.Header.Get("Origin")))
+		return
+	}
+
+	siteURL, err := url.Parse(strings.TrimRight(p.Client.AuthCnfg.GetSiteURL(), "/"))
+	if err != nil {
+		p.fail(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	req, err := p.newRequest(r, siteURL)
+	if err != nil {
+		p.fail(w, r, http.StatusBadGateway, err)
+		return
+	}
+
+	resp, err := p.Client.Execute(req)
+	if resp == nil {
+		p.fail(w, r, http.StatusBadGateway, err)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Error responses from SharePoint are forwarded as is, other errors are proxy failures
+	if _, ok := err.(*gosip.SPError); err != nil && !ok {
+		p.fail(w, r, resp.StatusCode, err)
+		return
+	}
+
+	origin := siteURL.Scheme + "://" + siteURL.Host
+	local := localOrigin(r)
+
+	header := w.Header()
+	copyHeader(header, resp.Header)
+	header.Del("Set-Cookie") // auth cookies belong to the proxy session
+	if location := header.Get("Location"); location != "" {
+		header.Set("Location", rewrite(location, origin, local))
+	}
+
+	if !isText(resp.Header.Get("Content-Type")) || resp.Header.Get("Content-Encoding") != "" || resp.ContentLength > maxRewriteSize {
+		w.WriteHeader(resp.StatusCode)
+		stream(w, resp.Body)
+		return
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		p.fail(w, r, http.StatusBadGateway, err)
+		return
+	}
+	data = []byte(rewrite(string(data), origin, local))
+	header.Del("Content-Length")
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(data)
+}
+
+// newRequest creates SharePoint request from the incoming one
+func (p *Proxy) newRequest(r *http.Request, siteURL *url.URL) (*http.Request, error) {
+	target := *siteURL
+	target.Path = r.URL.Path
+	for _, prefix := range siteRelative {
+		if strings.HasPrefix(strings.ToLower(r.URL.Path), prefix) {
+			target.Path = siteURL.Path + r.URL.Path
+			break
+		}
+	}
+	target.RawPath = ""
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r.Body == http.NoBody || r.ContentLength == 0 {
+		req.Body = nil
+	}
+	req.ContentLength = r.ContentLength
+
+	copyHeader(req.Header, r.Header)
+	// Credentials are injected by the auth strategy
+	req.Header.Del("Cookie")
+	req.Header.Del("Authorization")
+	// Compressed responses can't be rewritten, transport decompresses transparently
+	req.Header.Del("Accept-Encoding")
+	for _, h := range []string{"Origin", "Referer"} {
+		if v := req.Header.Get(h); v != "" {
+			req.Header.Set(h, rewrite(v, localOrigin(r), siteURL.Scheme+"://"+siteURL.Host))
+		}
+	}
+	if isWrite(req.Method) && req.Header.Get("X-RequestDigest") == "" &&
+		!strings.Contains(strings.ToLower(req.URL.Path), "/_api/contextinfo") {
+		digest, err := gosip.GetDigest(r.Context(), p.Client)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-RequestDigest", digest)
+	}
+	return req, nil
+}
+
+// allowedHost checks if the request is addressed to the proxy, this prevents DNS rebinding
+func (p *Proxy) allowedHost(host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	hostname = strings.Trim(hostname, "[]")
+	if len(p.Hosts) == 0 {
+		if strings.EqualFold(hostname, "localhost") {
+			return true
+		}
+		ip := net.ParseIP(hostname)
+		return ip != nil && ip.IsLoopback()
+	}
+	for _, allowed := range p.Hosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+		if _, _, err := net.SplitHostPort(allowed); err != nil && strings.EqualFold(strings.Trim(allowed, "[]"), hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedOrigin checks if the request is same-origin, not sent by a browser or its origin is allowed
+func (p *Proxy) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	for _, allowed := range p.Origins {
+		if origin != "" && strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+	switch strings.ToLower(r.Header.Get("Sec-Fetch-Site")) {
+	case "cross-site", "same-site":
+		return false
+	}
+	return origin == "" || strings.EqualFold(origin, localOrigin(r))
+}
+
+// fail responds with the proxy error
+func (p *Proxy) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if p.OnError != nil {
+		p.OnError(r, err)
+	}
+	if status < 400 {
+		status = http.StatusBadGateway
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// copyHeader copies headers skipping hop-by-hop ones
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
+		dst[name] = append([]string(nil), values...)
+	}
+	for _, name := range hopHeaders {
+		dst.Del(name)
+	}
+	for _, name := range strings.Split(src.Get("Connection"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			dst.Del(name)
+		}
+	}
+}
+
+// stream copies response body flushing each chunk, so large downloads are not buffered
+func stream(w http.ResponseWriter, body io.Reader) {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, e := w.Write(buf[:n]); e != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// rewrite replaces absolute origin URLs, including JSON escaped ones
+func rewrite(text, from, to string) string {
+	if from == to {
+		return text
+	}
+	return strings.NewReplacer(
+		from, to,
+		strings.ReplaceAll(from, "/", "\\/"), strings.ReplaceAll(to, "/", "\\/"),
+	).Replace(text)
+}
+
+// localOrigin gets the proxy origin as seen by the caller
+func localOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// isWrite checks if a request method requires X-RequestDigest
+func isWrite(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "MERGE", "DELETE":
+		return true
+	}
+	return false
+}
+
+// isText checks if a content type can contain absolute URLs to rewrite
+func isText(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.HasPrefix(contentType, "text/") {
+		return true
+	}
+	for _, t := range []string{"json", "xml", "javascript"} {
+		if strings.Contains(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth/token"
+)
+
+func TestProxy(t *testing.T) {
+	var site *httptest.Server
+	site = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Cookie") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Set-Cookie", "FedAuth=secret")
+		switch strings.Replace(r.URL.Path, "ContextInfo", "contextinfo", 1) {
+		case "/sites/test/_api/contextinfo":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"d":{"GetContextWebInformation":{"FormDigestValue":"digest","FormDigestTimeoutSeconds":1800}}}`))
+		case "/sites/test/_api/web":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"Url":"` + site.URL + `/sites/test","Escaped":"` + strings.ReplaceAll(site.URL, "/", `\/`) + `\/sites\/test"}`))
+		case "/sites/test/_vti_bin/client.svc/ProcessQuery":
+			if r.Header.Get("X-RequestDigest") != "digest" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"Received":` + `"` + string(body) + `"}]`))
+		case "/sites/test/Shared Documents/file.bin":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte(strings.Repeat(site.URL, 1000)))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not found"}`))
+		}
+	}))
+	defer site.Close()
+
+	client := &gosip.SPClient{AuthCnfg: &token.AuthCnfg{SiteURL: site.URL + "/sites/test", Token: "token"}}
+	local := httptest.NewServer(New(client))
+	defer local.Close()
+
+	get := func(path string) (*http.Response, string) {
+		req, _ := http.NewRequest("GET", local.URL+path, nil)
+		req.Header.Set("Cookie", "local=cookie")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	t.Run("Rewrite", func(t *testing.T) {
+		for _, path := range []string{"/_api/web", "/sites/test/_api/web"} {
+			resp, body := get(path)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s: unexpected status %d", path, resp.StatusCode)
+			}
+			if strings.Contains(body, site.URL) || !strings.Contains(body, local.URL+"/sites/test") ||
+				!strings.Contains(body, strings.ReplaceAll(local.URL, "/", `\/`)) {
+				t.Errorf("%s: URLs are not rewritten: %s", path, body)
+			}
+			if resp.Header.Get("Set-Cookie") != "" {
+				t.Error("auth cookies should not be forwarded")
+			}
+		}
+	})
+
+	t.Run("ProcessQuery", func(t *testing.T) {
+		resp, err := http.Post(local.URL+"/_vti_bin/client.svc/ProcessQuery", "text/xml", strings.NewReader("csom"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), `"Received":"csom"`) {
+			t.Errorf("unexpected response: %d %s", resp.StatusCode, data)
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		resp, body := get("/sites/test/Shared%20Documents/file.bin")
+		if resp.StatusCode != http.StatusOK || body != strings.Repeat(site.URL, 1000) {
+			t.Errorf("binary content should be forwarded as is: %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		resp, body := get("/_api/missing")
+		if resp.StatusCode != http.StatusNotFound || body != `{"error":"not found"}` {
+			t.Errorf("error response should be forwarded: %d %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("ForeignHost", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", local.URL+"/_api/web", nil)
+		req.Host = "attacker.example.com"
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("foreign host should be rejected: %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("CrossOrigin", func(t *testing.T) {
+		post := func(headers map[string]string) int {
+			req, _ := http.NewRequest("POST", local.URL+"/_vti_bin/client.svc/ProcessQuery", strings.NewReader("csom"))
+			for name, value := range headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		if status := post(map[string]string{"Origin": "https://attacker.example.com", "Sec-Fetch-Site": "cross-site"}); status != http.StatusForbidden {
+			t.Errorf("cross-origin request should be rejected: %d", status)
+		}
+		if status := post(map[string]string{"Sec-Fetch-Site": "same-site"}); status != http.StatusForbidden {
+			t.Errorf("same-site request should be rejected: %d", status)
+		}
+		if status := post(map[string]string{"Origin": local.URL, "Sec-Fetch-Site": "same-origin"}); status != http.StatusOK {
+			t.Errorf("same-origin request should be forwarded: %d", status)
+		}
+
+		allowed := New(client)
+		allowed.Origins = []string{"http://localhost:3000"}
+		server := httptest.NewServer(allowed)
+		defer server.Close()
+		req, _ := http.NewRequest("POST", server.URL+"/_vti_bin/client.svc/ProcessQuery", strings.NewReader("csom"))
+		req.Header.Set("Origin", "http://localhost:3000")
+		req.Header.Set("Sec-Fetch-Site", "same-site")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("allowed origin should be forwarded: %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package gosip
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ResourceAuthCnfg is implemented by auth configs which can acquire tokens for other resources
+// than the site host with the same credentials, e.g. SharePoint admin site, OneDrive host or Microsoft Graph.
+// Tokens are cached per resource.
+type ResourceAuthCnfg interface {
+	AuthCnfg
+	GetResourceAuth(resource string) (string, int64, error) // Gets access token for the resource, e.g. "https://graph.microsoft.com"
+}
+
+// ResourceOf gets resource URI from URL, e.g. "https://contoso-admin.sharepoint.com" from the admin site URL,
+// non-URL resources are returned as is
+func ResourceOf(u string) string {
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return u
+	}
+	return parsed.Scheme + "://" + strings.ToLower(parsed.Host)
+}
+
+// NewResourceAuth binds resource auth config to another site,
+// requests are authenticated with a token for the site host while the config is shared
+//
+//	admin := &gosip.SPClient{AuthCnfg: gosip.NewResourceAuth(auth, "https://contoso-admin.sharepoint.com")}
+func NewResourceAuth(auth ResourceAuthCnfg, siteURL string) AuthCnfg {
+	return &resourceAuth{ResourceAuthCnfg: auth, siteURL: strings.TrimRight(siteURL, "/")}
+}
+
+// resourceAuth - resource auth config bound to a site
+type resourceAuth struct {
+	ResourceAuthCnfg
+	siteURL string
+}
+
+// GetAuth gets access token for the site host
+func (a *resourceAuth) GetAuth() (string, int64, error) {
+	return a.GetResourceAuth(ResourceOf(a.siteURL))
+}
+
+// SetAuth authenticates request with the site host token
+func (a *resourceAuth) SetAuth(req *http.Request, client *SPClient) error {
+	token, _, err := a.GetAuth()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// GetSiteURL gets the bound site URL
+func (a *resourceAuth) GetSiteURL() string {
+	return a.siteURL
+}
+
+// GetTransportConfig gets the shared config transport settings
+func (a *resourceAuth) GetTransportConfig() *TransportConfig {
+	if c, ok := a.ResourceAuthCnfg.(TransportConfigurer); ok {
+		return c.GetTransportConfig()
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package gosip
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type resourceCnfg struct {
+	AnonymousCnfg
+	requested []string
+}
+
+func (c *resourceCnfg) GetResourceAuth(resource string) (string, int64, error) {
+	c.requested = append(c.requested, resource)
+	return "token@" + resource, 0, nil
+}
+
+func TestResourceOf(t *testing.T) {
+	cases := map[string]string{
+		"https://Contoso-Admin.sharepoint.com/sites/x/": "https://contoso-admin.sharepoint.com",
+		"https://graph.microsoft.com":                   "https://graph.microsoft.com",
+		"00000003-0000-0ff1-ce00-000000000000":          "00000003-0000-0ff1-ce00-000000000000",
+	}
+	for u, resource := range cases {
+		if r := ResourceOf(u); r != resource {
+			t.Errorf("%s: unexpected resource %s", u, r)
+		}
+	}
+}
+
+func TestResourceAuth(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		_, _ = fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	base := &resourceCnfg{AnonymousCnfg: AnonymousCnfg{SiteURL: "https://contoso.sharepoint.com/sites/test"}}
+
+	t.Run("NewResourceAuth", func(t *testing.T) {
+		client := &SPClient{AuthCnfg: NewResourceAuth(base, server.URL+"/")}
+		if client.AuthCnfg.GetSiteURL() != server.URL {
+			t.Errorf("unexpected site URL: %s", client.AuthCnfg.GetSiteURL())
+		}
+		req, _ := http.NewRequest("GET", server.URL+"/_api/web", nil)
+		if _, err := client.Execute(req); err != nil {
+			t.Fatal(err)
+		}
+		if authorization != "Bearer token@"+server.URL {
+			t.Errorf("unexpected authorization: %s", authorization)
+		}
+	})
+
+	t.Run("ClientPool", func(t *testing.T) {
+		pool := NewClientPool(base, nil)
+		defer pool.Close()
+		client, err := pool.Get("https://contoso-my.sharepoint.com/personal/user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := client.AuthCnfg.GetAuth()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "token@https://contoso-my.sharepoint.com" {
+			t.Errorf("other host should use resource token: %s", token)
+		}
+	})
+}
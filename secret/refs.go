@@ -0,0 +1,141 @@
+package secret
+
+import "sync"
+
+// refsMux guards resolved fields: Refresh writes them holding the lock,
+// while Retry holds a read lock for the auth call reading them
+var refsMux sync.RWMutex
+
+// Refs keeps original references of resolved config fields
+// Strategies hold Refs in an unexported field and resolve secret fields in ParseConfig,
+// the fields are read in auth calls wrapped with Retry, so refreshed values don't race with the reads.
+type Refs map[*string]ref
+
+// ref - field reference and the value it has been resolved to
+type ref struct {
+	ref   string
+	value string
+}
+
+// Resolve resolves referenced fields in place and remembers the references
+func (r *Refs) Resolve(fields ...*string) error {
+	refsMux.Lock()
+	defer refsMux.Unlock()
+	for _, field := range fields {
+		if !IsRef(*field) {
+			continue
+		}
+		value, err := resolve(*field, false)
+		if err != nil {
+			return err
+		}
+		if *r == nil {
+			*r = Refs{}
+		}
+		(*r)[field] = ref{ref: *field, value: value}
+		*field = value
+	}
+	return nil
+}
+
+// Copy copies a field to a field of a config clone keeping the reference
+// Referenced value is resolved again, so the clone receives a secret rotated by other clones.
+func (r *Refs) Copy(src Refs, from, to *string) {
+	refsMux.Lock()
+	defer refsMux.Unlock()
+	rf, ok := src[from]
+	if !ok || *from != rf.value {
+		*to = *from
+		return
+	}
+	value, err := resolve(rf.ref, false)
+	if err != nil {
+		value = rf.value
+	}
+	if *r == nil {
+		*r = Refs{}
+	}
+	(*r)[to] = ref{ref: rf.ref, value: value}
+	*to = value
+}
+
+// Refresh re-resolves the references bypassing exec results cache
+// Returns true when any of the fields has got a new value.
+// References are resolved without holding the lock, so credential helpers don't block auth calls.
+func (r Refs) Refresh() bool {
+	refsMux.RLock()
+	refs := map[*string]ref{}
+	for field, rf := range r {
+		if *field == rf.value { // skip fields changed since resolution
+			refs[field] = rf
+		}
+	}
+	refsMux.RUnlock()
+
+	resolved := map[*string]string{}
+	for field, rf := range refs {
+		if value, err := resolve(rf.ref, true); err == nil && value != rf.value {
+			resolved[field] = value
+		}
+	}
+
+	refsMux.Lock()
+	defer refsMux.Unlock()
+	changed := false
+	for field, value := range resolved {
+		rf := refs[field]
+		if *field != rf.value {
+			continue
+		}
+		r[field] = ref{ref: rf.ref, value: value}
+		*field = value
+		changed = true
+	}
+	return changed
+}
+
+// Ref gets original reference of a field, empty string when the field is not resolved from a reference
+func (r Refs) Ref(field *string) string {
+	refsMux.RLock()
+	defer refsMux.RUnlock()
+	if rf, ok := r[field]; ok && *field == rf.value {
+		return rf.ref
+	}
+	return ""
+}
+
+// Encode gets field value to persist: the original reference, or the value encoded with a provided encoder
+func (r Refs) Encode(field *string, encode func(string) (string, error)) (string, error) {
+	refsMux.RLock()
+	rf, ok := r[field]
+	value := *field
+	refsMux.RUnlock()
+	if ok && value == rf.value {
+		return rf.ref, nil
+	}
+	return encode(value)
+}
+
+// Retry calls getAuth and, when it fails, calls it once again if refreshed references have got new values
+// E.g. a password is rotated in a vault while an exec result is still cached.
+func (r Refs) Retry(getAuth func() (string, int64, error)) (string, int64, error) {
+	return r.RetryReset(getAuth, nil)
+}
+
+// RetryReset is Retry which calls reset before the second attempt, e.g. to drop clients created with previous secrets.
+// getAuth is called holding a read lock of the resolved fields, it must not resolve references itself.
+func (r Refs) RetryReset(getAuth func() (string, int64, error), reset func()) (string, int64, error) {
+	read := func() (string, int64, error) {
+		refsMux.RLock()
+		defer refsMux.RUnlock()
+		return getAuth()
+	}
+	token, exp, err := read()
+	if err != nil && r.Refresh() {
+		if reset != nil {
+			reset()
+		}
+		return read()
+	}
+	return token, exp, err
+}
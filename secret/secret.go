@@ -0,0 +1,118 @@
+/*
+Package secret resolves secret references in strategy configs
+
+Instead of storing a password or a client secret in a config file, a reference can be used:
+
+	"env:SP_PASSWORD"                    - environment variable value
+	"file:/run/secrets/sp"               - file content, trailing line break is trimmed
+	"exec:vault read -field=pw secret/sp" - command output (credential helper), trailing line break is trimmed
+
+A plain value which starts with a reference prefix is escaped with "raw:", e.g. "raw:env:pass" is "env:pass" value.
+Values starting with "raw:" are escaped the same way: "raw:raw:pass".
+
+Exec results are cached per command, Refs.Refresh re-runs the commands, e.g. when auth fails with rotated credentials.
+*/
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reference prefixes
+const (
+	PrefixEnv  = "env:"
+	PrefixFile = "file:"
+	PrefixExec = "exec:"
+	PrefixRaw  = "raw:" // escapes a plain value, the rest of the value is used as is
+)
+
+// ExecTimeout is a maximum duration of an exec credential helper run
+var ExecTimeout = 30 * time.Second
+
+var (
+	execCache = map[string]string{}
+	execMux   sync.Mutex
+)
+
+// IsRef checks if a value is a secret reference
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, PrefixEnv) ||
+		strings.HasPrefix(value, PrefixFile) ||
+		strings.HasPrefix(value, PrefixExec) ||
+		strings.HasPrefix(value, PrefixRaw)
+}
+
+// Resolve resolves a secret reference, values which are not references are returned as is
+func Resolve(value string) (string, error) {
+	return resolve(value, false)
+}
+
+// resolve resolves a secret reference, force flag bypasses exec results cache
+func resolve(value string, force bool) (string, error) {
+	switch {
+	case strings.HasPrefix(value, PrefixEnv):
+		name := strings.TrimPrefix(value, PrefixEnv)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("can't resolve %s secret: environment variable is not set", value)
+		}
+		return v, nil
+	case strings.HasPrefix(value, PrefixFile):
+		data, err := os.ReadFile(strings.TrimPrefix(value, PrefixFile))
+		if err != nil {
+			return "", fmt.Errorf("can't resolve %s secret: %w", value, err)
+		}
+		return trimLineBreak(string(data)), nil
+	case strings.HasPrefix(value, PrefixExec):
+		return execHelper(strings.TrimPrefix(value, PrefixExec), force)
+	case strings.HasPrefix(value, PrefixRaw):
+		return strings.TrimPrefix(value, PrefixRaw), nil
+	default:
+		return value, nil
+	}
+}
+
+// execHelper runs credential helper command and caches its output
+func execHelper(command string, force bool) (string, error) {
+	execMux.Lock()
+	defer execMux.Unlock()
+
+	if v, ok := execCache[command]; ok && !force {
+		return v, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ExecTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// Command output is not included as it might contain the secret
+		return "", fmt.Errorf("can't resolve exec secret, credential helper failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	v := trimLineBreak(stdout.String())
+	execCache[command] = v
+	return v, nil
+}
+
+// trimLineBreak trims a single trailing line break
+func trimLineBreak(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	return strings.TrimSuffix(value, "\r")
+}
@@ -0,0 +1,180 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Run("Plain", func(t *testing.T) {
+		if v, err := Resolve("password"); err != nil || v != "password" {
+			t.Errorf("plain value should be returned as is: %s, %v", v, err)
+		}
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		for value, expected := range map[string]string{
+			"raw:env:password": "env:password",
+			"raw:raw:password": "raw:password",
+			"raw:password":     "password",
+		} {
+			if !IsRef(value) {
+				t.Errorf("%s should be resolved", value)
+			}
+			if v, err := Resolve(value); err != nil || v != expected {
+				t.Errorf("%s: expected %s, got %s, %v", value, expected, v, err)
+			}
+		}
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		t.Setenv("GOSIP_TEST_SECRET", "from-env")
+		if v, err := Resolve("env:GOSIP_TEST_SECRET"); err != nil || v != "from-env" {
+			t.Errorf("unexpected env secret: %s, %v", v, err)
+		}
+		if _, err := Resolve("env:GOSIP_TEST_SECRET_MISSING"); err == nil {
+			t.Error("missing variable should not pass")
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "secret")
+		_ = os.WriteFile(file, []byte("from-file\n"), 0600)
+		if v, err := Resolve("file:" + file); err != nil || v != "from-file" {
+			t.Errorf("unexpected file secret: %s, %v", v, err)
+		}
+		if _, err := Resolve("file:" + file + ".missing"); err == nil {
+			t.Error("missing file should not pass")
+		}
+	})
+
+	t.Run("Exec", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("shell helper is not available")
+		}
+		if v, err := Resolve("exec:echo from-exec"); err != nil || v != "from-exec" {
+			t.Errorf("unexpected exec secret: %s, %v", v, err)
+		}
+		_, err := Resolve("exec:echo leaked; echo failure >&2; exit 1")
+		if err == nil {
+			t.Fatal("failed helper should not pass")
+		}
+		if strings.Contains(err.Error(), "leaked") || !strings.Contains(err.Error(), "failure") {
+			t.Errorf("unexpected helper error: %s", err)
+		}
+	})
+}
+
+func TestRefs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell helper is not available")
+	}
+
+	file := filepath.Join(t.TempDir(), "secret")
+	_ = os.WriteFile(file, []byte("v1"), 0600)
+
+	cnfg := struct {
+		Username string
+		Password string
+		secrets  Refs
+	}{
+		Username: "user",
+		Password: "exec:cat " + file,
+	}
+
+	if err := cnfg.secrets.Resolve(&cnfg.Username, &cnfg.Password); err != nil {
+		t.Fatal(err)
+	}
+	if cnfg.Password != "v1" || cnfg.secrets.Ref(&cnfg.Username) != "" {
+		t.Fatalf("unexpected resolution: %+v", cnfg)
+	}
+
+	// Exec results are cached until refresh
+	_ = os.WriteFile(file, []byte("v2"), 0600)
+	if v, _ := Resolve("exec:cat " + file); v != "v1" {
+		t.Errorf("exec result is not cached: %s", v)
+	}
+
+	calls := 0
+	_, _, err := cnfg.secrets.Retry(func() (string, int64, error) {
+		calls++
+		if cnfg.Password != "v2" {
+			return "", 0, os.ErrPermission
+		}
+		return "token", 0, nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("auth is not retried with a rotated secret: %v, %d calls", err, calls)
+	}
+
+	// Persisted value keeps the reference
+	encoded, _ := cnfg.secrets.Encode(&cnfg.Password, func(v string) (string, error) { return "encoded", nil })
+	if encoded != "exec:cat "+file {
+		t.Errorf("reference is not persisted: %s", encoded)
+	}
+	clone := struct {
+		Password string
+		secrets  Refs
+	}{}
+	clone.secrets.Copy(cnfg.secrets, &cnfg.Password, &clone.Password)
+	if clone.Password != "v2" || clone.secrets.Ref(&clone.Password) != "exec:cat "+file {
+		t.Errorf("reference is not copied: %+v", clone)
+	}
+
+	// Manually changed value is not a reference anymore
+	cnfg.Password = "manual"
+	encoded, _ = cnfg.secrets.Encode(&cnfg.Password, func(v string) (string, error) { return "encoded", nil })
+	if encoded != "encoded" {
+		t.Errorf("changed value should be encoded: %s", encoded)
+	}
+
+	// No changes, no retries
+	calls = 0
+	_, _, _ = cnfg.secrets.Retry(func() (string, int64, error) {
+		calls++
+		return "", 0, os.ErrPermission
+	})
+	if calls != 1 {
+		t.Errorf("auth should not be retried without changes: %d calls", calls)
+	}
+}
+
+func TestRefsConcurrentRefresh(t *testing.T) {
+	t.Setenv("GOSIP_TEST_ROTATED", "v1")
+
+	cnfg := struct {
+		Password string
+		secrets  Refs
+	}{Password: "env:GOSIP_TEST_ROTATED"}
+	if err := cnfg.secrets.Resolve(&cnfg.Password); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOSIP_TEST_ROTATED", "v2")
+
+	// Auth calls read the field while others refresh it, run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = cnfg.secrets.Retry(func() (string, int64, error) {
+				if cnfg.Password != "v2" {
+					return "", 0, os.ErrPermission
+				}
+				return "token", 0, nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if cnfg.Password != "v2" {
+		t.Errorf("rotated secret is not applied: %s", cnfg.Password)
+	}
+	if encoded, _ := cnfg.secrets.Encode(&cnfg.Password, nil); encoded != "env:GOSIP_TEST_ROTATED" {
+		t.Errorf("reference is not kept after refresh: %s", encoded)
+	}
+}
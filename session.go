@@ -0,0 +1,75 @@
+package gosip
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SessionRenewer is implemented by cookie-based auth configs which sessions can be invalidated
+// server-side before the expiry, e.g. on a farm restart or a forced sign-out.
+// When a response signals an expired session, SPClient evicts cached credentials,
+// re-authenticates once and replays the request.
+type SessionRenewer interface {
+	IsSessionExpired(resp *http.Response) bool // Checks if the response is a sign in page instead of the resource
+	CleanAuthCache() error                     // Evicts cached credentials
+}
+
+// IsLoginResponse checks if a response is a redirect to one of the sign in pages,
+// a followed redirect which ended up on a sign in page or a 403 HTML page.
+// Sign in pages are matched by a case-insensitive URL fragment, e.g. "/_login/".
+func IsLoginResponse(resp *http.Response, loginPages ...string) bool {
+	if resp == nil {
+		return false
+	}
+
+	// Not followed redirect
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if location, err := resp.Location(); err == nil {
+			return matchLoginPage(location.String(), loginPages)
+		}
+		return false
+	}
+
+	// Followed redirect
+	if resp.Request != nil && resp.Request.Response != nil && resp.Request.URL != nil {
+		if matchLoginPage(resp.Request.URL.String(), loginPages) {
+			return true
+		}
+	}
+
+	// SharePoint API responds with JSON or XML errors, HTML is an access denied or a sign in page
+	if resp.StatusCode == 403 {
+		return strings.HasPrefix(strings.ToLower(resp.Header.Get("Content-Type")), "text/html")
+	}
+
+	return false
+}
+
+// matchLoginPage checks if URL contains one of the sign in page fragments
+func matchLoginPage(u string, loginPages []string) bool {
+	u = strings.ToLower(u)
+	for _, page := range loginPages {
+		if strings.Contains(u, strings.ToLower(page)) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRenewSession checks if the response signals an expired auth session and evicts cached credentials
+func (c *SPClient) shouldRenewSession(req *http.Request, resp *http.Response) bool {
+	if req.Header.Get("X-Gosip-NoRetry") == "true" {
+		return false
+	}
+	renewer, ok := c.AuthCnfg.(SessionRenewer)
+	if !ok || !renewer.IsSessionExpired(resp) {
+		return false
+	}
+	if err := renewer.CleanAuthCache(); err != nil {
+		return false
+	}
+	if resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package gosip
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type sessionCnfg struct {
+	AnonymousCnfg
+	session  string
+	sessions int
+}
+
+func (c *sessionCnfg) SetAuth(req *http.Request, _ *SPClient) error {
+	if c.session == "" {
+		c.sessions++
+		c.session = "session-" + string(rune('0'+c.sessions))
+	}
+	req.Header.Set("Cookie", "FedAuth="+c.session)
+	return nil
+}
+
+func (c *sessionCnfg) IsSessionExpired(resp *http.Response) bool {
+	return IsLoginResponse(resp, "/_login/")
+}
+
+func (c *sessionCnfg) CleanAuthCache() error {
+	c.session = ""
+	return nil
+}
+
+func TestSessionRenewal(t *testing.T) {
+	expired := map[string]bool{"FedAuth=session-1": true}
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_login/default.aspx" {
+			_, _ = w.Write([]byte("<html>sign in</html>"))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if expired[r.Header.Get("Cookie")] {
+			http.Redirect(w, r, "/_login/default.aspx?ReturnUrl="+url.QueryEscape(r.URL.Path), http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	t.Run("Redirect", func(t *testing.T) {
+		cnfg := &sessionCnfg{AnonymousCnfg: AnonymousCnfg{SiteURL: server.URL}}
+		retries := 0
+		client := &SPClient{
+			Client:   http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }},
+			AuthCnfg: cnfg,
+			Hooks:    &HookHandlers{OnRetry: func(*HookEvent) { retries++ }},
+		}
+		req, _ := http.NewRequest("POST", server.URL+"/_api/web", strings.NewReader("payload"))
+		req.Header.Set("X-RequestDigest", "digest")
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(data) != "ok" {
+			t.Errorf("unexpected response: %s", data)
+		}
+		if cnfg.sessions != 2 || retries != 1 {
+			t.Errorf("session should be renewed once, sessions: %d, retries: %d", cnfg.sessions, retries)
+		}
+		if len(bodies) != 2 || bodies[1] != "payload" {
+			t.Errorf("request body is not replayed: %v", bodies)
+		}
+	})
+
+	t.Run("FollowedRedirect", func(t *testing.T) {
+		cnfg := &sessionCnfg{AnonymousCnfg: AnonymousCnfg{SiteURL: server.URL}}
+		client := &SPClient{AuthCnfg: cnfg}
+		req, _ := http.NewRequest("GET", server.URL+"/_api/web", nil)
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(data) != "ok" || cnfg.sessions != 2 {
+			t.Errorf("session is not renewed, response: %s, sessions: %d", data, cnfg.sessions)
+		}
+	})
+
+	t.Run("Once", func(t *testing.T) {
+		expired["FedAuth=session-2"] = true
+		defer delete(expired, "FedAuth=session-2")
+
+		cnfg := &sessionCnfg{AnonymousCnfg: AnonymousCnfg{SiteURL: server.URL}}
+		client := &SPClient{
+			Client:   http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }},
+			AuthCnfg: cnfg,
+		}
+		req, _ := http.NewRequest("GET", server.URL+"/_api/web", nil)
+		resp, err := client.Execute(req)
+		if err == nil {
+			t.Error("should fail when renewed session is not accepted")
+		}
+		if resp.StatusCode != http.StatusFound || cnfg.sessions != 2 {
+			t.Errorf("should re-authenticate once, status: %d, sessions: %d", resp.StatusCode, cnfg.sessions)
+		}
+	})
+}
+
+func TestIsLoginResponse(t *testing.T) {
+	loginURL, _ := url.Parse("https://sp.contoso.com/_layouts/15/Authenticate.aspx?Source=/")
+	apiURL, _ := url.Parse("https://sp.contoso.com/_api/web")
+
+	cases := []struct {
+		name     string
+		resp     *http.Response
+		expected bool
+	}{
+		{"Nil", nil, false},
+		{"Redirect", &http.Response{StatusCode: 302, Header: http.Header{"Location": {loginURL.String()}}}, true},
+		{"OtherRedirect", &http.Response{StatusCode: 302, Header: http.Header{"Location": {"/sites/test"}}}, false},
+		{"Followed", &http.Response{StatusCode: 200, Request: &http.Request{URL: loginURL, Response: &http.Response{}}}, true},
+		{"NotFollowed", &http.Response{StatusCode: 200, Request: &http.Request{URL: loginURL}}, false},
+		{"ForbiddenHTML", &http.Response{StatusCode: 403, Header: http.Header{"Content-Type": {"text/html; charset=utf-8"}}, Request: &http.Request{URL: apiURL}}, true},
+		{"ForbiddenJSON", &http.Response{StatusCode: 403, Header: http.Header{"Content-Type": {"application/json"}}, Request: &http.Request{URL: apiURL}}, false},
+	}
+	for _, c := range cases {
+		if IsLoginResponse(c.resp, "/_layouts/15/authenticate.aspx") != c.expected {
+			t.Errorf("%s: should be %t", c.name, c.expected)
+		}
+	}
+}
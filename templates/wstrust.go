@@ -0,0 +1,165 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// WS-Trust protocol versions
+const (
+	WsTrust13   = "1.3"  // http://docs.oasis-open.org/ws-sx/ws-trust/200512
+	WsTrust2005 = "2005" // http://schemas.xmlsoap.org/ws/2005/02/trust
+)
+
+// WsTrustTemplate : WS-Trust RequestSecurityToken envelope, security is a pre-rendered WS-Security header
+func WsTrustTemplate(version, to, appliesTo, security string) (string, error) {
+	type wsTrust struct {
+		Action      string
+		Namespace   string
+		KeyType     string
+		RequestType string
+		To          string
+		AppliesTo   string
+		Security    string
+	}
+
+	data := wsTrust{
+		To:        escapeParamString(to),
+		AppliesTo: escapeParamString(appliesTo),
+		Security:  security,
+	}
+
+	switch version {
+	case WsTrust13:
+		data.Namespace = "http://docs.oasis-open.org/ws-sx/ws-trust/200512"
+		data.Action = data.Namespace + "/RST/Issue"
+		data.KeyType = data.Namespace + "/Bearer"
+		data.RequestType = data.Namespace + "/Issue"
+	case WsTrust2005:
+		data.Namespace = "http://schemas.xmlsoap.org/ws/2005/02/trust"
+		data.Action = data.Namespace + "/RST/Issue"
+		data.KeyType = "http://schemas.xmlsoap.org/ws/2005/05/identity/NoProofKey"
+		data.RequestType = data.Namespace + "/Issue"
+	default:
+		return "", fmt.Errorf("unsupported WS-Trust version: %s", version)
+	}
+
+	t, err := template.New("wsTrust").Parse(`
+		<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://www.w3.org/2005/08/addressing" xmlns:u="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">
+			<s:Header>
+				<a:Action s:mustUnderstand="1">{{.Action}}</a:Action>
+				<a:MessageID>urn:uuid:7b105801-44ac-4da7-aa69-a87f9db37299</a:MessageID>
+				<a:ReplyTo>
+					<a:Address>http://www.w3.org/2005/08/addressing/anonymous</a:Address>
+				</a:ReplyTo>
+				<a:To s:mustUnderstand="1">{{.To}}</a:To>
+				{{.Security}}
+			</s:Header>
+			<s:Body>
+				<t:RequestSecurityToken xmlns:t="{{.Namespace}}">
+					<wsp:AppliesTo xmlns:wsp="http://schemas.xmlsoap.org/ws/2004/09/policy">
+						<a:EndpointReference>
+							<a:Address>{{.AppliesTo}}</a:Address>
+						</a:EndpointReference>
+					</wsp:AppliesTo>
+					<t:KeyType>{{.KeyType}}</t:KeyType>
+					<t:RequestType>{{.RequestType}}</t:RequestType>
+				</t:RequestSecurityToken>
+			</s:Body>
+		</s:Envelope>
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	var tpl bytes.Buffer
+	if err := t.Execute(&tpl, data); err != nil {
+		return "", err
+	}
+
+	result := compactTemplate(tpl.String())
+
+	return result, nil
+}
+
+// WsSecurityUsernameTemplate : WS-Security header with a username token, used with usernamemixed endpoints
+func WsSecurityUsernameTemplate(username, password string) (string, error) {
+	type wsSecurityUsername struct {
+		Username string
+		Password string
+	}
+
+	t, err := template.New("wsSecurityUsername").Parse(`
+		<o:Security s:mustUnderstand="1" xmlns:o="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+			<o:UsernameToken u:Id="uuid-7b105801-44ac-4da7-aa69-a87f9db37299-1">
+				<o:Username>{{.Username}}</o:Username>
+				<o:Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText">{{.Password}}</o:Password>
+			</o:UsernameToken>
+		</o:Security>
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	data := wsSecurityUsername{
+		Username: escapeParamString(username),
+		Password: escapeParamString(password),
+	}
+
+	var tpl bytes.Buffer
+	if err := t.Execute(&tpl, data); err != nil {
+		return "", err
+	}
+
+	result := compactTemplate(tpl.String())
+
+	return result, nil
+}
+
+// WsSecurityX509Template : WS-Security header with a timestamp signed by an X.509 certificate,
+// used with certificatemixed endpoints. Timestamp and signed info should be in canonical form
+// as they are signed as is.
+func WsSecurityX509Template(timestamp, certificate, signedInfo, signatureValue string) (string, error) {
+	type wsSecurityX509 struct {
+		Timestamp      string
+		Certificate    string
+		SignedInfo     string
+		SignatureValue string
+	}
+
+	t, err := template.New("wsSecurityX509").Parse(`
+		<o:Security s:mustUnderstand="1" xmlns:o="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+			{{.Timestamp}}
+			<o:BinarySecurityToken u:Id="uuid-x509" ValueType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-x509-token-profile-1.0#X509v3" EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">{{.Certificate}}</o:BinarySecurityToken>
+			<Signature xmlns="http://www.w3.org/2000/09/xmldsig#">
+				{{.SignedInfo}}
+				<SignatureValue>{{.SignatureValue}}</SignatureValue>
+				<KeyInfo>
+					<o:SecurityTokenReference>
+						<o:Reference URI="#uuid-x509" ValueType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-x509-token-profile-1.0#X509v3"/>
+					</o:SecurityTokenReference>
+				</KeyInfo>
+			</Signature>
+		</o:Security>
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	data := wsSecurityX509{
+		Timestamp:      timestamp,
+		Certificate:    certificate,
+		SignedInfo:     signedInfo,
+		SignatureValue: signatureValue,
+	}
+
+	var tpl bytes.Buffer
+	if err := t.Execute(&tpl, data); err != nil {
+		return "", err
+	}
+
+	result := compactTemplate(tpl.String())
+
+	return result, nil
+}
@@ -2,9 +2,9 @@ package helpers
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/diag/phase"
 	u "github.com/koltyakov/gosip/test/utils"
 )
 
@@ -19,22 +19,5 @@ func CheckDigest(auth gosip.AuthCnfg, cnfgPath string) error {
 		AuthCnfg: auth,
 	}
 
-	digest, err := gosip.GetDigest(context.Background(), client)
-	if err != nil {
-		return fmt.Errorf("unable to get digest: %w", err)
-	}
-
-	if digest == "" {
-		return fmt.Errorf("got empty digest")
-	}
-
-	if _, err := gosip.GetDigest(context.Background(), client); err != nil {
-		return fmt.Errorf("unable to get cached digest: %w", err)
-	}
-
-	// if digest != cachedDigest {
-	// 	return fmt.Errorf("digest cache is broken")
-	// }
-
-	return nil
+	return phase.Digest(context.Background(), client)
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/koltyakov/gosip"
+	a "github.com/koltyakov/gosip/auth"
 	"github.com/koltyakov/gosip/auth/addin"
 	"github.com/koltyakov/gosip/auth/adfs"
 	"github.com/koltyakov/gosip/auth/azurecert"
@@ -14,6 +15,7 @@ import (
 	"github.com/koltyakov/gosip/auth/azureenv"
 	"github.com/koltyakov/gosip/auth/device"
 	"github.com/koltyakov/gosip/auth/fba"
+	"github.com/koltyakov/gosip/auth/federated"
 	"github.com/koltyakov/gosip/auth/ntlm"
 	"github.com/koltyakov/gosip/auth/saml"
 	"github.com/koltyakov/gosip/auth/tmg"
@@ -33,6 +35,8 @@ func GetTestClient(strategy string) (*gosip.SPClient, error) {
 		client, err = getAzureenvAuthTest()
 	case "device":
 		client, err = getDeviceAuthTest()
+	case "federated":
+		client, err = getFederatedAuthTest()
 	case "addin":
 		client, err = getAddinAuthTest()
 	case "adfs":
@@ -71,6 +75,11 @@ func getDeviceAuthTest() (*gosip.SPClient, error) {
 	return r(&device.AuthCnfg{}, "./config/private.spo-device.json")
 }
 
+// getFederatedAuthTest : Workload Identity Federation auth test scenario
+func getFederatedAuthTest() (*gosip.SPClient, error) {
+	return r(&federated.AuthCnfg{}, "./config/private.spo-federated.json")
+}
+
 // getAddinAuthTest : Addin auth test scenario
 func getAddinAuthTest() (*gosip.SPClient, error) {
 	return r(&addin.AuthCnfg{}, "./config/private.spo-addin.json")
@@ -117,6 +126,23 @@ func r(auth gosip.AuthCnfg, cnfgPath string) (*gosip.SPClient, error) {
 		return nil, fmt.Errorf("unable to get config: %w", err)
 	}
 
+	return connect(auth, startAt)
+}
+
+// GetProfileClient gets a client for a named profile of the credentials file
+func GetProfileClient(profile string) (*gosip.SPClient, error) {
+	startAt := time.Now()
+
+	auth, err := a.NewAuthFromProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get profile: %w", err)
+	}
+
+	return connect(auth, startAt)
+}
+
+// connect checks the connection with a test request
+func connect(auth gosip.AuthCnfg, startAt time.Time) (*gosip.SPClient, error) {
 	fmt.Printf("Site Url: %s\n", auth.GetSiteURL())
 
 	client := &gosip.SPClient{AuthCnfg: auth}
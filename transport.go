@@ -0,0 +1,264 @@
+package gosip
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/koltyakov/gosip/cpass"
+	"github.com/koltyakov/gosip/secret"
+)
+
+// TransportConfig is HTTP transport settings shared by auth strategies,
+// defined with `transport` section of private.json
+/* Config sample:
+{
+	"siteUrl": "https://sp.contoso.com/sites/test",
+	"username": "contoso\\user",
+	"password": "password",
+	"transport": {
+		"rootCAs": ["certs/contoso-root.pem"],
+		"clientCert": "certs/client.pem",
+		"clientKey": "certs/client.key",
+		"minTlsVersion": "1.2",
+		"proxyUrl": "http://proxy.contoso.com:8080",
+		"proxyUsername": "contoso\\proxy",
+		"proxyPassword": "password",
+		"dialTimeout": "10s",
+		"timeout": "2m"
+	}
+}
+*/
+// The settings apply to SharePoint API calls and to strategies' token and cookie requests.
+// File paths are relative to config location or absolute.
+type TransportConfig struct {
+	RootCAs       []string `json:"rootCAs,omitempty"`                     // PEM bundles with root certificates trusted in addition to the system ones
+	ClientCert    string   `json:"clientCert,omitempty"`                  // PEM client certificate for mutual TLS
+	ClientKey     string   `json:"clientKey,omitempty"`                   // PEM client certificate private key
+	MinTLSVersion string   `json:"minTlsVersion,omitempty"`               // Minimum TLS version: 1.0, 1.1, 1.2 or 1.3
+	ProxyURL      string   `json:"proxyUrl,omitempty"`                    // Proxy URL, proxy environment variables are used by default
+	ProxyUsername string   `json:"proxyUsername,omitempty"`               // Proxy basic auth username
+	ProxyPassword string   `json:"proxyPassword,omitempty" secret:"true"` // Proxy basic auth password
+
+	DialTimeout           string `json:"dialTimeout,omitempty"`           // Connection timeout, e.g. "10s"
+	TLSHandshakeTimeout   string `json:"tlsHandshakeTimeout,omitempty"`   // TLS handshake timeout
+	ResponseHeaderTimeout string `json:"responseHeaderTimeout,omitempty"` // Response headers wait timeout
+	Timeout               string `json:"timeout,omitempty"`               // Overall request timeout
+
+	baseDir string
+	secrets secret.Refs
+}
+
+// TransportConfigurer is implemented by auth configs which support transport settings
+type TransportConfigurer interface {
+	GetTransportConfig() *TransportConfig
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Prepare resolves secret references and encoded proxy password,
+// relative paths are resolved against privateFile location
+func (t *TransportConfig) Prepare(privateFile string, masterKey string) error {
+	if t == nil {
+		return nil
+	}
+	if privateFile != "" {
+		t.baseDir = filepath.Dir(privateFile)
+	}
+	if err := t.secrets.Resolve(&t.ProxyPassword); err != nil {
+		return err
+	}
+	if t.ProxyPassword == "" {
+		return nil
+	}
+	crypt, err := cpass.New(masterKey)
+	if err != nil {
+		return err
+	}
+	if secret, err := crypt.Decode(t.ProxyPassword); err == nil {
+		t.ProxyPassword = secret
+	}
+	return nil
+}
+
+// Encode gets a copy of the settings for writing to a config, proxy password is encoded
+func (t *TransportConfig) Encode(masterKey string) (*TransportConfig, error) {
+	if t == nil {
+		return nil, nil
+	}
+	config := &TransportConfig{
+		RootCAs:               t.RootCAs,
+		ClientCert:            t.ClientCert,
+		ClientKey:             t.ClientKey,
+		MinTLSVersion:         t.MinTLSVersion,
+		ProxyURL:              t.ProxyURL,
+		ProxyUsername:         t.ProxyUsername,
+		DialTimeout:           t.DialTimeout,
+		TLSHandshakeTimeout:   t.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: t.ResponseHeaderTimeout,
+		Timeout:               t.Timeout,
+	}
+	if t.ProxyPassword != "" {
+		crypt, err := cpass.New(masterKey)
+		if err != nil {
+			return nil, err
+		}
+		secret, err := t.secrets.Encode(&t.ProxyPassword, crypt.Encode)
+		if err != nil {
+			return nil, err
+		}
+		config.ProxyPassword = secret
+	}
+	return config, nil
+}
+
+// NewTransport creates HTTP transport based on default one with the settings applied
+func (t *TransportConfig) NewTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if t == nil {
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if len(t.RootCAs) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, caFile := range t.RootCAs {
+			pem, err := os.ReadFile(t.path(caFile))
+			if err != nil {
+				return nil, fmt.Errorf("can't read root CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in root CA bundle %s", caFile)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if t.ClientCert != "" || t.ClientKey != "" {
+		if t.ClientCert == "" || t.ClientKey == "" {
+			return nil, fmt.Errorf("both clientCert and clientKey should be provided")
+		}
+		cert, err := tls.LoadX509KeyPair(t.path(t.ClientCert), t.path(t.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if t.MinTLSVersion != "" {
+		version, ok := tlsVersions[t.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minTlsVersion: %s", t.MinTLSVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if t.ProxyURL != "" {
+		proxyURL, err := url.Parse(t.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse proxyUrl: %w", err)
+		}
+		if t.ProxyUsername != "" {
+			proxyURL.User = url.UserPassword(t.ProxyUsername, t.ProxyPassword)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialTimeout, err := parseTimeout("dialTimeout", t.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if dialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	handshakeTimeout, err := parseTimeout("tlsHandshakeTimeout", t.TLSHandshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if handshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = handshakeTimeout
+	}
+	if transport.ResponseHeaderTimeout, err = parseTimeout("responseHeaderTimeout", t.ResponseHeaderTimeout); err != nil {
+		return nil, err
+	}
+
+	return transport, nil
+}
+
+// NewClient creates HTTP client with the settings applied,
+// a nil config ends up with a default client
+func (t *TransportConfig) NewClient() (*http.Client, error) {
+	if t == nil {
+		return &http.Client{}, nil
+	}
+	transport, err := t.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseTimeout("timeout", t.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// path resolves a file path relative to config location
+func (t *TransportConfig) path(file string) string {
+	if filepath.IsAbs(file) || t.baseDir == "" {
+		return file
+	}
+	return filepath.Join(t.baseDir, file)
+}
+
+// parseTimeout parses a duration setting, empty value means no timeout
+func parseTimeout(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("can't parse %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// applyTransport applies auth config transport settings when the client has no custom transport,
+// settings are applied once, so concurrent requests don't race on the shared client fields
+func (c *SPClient) applyTransport() error {
+	c.transportOnce.Do(func() {
+		cnfg, ok := c.AuthCnfg.(TransportConfigurer)
+		if !ok {
+			return
+		}
+		t := cnfg.GetTransportConfig()
+		if t == nil || c.Transport != nil {
+			return
+		}
+		client, err := t.NewClient()
+		if err != nil {
+			c.transportErr = err
+			return
+		}
+		c.Transport = client.Transport
+		if c.Timeout == 0 {
+			c.Timeout = client.Timeout
+		}
+	})
+	return c.transportErr
+}
@@ -0,0 +1,261 @@
+package gosip
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/gosip/cpass"
+)
+
+type transportCnfg struct {
+	AnonymousCnfg
+	Transport *TransportConfig
+}
+
+func (c *transportCnfg) GetTransportConfig() *TransportConfig { return c.Transport }
+
+func TestTransportConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("RootCAs", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		client := &SPClient{AuthCnfg: &transportCnfg{AnonymousCnfg: AnonymousCnfg{SiteURL: server.URL}}}
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if _, err := client.Execute(req); err == nil {
+			t.Error("untrusted certificate should fail")
+		}
+
+		caFile := filepath.Join(dir, "ca.pem")
+		writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+
+		client = &SPClient{AuthCnfg: &transportCnfg{
+			AnonymousCnfg: AnonymousCnfg{SiteURL: server.URL},
+			Transport:     &TransportConfig{RootCAs: []string{"ca.pem"}, baseDir: dir},
+		}}
+		req, _ = http.NewRequest("GET", server.URL, nil)
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	})
+
+	t.Run("ClientCertificate", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.TLS.PeerCertificates) == 0 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}))
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		server.StartTLS()
+		defer server.Close()
+
+		writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", server.Certificate().Raw)
+		key, cert := newClientCert(t, "gosip-client")
+		writePEM(t, filepath.Join(dir, "client.pem"), "CERTIFICATE", cert)
+		writePEM(t, filepath.Join(dir, "client.key"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+		cnfg := &TransportConfig{RootCAs: []string{"ca.pem"}, ClientCert: "client.pem", ClientKey: "client.key", MinTLSVersion: "1.2"}
+		if err := cnfg.Prepare(filepath.Join(dir, "private.json"), ""); err != nil {
+			t.Fatal(err)
+		}
+		client, err := cnfg.NewClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status: %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Proxy", func(t *testing.T) {
+		var proxyAuth, requestURI string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxyAuth = r.Header.Get("Proxy-Authorization")
+			requestURI = r.RequestURI
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer proxy.Close()
+
+		client := &SPClient{AuthCnfg: &transportCnfg{
+			AnonymousCnfg: AnonymousCnfg{SiteURL: "http://contoso.sharepoint.com"},
+			Transport: &TransportConfig{
+				ProxyURL:      proxy.URL,
+				ProxyUsername: "user",
+				ProxyPassword: "pass",
+				Timeout:       "5s",
+			},
+		}}
+		req, _ := http.NewRequest("GET", "http://contoso.sharepoint.com/_api/web", nil)
+		resp, err := client.Execute(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+
+		if requestURI != "http://contoso.sharepoint.com/_api/web" {
+			t.Errorf("request was not proxied: %s", requestURI)
+		}
+		if proxyAuth != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")) {
+			t.Errorf("unexpected proxy authorization: %s", proxyAuth)
+		}
+		if client.Timeout != 5*time.Second {
+			t.Errorf("timeout is not applied: %s", client.Timeout)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		client := &SPClient{AuthCnfg: &transportCnfg{
+			AnonymousCnfg: AnonymousCnfg{SiteURL: "http://localhost"},
+			Transport:     &TransportConfig{Timeout: "5s"},
+		}}
+		var wg sync.WaitGroup
+		transports := make([]http.RoundTripper, 10)
+		for i := range transports {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := client.applyTransport(); err != nil {
+					t.Error(err)
+				}
+				transports[i] = client.Transport
+			}(i)
+		}
+		wg.Wait()
+		for _, transport := range transports {
+			if transport == nil || transport != transports[0] {
+				t.Fatal("transport should be applied once for concurrent requests")
+			}
+		}
+	})
+
+	t.Run("CustomTransport", func(t *testing.T) {
+		custom := &http.Transport{}
+		client := &SPClient{
+			Client: http.Client{Transport: custom},
+			AuthCnfg: &transportCnfg{
+				AnonymousCnfg: AnonymousCnfg{SiteURL: "http://localhost"},
+				Transport:     &TransportConfig{MinTLSVersion: "1.3"},
+			},
+		}
+		if err := client.applyTransport(); err != nil {
+			t.Fatal(err)
+		}
+		if client.Transport != custom {
+			t.Error("custom transport should be kept")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := map[string]*TransportConfig{
+			"minTlsVersion":      {MinTLSVersion: "2.0"},
+			"clientKey":          {ClientCert: "client.pem"},
+			"root CA bundle":     {RootCAs: []string{filepath.Join(dir, "missing.pem")}},
+			"dialTimeout":        {DialTimeout: "soon"},
+			"timeout":            {Timeout: "10"},
+			"proxyUrl":           {ProxyURL: "://proxy"},
+			"client certificate": {ClientCert: "missing.pem", ClientKey: "missing.key"},
+		}
+		for name, cnfg := range cases {
+			if _, err := cnfg.NewClient(); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("%s: unexpected error: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		var cnfg *TransportConfig
+		if err := cnfg.Prepare("private.json", ""); err != nil {
+			t.Error(err)
+		}
+		if c, err := cnfg.Encode(""); c != nil || err != nil {
+			t.Error("nil config should be encoded to nil")
+		}
+		if _, err := cnfg.NewClient(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("ProxyPassword", func(t *testing.T) {
+		cnfg := &TransportConfig{ProxyURL: "http://proxy", ProxyPassword: "pass"}
+		encoded, err := cnfg.Encode("masterkey")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cpass.IsV2(encoded.ProxyPassword) {
+			t.Errorf("proxy password is not encoded: %s", encoded.ProxyPassword)
+		}
+		if err := encoded.Prepare("", "masterkey"); err != nil {
+			t.Fatal(err)
+		}
+		if encoded.ProxyPassword != "pass" {
+			t.Errorf("proxy password is not decoded: %s", encoded.ProxyPassword)
+		}
+
+		t.Setenv("GOSIP_TEST_PROXY_PASS", "from-env")
+		cnfg = &TransportConfig{ProxyPassword: "env:GOSIP_TEST_PROXY_PASS"}
+		if err := cnfg.Prepare("", ""); err != nil {
+			t.Fatal(err)
+		}
+		if cnfg.ProxyPassword != "from-env" {
+			t.Errorf("proxy password reference is not resolved: %s", cnfg.ProxyPassword)
+		}
+		encoded, _ = cnfg.Encode("")
+		if encoded.ProxyPassword != "env:GOSIP_TEST_PROXY_PASS" {
+			t.Errorf("proxy password reference should be kept: %s", encoded.ProxyPassword)
+		}
+	})
+}
+
+func writePEM(t *testing.T, file string, blockType string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newClientCert(t *testing.T, commonName string) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}